@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mvmaasakkers/certificates/acme"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+var acmeServeCommand = cli.Command{
+	Name:  "acme-serve",
+	Usage: "Serve an ACME (RFC 8555) server issuing certificates signed by the CA pair",
+	Description: `The server supports dns identifiers validated with the http-01 and dns-01 challenges. Accounts, orders and the
+   issued certificates are stored in the CA DB. The directory is served at <base-url>/directory.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "listen",
+			Value: ":8443",
+			Usage: "Address to listen on",
+		},
+		cli.StringFlag{
+			Name:  "base-url",
+			Value: "",
+			Usage: "External URL of the server, for example https://ca.example.com/acme",
+		},
+		cli.StringFlag{
+			Name:  "tls-crt",
+			Value: "",
+			Usage: "Certificate file to serve HTTPS with. Without --tls-crt and --tls-key plain HTTP is served.",
+		},
+		cli.StringFlag{
+			Name:  "tls-key",
+			Value: "",
+			Usage: "Key file to serve HTTPS with",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "CA Key file",
+		},
+		cli.StringFlag{
+			Name:  "ca-chain",
+			Value: "",
+			Usage: "File with the intermediate certificates above the CA, added to the issued certificate chains",
+		},
+		cli.StringFlag{
+			Name:  "dns-resolver",
+			Value: "",
+			Usage: "DNS server (host:port) used for the challenge validation. The default is the system resolver.",
+		},
+		cli.IntFlag{
+			Name:  "http-port",
+			Value: 80,
+			Usage: "Port the http-01 challenges are validated on",
+		},
+		cli.DurationFlag{
+			Name:  "validity",
+			Value: 90 * 24 * time.Hour,
+			Usage: "Validity of the issued certificates",
+		},
+	}, caKeyPassFlags, caSignerFlags, policyFlags, dbFlags, serialFlags),
+	Action: func(c *cli.Context) error {
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		caSigner, err := openCASigner(c, caCrt)
+		if err != nil {
+			fmt.Printf("Error reading CA key: %s\n", err.Error())
+			return err
+		}
+		defer caSigner.Close()
+
+		var caChain []byte
+		if c.String("ca-chain") != "" {
+			caChain, err = ioutil.ReadFile(c.String("ca-chain"))
+			if err != nil {
+				fmt.Printf("Error reading CA chain: %s\n", err.Error())
+				return err
+			}
+		}
+
+		policy, err := readPolicy(c)
+		if err != nil {
+			return err
+		}
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		serials, err := openSerialGenerator(c, DB)
+		if err != nil {
+			return err
+		}
+
+		http01 := &acme.HTTP01Validator{Port: c.Int("http-port")}
+		dns01 := &acme.DNS01Validator{}
+		if c.String("dns-resolver") != "" {
+			resolver := acme.NewResolver(c.String("dns-resolver"))
+			http01.Resolver = resolver
+			dns01.Resolver = resolver
+		}
+
+		s, err := acme.New(acme.Config{
+			BaseURL:  c.String("base-url"),
+			CACrt:    caCrt,
+			CASigner: caSigner,
+			CAChain:  caChain,
+			DB:       DB,
+			Validators: map[string]acme.Validator{
+				acme.ChallengeHTTP01: http01,
+				acme.ChallengeDNS01:  dns01,
+			},
+			Validity: c.Duration("validity"),
+			Policy:   policy,
+			Serials:  serials,
+		})
+		if err != nil {
+			fmt.Printf("Error creating ACME server: %s\n", err.Error())
+			return err
+		}
+
+		fmt.Printf("Serving ACME directory %s/directory on %s\n", c.String("base-url"), c.String("listen"))
+		if c.String("tls-crt") != "" || c.String("tls-key") != "" {
+			err = http.ListenAndServeTLS(c.String("listen"), c.String("tls-crt"), c.String("tls-key"), s)
+		} else {
+			err = http.ListenAndServe(c.String("listen"), s)
+		}
+		if err != nil {
+			fmt.Printf("Error serving ACME server: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,141 @@
+package acme
+
+import (
+	"encoding/json"
+	"github.com/mvmaasakkers/certificates/database"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+// accountRequest is the payload of new-account and account update requests (RFC 8555 section 7.3)
+type accountRequest struct {
+	Contact              []string `json:"contact"`
+	TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+	OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	Status               string   `json:"status"`
+}
+
+// accountResponse is the account object (RFC 8555 section 7.1.2)
+type accountResponse struct {
+	Status  string   `json:"status"`
+	Contact []string `json:"contact,omitempty"`
+}
+
+func newAccountResponse(account *database.Account) accountResponse {
+	res := accountResponse{Status: account.Status}
+	if account.Contact != "" {
+		res.Contact = strings.Split(account.Contact, ",")
+	}
+	return res
+}
+
+func (s *Server) handleNewAccount(w http.ResponseWriter, r *http.Request) *problem {
+	req, p := s.verify(r, "/new-account")
+	if p != nil {
+		return p
+	}
+
+	var payload accountRequest
+	if p := req.decode(&payload); p != nil {
+		return p
+	}
+
+	repo := s.db.GetAccountRepository()
+
+	keyID := req.jwk.thumbprint()
+	account, err := repo.GetByKeyID(keyID)
+	switch {
+	case err == nil:
+		w.Header().Set("Location", s.accountURL(account.UUID))
+		writeJSON(w, http.StatusOK, newAccountResponse(account))
+		return nil
+	case err != database.ErrorObjectNotFound:
+		return serverInternal(err)
+	}
+
+	if payload.OnlyReturnExisting {
+		return newProblem(problemAccountDoesNotExist, http.StatusBadRequest, "account does not exist")
+	}
+
+	if p := validateContacts(payload.Contact); p != nil {
+		return p
+	}
+
+	jwk, err := json.Marshal(req.jwk)
+	if err != nil {
+		return serverInternal(err)
+	}
+
+	account = database.NewAccount()
+	account.KeyID = keyID
+	account.JWK = string(jwk)
+	account.Contact = strings.Join(payload.Contact, ",")
+	account.Status = statusValid
+
+	if err := repo.Create(account); err != nil {
+		return serverInternal(err)
+	}
+
+	w.Header().Set("Location", s.accountURL(account.UUID))
+	writeJSON(w, http.StatusCreated, newAccountResponse(account))
+	return nil
+}
+
+// handleAccount returns the account, or updates its contacts or deactivates it when the request has a payload
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request, id string) *problem {
+	req, p := s.verify(r, "/account/"+id)
+	if p != nil {
+		return p
+	}
+
+	account := req.account
+	if account.UUID != id {
+		return unauthorized("account does not belong to the key")
+	}
+
+	if !req.postAsGet() {
+		var payload accountRequest
+		if p := req.decode(&payload); p != nil {
+			return p
+		}
+
+		if payload.Contact != nil {
+			if p := validateContacts(payload.Contact); p != nil {
+				return p
+			}
+			account.Contact = strings.Join(payload.Contact, ",")
+		}
+
+		switch payload.Status {
+		case "":
+		case statusDeactivated:
+			account.Status = statusDeactivated
+		default:
+			return malformed("invalid account status %q", payload.Status)
+		}
+
+		if err := s.db.GetAccountRepository().Update(account); err != nil {
+			return serverInternal(err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, newAccountResponse(account))
+	return nil
+}
+
+// validateContacts checks that all contacts are mailto URLs with a single email address
+func validateContacts(contacts []string) *problem {
+	for _, contact := range contacts {
+		if !strings.HasPrefix(contact, "mailto:") {
+			return newProblem(problemUnsupportedContact, http.StatusBadRequest, "unsupported contact %q", contact)
+		}
+
+		address := strings.TrimPrefix(contact, "mailto:")
+		parsed, err := mail.ParseAddress(address)
+		if err != nil || parsed.Address != address || strings.ContainsAny(address, ",?") {
+			return newProblem(problemInvalidContact, http.StatusBadRequest, "invalid contact %q", contact)
+		}
+	}
+	return nil
+}
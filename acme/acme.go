@@ -0,0 +1,391 @@
+// Package acme implements an RFC 8555 ACME server backed by the CA and the CA database.
+//
+// The server offers the directory, nonce, account, order, authorization, challenge, finalize and certificate
+// resources. Identifiers of type dns are supported and validated with the http-01 and dns-01 challenges, wildcard
+// identifiers can only be validated with dns-01. Challenges are validated synchronously when the client responds to
+// them. Certificates are issued by the CA from the CSR given at finalization and stored in the CA database like
+// certificates issued with the CLI, with the order UUID as NameSerialNumber.
+//
+// Accounts and orders are stored in the CA database as well, so the server can be restarted without clients losing
+// their accounts. Nonces are kept in memory, which means a single server process should serve a BaseURL.
+package acme
+
+import (
+	"crypto"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultValidity is the validity of the issued certificates when no validity is configured
+	defaultValidity = 90 * 24 * time.Hour
+	// orderLifetime is the time a client has to validate and finalize an order
+	orderLifetime = 24 * time.Hour
+	// validationTimeout is the maximum duration of a challenge validation
+	validationTimeout = 30 * time.Second
+	// maxRequestSize is the maximum size of a JWS request body
+	maxRequestSize = 64 * 1024
+)
+
+// Statuses of accounts, orders, authorizations and challenges (RFC 8555 section 7.1.6)
+const (
+	statusPending     = "pending"
+	statusProcessing  = "processing"
+	statusReady       = "ready"
+	statusValid       = "valid"
+	statusInvalid     = "invalid"
+	statusDeactivated = "deactivated"
+	statusExpired     = "expired"
+)
+
+// ErrorInvalidBaseURL is given if the base URL is not an absolute http or https URL
+var ErrorInvalidBaseURL = errors.New("invalid base url")
+
+// Config is the configuration of an ACME server
+type Config struct {
+	// BaseURL is the external URL the server is reachable on, for example https://ca.example.com/acme. The
+	// directory is served at BaseURL + "/directory".
+	BaseURL string
+
+	// CACrt and CAKey are the PEM encoded CA pair signing the certificates
+	CACrt []byte
+	CAKey []byte
+	// CASigner signs the certificates instead of CAKey when set, for CA keys kept in an HSM or KMS
+	CASigner crypto.Signer
+	// CAChain holds the PEM encoded intermediates between the CA and the root, they are added to the certificate
+	// chain given to clients
+	CAChain []byte
+
+	// DB stores the accounts, orders and issued certificates
+	DB database.DB
+
+	// Validators maps the supported challenge types to their validator. When nil the http-01 and dns-01
+	// challenges are validated with an HTTP01Validator and a DNS01Validator using the system resolver.
+	Validators map[string]Validator
+
+	// Validity is the validity of the issued certificates, the default (0) is 90 days
+	Validity time.Duration
+
+	// Policy restricts the certificates that are issued when not nil, CSRs violating it are rejected on finalize
+	Policy *cert.Policy
+
+	// Serials generates the serial numbers of issued certificates, random serial numbers are used when nil
+	Serials cert.SerialGenerator
+}
+
+// Server is an http.Handler serving the ACME resources
+type Server struct {
+	baseURL  string
+	basePath string
+
+	caCrt    []byte
+	caSigner crypto.Signer
+	caChain  []byte
+
+	db         database.DB
+	validators map[string]Validator
+	validity   time.Duration
+	policy     *cert.Policy
+	serials    cert.SerialGenerator
+
+	nonces *nonces
+
+	// lock serializes the changes to orders, so concurrent requests for the same order do not overwrite each other
+	lock sync.Mutex
+
+	now func() time.Time
+}
+
+// New creates a new ACME server for the given configuration
+func New(cfg Config) (*Server, error) {
+	if cfg.DB == nil {
+		return nil, database.ErrorNilConnection
+	}
+
+	baseURL, err := url.Parse(cfg.BaseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return nil, ErrorInvalidBaseURL
+	}
+
+	signer := cfg.CASigner
+	if signer == nil {
+		keyPair, err := tls.X509KeyPair(cfg.CACrt, cfg.CAKey)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if signer, ok = keyPair.PrivateKey.(crypto.Signer); !ok {
+			return nil, cert.ErrorInvalidKeyType
+		}
+	} else if err := cert.CheckSigner(cfg.CACrt, signer); err != nil {
+		return nil, err
+	}
+
+	validators := cfg.Validators
+	if validators == nil {
+		validators = map[string]Validator{
+			ChallengeHTTP01: &HTTP01Validator{},
+			ChallengeDNS01:  &DNS01Validator{},
+		}
+	}
+
+	validity := cfg.Validity
+	if validity <= 0 {
+		validity = defaultValidity
+	}
+
+	serials := cfg.Serials
+	if serials == nil {
+		serials = cert.RandomSerialGenerator{}
+	}
+
+	return &Server{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		basePath:   strings.TrimSuffix(baseURL.Path, "/"),
+		caCrt:      cfg.CACrt,
+		caSigner:   signer,
+		caChain:    cfg.CAChain,
+		db:         cfg.DB,
+		validators: validators,
+		validity:   validity,
+		policy:     cfg.Policy,
+		serials:    serials,
+		nonces:     newNonces(),
+		now:        time.Now,
+	}, nil
+}
+
+// ServeHTTP routes the request to the ACME resource it is sent to
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	nonce, err := s.nonces.new()
+	if err != nil {
+		writeProblem(w, serverInternal(err))
+		return
+	}
+	w.Header().Set("Replay-Nonce", nonce)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Add("Link", link(s.url("/directory"), "index"))
+
+	if !strings.HasPrefix(r.URL.Path, s.basePath+"/") {
+		writeProblem(w, notFound())
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, s.basePath)
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	var p *problem
+	switch {
+	case path == "/directory":
+		p = s.handleDirectory(w, r)
+	case path == "/new-nonce":
+		p = s.handleNewNonce(w, r)
+	case path == "/new-account":
+		p = s.handleNewAccount(w, r)
+	case path == "/new-order":
+		p = s.handleNewOrder(w, r)
+	case len(parts) == 2 && parts[0] == "account":
+		p = s.handleAccount(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "order":
+		p = s.handleOrder(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "authz":
+		p = s.handleAuthorization(w, r, parts[1], parts[2])
+	case len(parts) == 4 && parts[0] == "chall":
+		p = s.handleChallenge(w, r, parts[1], parts[2], parts[3])
+	case len(parts) == 2 && parts[0] == "finalize":
+		p = s.handleFinalize(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "cert":
+		p = s.handleCertificate(w, r, parts[1])
+	default:
+		p = notFound()
+	}
+
+	if p != nil {
+		writeProblem(w, p)
+	}
+}
+
+// directory is the directory object (RFC 8555 section 7.1.1)
+type directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+func (s *Server) handleDirectory(w http.ResponseWriter, r *http.Request) *problem {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed()
+	}
+
+	writeJSON(w, http.StatusOK, directory{
+		NewNonce:   s.url("/new-nonce"),
+		NewAccount: s.url("/new-account"),
+		NewOrder:   s.url("/new-order"),
+	})
+	return nil
+}
+
+func (s *Server) handleNewNonce(w http.ResponseWriter, r *http.Request) *problem {
+	switch r.Method {
+	case http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		return methodNotAllowed()
+	}
+	return nil
+}
+
+// request is a verified ACME request. For new-account requests the jwk is set, for all other requests the account
+// that signed the request.
+type request struct {
+	jws     *jws
+	jwk     *jsonWebKey
+	account *database.Account
+}
+
+// postAsGet checks if the request is a POST-as-GET request, which has an empty payload
+func (req *request) postAsGet() bool {
+	return req.jws.Payload == ""
+}
+
+// decode decodes the JSON payload of the request into v
+func (req *request) decode(v interface{}) *problem {
+	if err := json.Unmarshal(req.jws.payload, v); err != nil {
+		return malformed("invalid payload: %s", err.Error())
+	}
+	return nil
+}
+
+// verify reads the JWS from the request and verifies its nonce, url and signature (RFC 8555 section 6). Requests to
+// new-account are signed with the jwk of the new account, all other requests with the key of an existing account
+// which is identified by its kid.
+func (s *Server) verify(r *http.Request, path string) (*request, *problem) {
+	if r.Method != http.MethodPost {
+		return nil, methodNotAllowed()
+	}
+
+	if r.Header.Get("Content-Type") != "application/jose+json" {
+		return nil, newProblem(problemMalformed, http.StatusUnsupportedMediaType, "content type must be application/jose+json")
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestSize+1))
+	if err != nil {
+		return nil, malformed("error reading request: %s", err.Error())
+	}
+	if len(body) > maxRequestSize {
+		return nil, newProblem(problemMalformed, http.StatusRequestEntityTooLarge, "request too large")
+	}
+
+	j, p := parseJWS(body)
+	if p != nil {
+		return nil, p
+	}
+
+	if !s.nonces.use(j.header.Nonce) {
+		return nil, newProblem(problemBadNonce, http.StatusBadRequest, "invalid or expired nonce")
+	}
+
+	if j.header.URL != s.url(path) {
+		return nil, unauthorized("url %q does not match request url", j.header.URL)
+	}
+
+	req := &request{jws: j}
+	var jwk *jsonWebKey
+	if path == "/new-account" {
+		if j.header.JWK == nil || j.header.KID != "" {
+			return nil, malformed("new-account requests must be signed with a jwk")
+		}
+		jwk = j.header.JWK
+		req.jwk = jwk
+	} else {
+		if j.header.JWK != nil || !strings.HasPrefix(j.header.KID, s.url("/account/")) {
+			return nil, malformed("requests must be signed with the kid of an account")
+		}
+
+		account, err := s.db.GetAccountRepository().GetByUUID(strings.TrimPrefix(j.header.KID, s.url("/account/")))
+		if err == database.ErrorObjectNotFound {
+			return nil, newProblem(problemAccountDoesNotExist, http.StatusBadRequest, "account does not exist")
+		}
+		if err != nil {
+			return nil, serverInternal(err)
+		}
+
+		if account.Status != statusValid {
+			return nil, unauthorized("account is %s", account.Status)
+		}
+
+		jwk = &jsonWebKey{}
+		if err := json.Unmarshal([]byte(account.JWK), jwk); err != nil {
+			return nil, serverInternal(err)
+		}
+		req.account = account
+	}
+
+	pub, err := jwk.publicKey()
+	if err != nil {
+		return nil, newProblem(problemBadPublicKey, http.StatusBadRequest, "%s", err.Error())
+	}
+
+	if p := j.verify(pub); p != nil {
+		return nil, p
+	}
+
+	return req, nil
+}
+
+// url returns the absolute URL of the resource at the given path
+func (s *Server) url(path string) string {
+	return s.baseURL + path
+}
+
+func (s *Server) accountURL(id string) string {
+	return s.url("/account/" + id)
+}
+
+func (s *Server) orderURL(id string) string {
+	return s.url("/order/" + id)
+}
+
+func (s *Server) authorizationURL(orderID string, idx int) string {
+	return s.url(fmt.Sprintf("/authz/%s/%d", orderID, idx))
+}
+
+func (s *Server) challengeURL(orderID string, idx int, typ string) string {
+	return s.url(fmt.Sprintf("/chall/%s/%d/%s", orderID, idx, typ))
+}
+
+func (s *Server) finalizeURL(orderID string) string {
+	return s.url("/finalize/" + orderID)
+}
+
+func (s *Server) certificateURL(orderID string) string {
+	return s.url("/cert/" + orderID)
+}
+
+func link(url string, rel string) string {
+	return fmt.Sprintf("<%s>;rel=%q", url, rel)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeProblem(w http.ResponseWriter, p *problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,489 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/mvmaasakkers/certificates/database/file"
+	"golang.org/x/crypto/acme"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	Crt []byte
+	Key []byte
+}
+
+// stubValidator accepts every challenge when err is nil and records the validated domains
+type stubValidator struct {
+	err     error
+	domains []string
+}
+
+func (v *stubValidator) Validate(ctx context.Context, domain string, token string, keyAuthorization string) error {
+	v.domains = append(v.domains, domain)
+	return v.err
+}
+
+func newTestDB(t *testing.T) database.DB {
+	db := file.NewDB(filepath.Join(t.TempDir(), "file.db"))
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Provision(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestCA(t *testing.T) *testCA {
+	ca := &testCA{}
+	var err error
+	ca.Crt, ca.Key, err = cert.GenerateCA(&cert.Request{CommonName: "ca.test.local", KeyType: cert.KeyTypeECDSA, NotBefore: time.Now().Add(-time.Minute), NotAfter: time.Now().AddDate(1, 0, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+// newTestServer starts an ACME server with the given validators and returns a registered client
+func newTestServer(t *testing.T, validators map[string]Validator) (*acme.Client, database.DB, *testCA) {
+	db := newTestDB(t)
+	ca := newTestCA(t)
+
+	var s *Server
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	var err error
+	s, err = New(Config{BaseURL: ts.URL + "/acme", CACrt: ca.Crt, CAKey: ca.Key, DB: db, Validators: validators})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &acme.Client{Key: key, DirectoryURL: ts.URL + "/acme/directory"}
+	if _, err := client.Register(context.Background(), &acme.Account{Contact: []string{"mailto:admin@test.local"}}, acme.AcceptTOS); err != nil {
+		t.Fatal(err)
+	}
+
+	return client, db, ca
+}
+
+func newTestCSR(t *testing.T, cn string, names ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: cn}, DNSNames: names}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return csr
+}
+
+// authorizeOrder creates an order and accepts the challenge of the given type of all its authorizations
+func authorizeOrder(t *testing.T, client *acme.Client, typ string, names ...string) *acme.Order {
+	ctx := context.Background()
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(names...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var chal *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == typ {
+				chal = c
+			}
+		}
+		if chal == nil {
+			t.Fatalf("authorization for %s has no %s challenge", authz.Identifier.Value, typ)
+		}
+
+		if _, err := client.Accept(ctx, chal); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return order
+}
+
+func TestNew(t *testing.T) {
+	db := newTestDB(t)
+	ca := newTestCA(t)
+	other := newTestCA(t)
+
+	caKeyPair, err := tls.X509KeyPair(ca.Crt, ca.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeyPair, err := tls.X509KeyPair(other.Crt, other.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "ok", cfg: Config{BaseURL: "https://ca.test.local/acme", CACrt: ca.Crt, CAKey: ca.Key, DB: db}, wantErr: false},
+		{name: "nil_db", cfg: Config{BaseURL: "https://ca.test.local/acme", CACrt: ca.Crt, CAKey: ca.Key}, wantErr: true},
+		{name: "relative_base_url", cfg: Config{BaseURL: "/acme", CACrt: ca.Crt, CAKey: ca.Key, DB: db}, wantErr: true},
+		{name: "invalid_scheme", cfg: Config{BaseURL: "ftp://ca.test.local/acme", CACrt: ca.Crt, CAKey: ca.Key, DB: db}, wantErr: true},
+		{name: "invalid_ca", cfg: Config{BaseURL: "https://ca.test.local/acme", CACrt: []byte("invalid"), CAKey: ca.Key, DB: db}, wantErr: true},
+		{name: "mismatching_key", cfg: Config{BaseURL: "https://ca.test.local/acme", CACrt: ca.Crt, CAKey: other.Key, DB: db}, wantErr: true},
+		{name: "signer", cfg: Config{BaseURL: "https://ca.test.local/acme", CACrt: ca.Crt, CASigner: caKeyPair.PrivateKey.(crypto.Signer), DB: db}, wantErr: false},
+		{name: "mismatching_signer", cfg: Config{BaseURL: "https://ca.test.local/acme", CACrt: ca.Crt, CASigner: otherKeyPair.PrivateKey.(crypto.Signer), DB: db}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_Issue(t *testing.T) {
+	validator := &stubValidator{}
+	client, db, ca := newTestServer(t, map[string]Validator{ChallengeHTTP01: validator})
+	ctx := context.Background()
+
+	orderURL := authorizeOrder(t, client, ChallengeHTTP01, "www.test.local", "test.local").URI
+
+	order, err := client.WaitOrder(ctx, orderURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != acme.StatusReady {
+		t.Fatalf("order status = %s, want %s", order.Status, acme.StatusReady)
+	}
+
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, newTestCSR(t, "", "test.local", "www.test.local"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(der) != 1 {
+		t.Fatalf("chain length = %d, want 1", len(der))
+	}
+
+	crt, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crt.Subject.CommonName != "test.local" || strings.Join(crt.DNSNames, ",") != "test.local,www.test.local" {
+		t.Errorf("certificate names = %s %v", crt.Subject.CommonName, crt.DNSNames)
+	}
+	if err := crt.CheckSignatureFrom(parseTestCertificate(t, ca.Crt)); err != nil {
+		t.Errorf("certificate is not signed by the ca: %s", err)
+	}
+
+	if strings.Join(validator.domains, ",") != "test.local,www.test.local" {
+		t.Errorf("validated domains = %v", validator.domains)
+	}
+
+	stored, err := db.GetCertificateRepository().GetBySerialNumber(crt.SerialNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != database.StatusValid || stored.CommonName != "test.local" {
+		t.Errorf("stored certificate = %s %s", stored.Status, stored.CommonName)
+	}
+
+	orderID := orderURL[strings.LastIndex(orderURL, "/")+1:]
+	dbOrder, err := db.GetOrderRepository().GetByUUID(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dbOrder.Status != statusValid || dbOrder.CertificateSerialNumber != crt.SerialNumber.String() || stored.NameSerialNumber != orderID {
+		t.Errorf("stored order = %s %s", dbOrder.Status, dbOrder.CertificateSerialNumber)
+	}
+
+	// Finalizing a second time is not allowed
+	if _, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, newTestCSR(t, "test.local", "www.test.local"), true); !isProblem(err, problemOrderNotReady) {
+		t.Errorf("CreateOrderCert() error = %v, want %s", err, problemOrderNotReady)
+	}
+}
+
+func TestServer_Wildcard(t *testing.T) {
+	client, _, _ := newTestServer(t, map[string]Validator{ChallengeHTTP01: &stubValidator{}, ChallengeDNS01: &stubValidator{}})
+	ctx := context.Background()
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs("*.test.local", "test.local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		types := []string{}
+		for _, c := range authz.Challenges {
+			types = append(types, c.Type)
+		}
+
+		want := "http-01,dns-01"
+		if authz.Wildcard {
+			want = "dns-01"
+		}
+		if strings.Join(types, ",") != want || authz.Identifier.Value != "test.local" {
+			t.Errorf("authorization %s (wildcard %v) challenges = %v, want %s", authz.Identifier.Value, authz.Wildcard, types, want)
+		}
+	}
+
+	// Wildcards can not be validated without dns-01
+	client, _, _ = newTestServer(t, map[string]Validator{ChallengeHTTP01: &stubValidator{}})
+	if _, err := client.AuthorizeOrder(ctx, acme.DomainIDs("*.test.local")); !isProblem(err, problemRejectedIdentifier) {
+		t.Errorf("AuthorizeOrder() error = %v, want %s", err, problemRejectedIdentifier)
+	}
+}
+
+func TestServer_InvalidChallenge(t *testing.T) {
+	client, _, _ := newTestServer(t, map[string]Validator{ChallengeDNS01: &stubValidator{err: ErrorIncorrectResponse}})
+	ctx := context.Background()
+
+	order := authorizeOrder(t, client, ChallengeDNS01, "test.local")
+
+	authz, err := client.GetAuthorization(ctx, order.AuthzURLs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authz.Status != acme.StatusInvalid {
+		t.Errorf("authorization status = %s, want %s", authz.Status, acme.StatusInvalid)
+	}
+
+	order, err = client.GetOrder(ctx, order.URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != acme.StatusInvalid {
+		t.Errorf("order status = %s, want %s", order.Status, acme.StatusInvalid)
+	}
+	if order.Error == nil || order.Error.ProblemType != problemIncorrectResponse {
+		t.Errorf("order error = %v, want %s", order.Error, problemIncorrectResponse)
+	}
+}
+
+func TestServer_Finalize(t *testing.T) {
+	client, _, _ := newTestServer(t, map[string]Validator{ChallengeHTTP01: &stubValidator{}})
+	ctx := context.Background()
+
+	pending, err := client.AuthorizeOrder(ctx, acme.DomainIDs("test.local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.CreateOrderCert(ctx, pending.FinalizeURL, newTestCSR(t, "test.local"), true); !isProblem(err, problemOrderNotReady) {
+		t.Errorf("CreateOrderCert() error = %v, want %s", err, problemOrderNotReady)
+	}
+
+	order := authorizeOrder(t, client, ChallengeHTTP01, "test.local")
+
+	tests := []struct {
+		name string
+		csr  []byte
+	}{
+		{name: "missing_name", csr: newTestCSR(t, "", "www.test.local")},
+		{name: "extra_name", csr: newTestCSR(t, "test.local", "www.test.local")},
+		{name: "invalid_csr", csr: []byte("invalid")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, tt.csr, true); !isProblem(err, problemBadCSR) {
+				t.Errorf("CreateOrderCert() error = %v, want %s", err, problemBadCSR)
+			}
+		})
+	}
+
+	// The order is still ready after a bad CSR
+	if _, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, newTestCSR(t, "test.local"), true); err != nil {
+		t.Errorf("CreateOrderCert() error = %v", err)
+	}
+}
+
+func TestServer_NewOrder(t *testing.T) {
+	client, _, _ := newTestServer(t, map[string]Validator{ChallengeHTTP01: &stubValidator{}})
+
+	tests := []struct {
+		name    string
+		ids     []acme.AuthzID
+		problem string
+	}{
+		{name: "ip", ids: acme.IPIDs("127.0.0.1"), problem: problemUnsupportedIdentifier},
+		{name: "invalid_name", ids: acme.DomainIDs("test_local"), problem: problemRejectedIdentifier},
+		{name: "ip_as_dns", ids: acme.DomainIDs("127.0.0.1"), problem: problemRejectedIdentifier},
+		{name: "inner_wildcard", ids: acme.DomainIDs("www.*.test.local"), problem: problemRejectedIdentifier},
+		{name: "no_identifiers", ids: []acme.AuthzID{}, problem: problemMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.AuthorizeOrder(context.Background(), tt.ids); !isProblem(err, tt.problem) {
+				t.Errorf("AuthorizeOrder() error = %v, want %s", err, tt.problem)
+			}
+		})
+	}
+}
+
+func TestServer_Account(t *testing.T) {
+	client, _, _ := newTestServer(t, map[string]Validator{ChallengeHTTP01: &stubValidator{}})
+	ctx := context.Background()
+
+	account, err := client.GetReg(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Status != acme.StatusValid || strings.Join(account.Contact, ",") != "mailto:admin@test.local" {
+		t.Errorf("account = %s %v", account.Status, account.Contact)
+	}
+
+	if _, err := client.Register(ctx, &acme.Account{}, acme.AcceptTOS); err != acme.ErrAccountAlreadyExists {
+		t.Errorf("Register() error = %v, want %v", err, acme.ErrAccountAlreadyExists)
+	}
+
+	account.Contact = []string{"mailto:security@test.local"}
+	account, err = client.UpdateReg(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(account.Contact, ",") != "mailto:security@test.local" {
+		t.Errorf("account contact = %v", account.Contact)
+	}
+
+	account.Contact = []string{"tel:+31000000000"}
+	if _, err := client.UpdateReg(ctx, account); !isProblem(err, problemUnsupportedContact) {
+		t.Errorf("UpdateReg() error = %v, want %s", err, problemUnsupportedContact)
+	}
+
+	if err := client.DeactivateReg(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.AuthorizeOrder(ctx, acme.DomainIDs("test.local")); !isProblem(err, problemUnauthorized) {
+		t.Errorf("AuthorizeOrder() error = %v, want %s", err, problemUnauthorized)
+	}
+
+	// An unknown key has no account
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other := &acme.Client{Key: key, DirectoryURL: client.DirectoryURL}
+	if _, err := other.GetReg(ctx, ""); err != acme.ErrNoAccount {
+		t.Errorf("GetReg() error = %v, want %v", err, acme.ErrNoAccount)
+	}
+}
+
+func TestServer_OtherAccount(t *testing.T) {
+	client, _, _ := newTestServer(t, map[string]Validator{ChallengeHTTP01: &stubValidator{}})
+	ctx := context.Background()
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs("test.local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other := &acme.Client{Key: key, DirectoryURL: client.DirectoryURL}
+	if _, err := other.Register(ctx, &acme.Account{}, acme.AcceptTOS); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := other.GetOrder(ctx, order.URI); !isProblem(err, problemUnauthorized) {
+		t.Errorf("GetOrder() error = %v, want %s", err, problemUnauthorized)
+	}
+	if _, err := other.GetAuthorization(ctx, order.AuthzURLs[0]); !isProblem(err, problemUnauthorized) {
+		t.Errorf("GetAuthorization() error = %v, want %s", err, problemUnauthorized)
+	}
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	db := newTestDB(t)
+	ca := newTestCA(t)
+
+	s, err := New(Config{BaseURL: "https://ca.test.local/acme", CACrt: ca.Crt, CAKey: ca.Key, DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "directory", method: http.MethodGet, path: "/acme/directory", wantStatus: http.StatusOK},
+		{name: "nonce_head", method: http.MethodHead, path: "/acme/new-nonce", wantStatus: http.StatusOK},
+		{name: "nonce_get", method: http.MethodGet, path: "/acme/new-nonce", wantStatus: http.StatusNoContent},
+		{name: "not_found", method: http.MethodGet, path: "/acme/unknown", wantStatus: http.StatusNotFound},
+		{name: "outside_base_path", method: http.MethodGet, path: "/directory", wantStatus: http.StatusNotFound},
+		{name: "get_account", method: http.MethodGet, path: "/acme/new-account", wantStatus: http.StatusMethodNotAllowed},
+		{name: "content_type", method: http.MethodPost, path: "/acme/new-account", contentType: "application/json", body: "{}", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "invalid_jws", method: http.MethodPost, path: "/acme/new-account", contentType: "application/jose+json", body: "invalid", wantStatus: http.StatusBadRequest},
+		{name: "bad_nonce", method: http.MethodPost, path: "/acme/new-account", contentType: "application/jose+json", body: `{"protected":"eyJub25jZSI6Im5vbmUifQ","payload":"","signature":""}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "https://ca.test.local"+tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Header().Get("Replay-Nonce") == "" {
+				t.Errorf("ServeHTTP() did not set a Replay-Nonce")
+			}
+		})
+	}
+}
+
+func isProblem(err error, typ string) bool {
+	var e *acme.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.ProblemType == typ
+}
+
+func parseTestCertificate(t *testing.T, crt []byte) *x509.Certificate {
+	block, _ := pem.Decode(crt)
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -0,0 +1,133 @@
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported challenge types
+const (
+	ChallengeHTTP01 = "http-01"
+	ChallengeDNS01  = "dns-01"
+)
+
+// ErrorIncorrectResponse is given by a Validator if the challenge response does not match the key authorization
+var ErrorIncorrectResponse = errors.New("incorrect challenge response")
+
+// Validator validates a challenge for a domain. The keyAuthorization is the expected key authorization for the
+// token (RFC 8555 section 8.1). An error wrapping ErrorIncorrectResponse is returned if the response was retrieved
+// but does not match, any other error means the response could not be retrieved.
+type Validator interface {
+	Validate(ctx context.Context, domain string, token string, keyAuthorization string) error
+}
+
+// HTTP01Validator validates http-01 challenges by fetching the key authorization from
+// http://<domain>/.well-known/acme-challenge/<token> (RFC 8555 section 8.3)
+type HTTP01Validator struct {
+	// Client is the HTTP client used to fetch the key authorization. When nil a client with a 10 second timeout
+	// resolving hosts with Resolver is used.
+	Client *http.Client
+	// Resolver is used to resolve the domain when Client is nil. When nil the system resolver is used.
+	Resolver *net.Resolver
+	// Port is the port to connect to, the default (0) is 80
+	Port int
+}
+
+// Validate implements the Validator interface
+func (v *HTTP01Validator) Validate(ctx context.Context, domain string, token string, keyAuthorization string) error {
+	client := v.Client
+	if client == nil {
+		dialer := &net.Dialer{Timeout: 10 * time.Second, Resolver: v.Resolver}
+		client = &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		}
+	}
+
+	host := domain
+	if v.Port != 0 && v.Port != 80 {
+		host = net.JoinHostPort(domain, strconv.Itoa(v.Port))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/.well-known/acme-challenge/"+token, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrorIncorrectResponse, res.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimRight(string(body), " \t\r\n") != keyAuthorization {
+		return fmt.Errorf("%w: key authorization does not match", ErrorIncorrectResponse)
+	}
+	return nil
+}
+
+// TXTResolver looks up TXT records, it is implemented by *net.Resolver
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DNS01Validator validates dns-01 challenges by looking up the TXT record _acme-challenge.<domain>
+// (RFC 8555 section 8.4)
+type DNS01Validator struct {
+	// Resolver is used to look up the TXT records. When nil the system resolver is used.
+	Resolver TXTResolver
+}
+
+// Validate implements the Validator interface
+func (v *DNS01Validator) Validate(ctx context.Context, domain string, token string, keyAuthorization string) error {
+	resolver := v.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	records, err := resolver.LookupTXT(ctx, "_acme-challenge."+domain)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.Sum256([]byte(keyAuthorization))
+	expected := base64.RawURLEncoding.EncodeToString(h[:])
+	for _, record := range records {
+		if record == expected {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: no matching TXT record", ErrorIncorrectResponse)
+}
+
+// NewResolver creates a resolver that sends its queries to the DNS server at the given address (host:port) instead
+// of the servers configured in the system
+func NewResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := &net.Dialer{Timeout: 5 * time.Second}
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestHTTP01Validator_Validate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/acme-challenge/token":
+			w.Write([]byte("token.thumbprint\n"))
+		case "/.well-known/acme-challenge/other":
+			w.Write([]byte("other.thumbprint"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	p, _ := strconv.Atoi(port)
+
+	tests := []struct {
+		name      string
+		token     string
+		keyAuth   string
+		wantErr   bool
+		incorrect bool
+	}{
+		{name: "valid", token: "token", keyAuth: "token.thumbprint", wantErr: false},
+		{name: "mismatch", token: "other", keyAuth: "other.wrong", wantErr: true, incorrect: true},
+		{name: "not_found", token: "unknown", keyAuth: "unknown.thumbprint", wantErr: true, incorrect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &HTTP01Validator{Port: p}
+			err := v.Validate(context.Background(), host, tt.token, tt.keyAuth)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrorIncorrectResponse) != tt.incorrect {
+				t.Errorf("Validate() error = %v, incorrect response %v", err, tt.incorrect)
+			}
+		})
+	}
+
+	// Closed port
+	ts.Close()
+	v := &HTTP01Validator{Port: p}
+	if err := v.Validate(context.Background(), host, "token", "token.thumbprint"); err == nil || errors.Is(err, ErrorIncorrectResponse) {
+		t.Errorf("Validate() error = %v, want connection error", err)
+	}
+}
+
+// stubResolver returns the TXT records in its map
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestDNS01Validator_Validate(t *testing.T) {
+	h := sha256.Sum256([]byte("token.thumbprint"))
+	digest := base64.RawURLEncoding.EncodeToString(h[:])
+
+	v := &DNS01Validator{Resolver: stubResolver{
+		"_acme-challenge.test.local":  {"other", digest},
+		"_acme-challenge.wrong.local": {"other"},
+	}}
+
+	tests := []struct {
+		name      string
+		domain    string
+		wantErr   bool
+		incorrect bool
+	}{
+		{name: "valid", domain: "test.local", wantErr: false},
+		{name: "mismatch", domain: "wrong.local", wantErr: true, incorrect: true},
+		{name: "no_record", domain: "unknown.local", wantErr: true, incorrect: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(context.Background(), tt.domain, "token", "token.thumbprint")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrorIncorrectResponse) != tt.incorrect {
+				t.Errorf("Validate() error = %v, incorrect response %v", err, tt.incorrect)
+			}
+		})
+	}
+}
+
+func TestChallengeProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  string
+		err  error
+		want string
+	}{
+		{name: "incorrect", typ: ChallengeHTTP01, err: ErrorIncorrectResponse, want: problemIncorrectResponse},
+		{name: "connection", typ: ChallengeHTTP01, err: errors.New("connection refused"), want: problemConnection},
+		{name: "dns", typ: ChallengeDNS01, err: errors.New("no such host"), want: problemDNS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := challengeProblem(tt.typ, tt.err); got.Type != tt.want {
+				t.Errorf("challengeProblem() = %s, want %s", got.Type, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+// Supported JWS signature algorithms
+const (
+	algES256 = "ES256"
+	algES384 = "ES384"
+	algRS256 = "RS256"
+	algEdDSA = "EdDSA"
+)
+
+// jsonWebKey is an RFC 7517 public key
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// publicKey decodes the JWK into an *ecdsa.PublicKey, *rsa.PublicKey or ed25519.PublicKey
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid ec key")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if n.BitLen() < 2048 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid rsa key")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// thumbprint calculates the RFC 7638 thumbprint of the key, which is used as account key ID and in key
+// authorizations
+func (k *jsonWebKey) thumbprint() string {
+	var members string
+	switch k.Kty {
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, k.Crv, k.Kty, k.X, k.Y)
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, k.E, k.Kty, k.N)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, k.Crv, k.Kty, k.X)
+	}
+
+	h := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// jwsHeader is the protected header of an ACME JWS (RFC 8555 section 6.2)
+type jwsHeader struct {
+	Alg   string      `json:"alg"`
+	Nonce string      `json:"nonce"`
+	URL   string      `json:"url"`
+	JWK   *jsonWebKey `json:"jwk,omitempty"`
+	KID   string      `json:"kid,omitempty"`
+}
+
+// jws is a JWS in flattened JSON serialization
+type jws struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+
+	header  jwsHeader
+	payload []byte
+}
+
+// parseJWS decodes the JWS, its protected header and its payload. The signature is not verified.
+func parseJWS(body []byte) (*jws, *problem) {
+	j := &jws{}
+	if err := json.Unmarshal(body, j); err != nil {
+		return nil, malformed("invalid jws: %s", err.Error())
+	}
+
+	protected, err := base64.RawURLEncoding.DecodeString(j.Protected)
+	if err != nil {
+		return nil, malformed("invalid jws protected header encoding")
+	}
+	if err := json.Unmarshal(protected, &j.header); err != nil {
+		return nil, malformed("invalid jws protected header: %s", err.Error())
+	}
+
+	j.payload, err = base64.RawURLEncoding.DecodeString(j.Payload)
+	if err != nil {
+		return nil, malformed("invalid jws payload encoding")
+	}
+
+	return j, nil
+}
+
+// verify verifies the signature of the JWS with the given public key
+func (j *jws) verify(pub crypto.PublicKey) *problem {
+	sig, err := base64.RawURLEncoding.DecodeString(j.Signature)
+	if err != nil {
+		return malformed("invalid jws signature encoding")
+	}
+
+	input := []byte(j.Protected + "." + j.Payload)
+
+	valid := false
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		var digest []byte
+		switch {
+		case j.header.Alg == algES256 && key.Curve == elliptic.P256():
+			h := sha256.Sum256(input)
+			digest = h[:]
+		case j.header.Alg == algES384 && key.Curve == elliptic.P384():
+			h := sha512.Sum384(input)
+			digest = h[:]
+		default:
+			return badSignatureAlgorithm(j.header.Alg)
+		}
+
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			valid = ecdsa.Verify(key, digest, r, s)
+		}
+	case *rsa.PublicKey:
+		if j.header.Alg != algRS256 {
+			return badSignatureAlgorithm(j.header.Alg)
+		}
+
+		h := sha256.Sum256(input)
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, h[:], sig) == nil
+	case ed25519.PublicKey:
+		if j.header.Alg != algEdDSA {
+			return badSignatureAlgorithm(j.header.Alg)
+		}
+
+		valid = ed25519.Verify(key, input, sig)
+	default:
+		return badSignatureAlgorithm(j.header.Alg)
+	}
+
+	if !valid {
+		return malformed("invalid jws signature")
+	}
+	return nil
+}
+
+func badSignatureAlgorithm(alg string) *problem {
+	return newProblem(problemBadSignatureAlgorithm, http.StatusBadRequest, "unsupported signature algorithm %q for key", alg)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
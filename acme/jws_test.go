@@ -0,0 +1,209 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+// testJWK returns the JWK of the public key
+func testJWK(pub crypto.PublicKey) *jsonWebKey {
+	enc := base64.RawURLEncoding.EncodeToString
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return &jsonWebKey{Kty: "EC", Crv: key.Curve.Params().Name, X: enc(key.X.FillBytes(make([]byte, size))), Y: enc(key.Y.FillBytes(make([]byte, size)))}
+	case *rsa.PublicKey:
+		return &jsonWebKey{Kty: "RSA", N: enc(key.N.Bytes()), E: enc(big.NewInt(int64(key.E)).Bytes())}
+	case ed25519.PublicKey:
+		return &jsonWebKey{Kty: "OKP", Crv: "Ed25519", X: enc(key)}
+	}
+	return nil
+}
+
+// testSign creates a JWS of the payload signed by the key with the given algorithm
+func testSign(t *testing.T, alg string, key crypto.Signer, payload string) []byte {
+	header, err := json.Marshal(jwsHeader{Alg: alg, Nonce: "nonce", URL: "https://ca.test.local/acme/new-account", JWK: testJWK(key.Public())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protected := base64.RawURLEncoding.EncodeToString(header)
+	encodedPayload := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	input := []byte(protected + "." + encodedPayload)
+
+	var sig []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		var digest []byte
+		if k.Curve == elliptic.P384() {
+			h := sha512.Sum384(input)
+			digest = h[:]
+		} else {
+			h := sha256.Sum256(input)
+			digest = h[:]
+		}
+
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	case *rsa.PrivateKey:
+		h := sha256.Sum256(input)
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, input)
+	}
+
+	body, err := json.Marshal(jws{Protected: protected, Payload: encodedPayload, Signature: base64.RawURLEncoding.EncodeToString(sig)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestJSONWebKey_thumbprint(t *testing.T) {
+	// Example of RFC 7638 section 3.1
+	k := &jsonWebKey{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	}
+
+	if got := k.thumbprint(); got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("thumbprint() = %s", got)
+	}
+
+	if _, err := k.publicKey(); err != nil {
+		t.Errorf("publicKey() error = %v", err)
+	}
+}
+
+func TestJSONWebKey_publicKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     *jsonWebKey
+		wantErr bool
+	}{
+		{name: "unsupported_kty", key: &jsonWebKey{Kty: "oct"}, wantErr: true},
+		{name: "unsupported_curve", key: &jsonWebKey{Kty: "EC", Crv: "P-521", X: "AQ", Y: "AQ"}, wantErr: true},
+		{name: "not_on_curve", key: &jsonWebKey{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}, wantErr: true},
+		{name: "small_rsa", key: &jsonWebKey{Kty: "RSA", N: "AQAB", E: "AQAB"}, wantErr: true},
+		{name: "invalid_ed25519", key: &jsonWebKey{Kty: "OKP", Crv: "Ed25519", X: "AQAB"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.key.publicKey()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("publicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWS_verify(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name        string
+		alg         string
+		key         crypto.Signer
+		verifyKey   crypto.PublicKey
+		wantProblem string
+	}{
+		{name: "es256", alg: algES256, key: p256, verifyKey: p256.Public()},
+		{name: "es384", alg: algES384, key: p384, verifyKey: p384.Public()},
+		{name: "rs256", alg: algRS256, key: rsaKey, verifyKey: rsaKey.Public()},
+		{name: "eddsa", alg: algEdDSA, key: edKey, verifyKey: edKey.Public()},
+		{name: "alg_curve_mismatch", alg: algES384, key: p256, verifyKey: p256.Public(), wantProblem: problemBadSignatureAlgorithm},
+		{name: "alg_none", alg: "none", key: rsaKey, verifyKey: rsaKey.Public(), wantProblem: problemBadSignatureAlgorithm},
+		{name: "wrong_key", alg: algES256, key: p256, verifyKey: testJWKPublicKey(t, p384), wantProblem: problemBadSignatureAlgorithm},
+		{name: "other_key", alg: algRS256, key: rsaKey, verifyKey: mustRSAKey(t).Public(), wantProblem: problemMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, p := parseJWS(testSign(t, tt.alg, tt.key, `{"test":true}`))
+			if p != nil {
+				t.Fatal(p)
+			}
+
+			if string(j.payload) != `{"test":true}` || j.header.URL != "https://ca.test.local/acme/new-account" {
+				t.Errorf("parseJWS() payload = %s, url = %s", j.payload, j.header.URL)
+			}
+
+			p = j.verify(tt.verifyKey)
+			switch {
+			case tt.wantProblem == "" && p != nil:
+				t.Errorf("verify() = %v", p)
+			case tt.wantProblem != "" && (p == nil || p.Type != tt.wantProblem):
+				t.Errorf("verify() = %v, want %s", p, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestJWS_verifyTampered(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	j, p := parseJWS(testSign(t, algES256, key, `{"test":true}`))
+	if p != nil {
+		t.Fatal(p)
+	}
+	j.Payload = base64.RawURLEncoding.EncodeToString([]byte(`{"test":false}`))
+
+	if p := j.verify(key.Public()); p == nil || p.Type != problemMalformed {
+		t.Errorf("verify() = %v, want %s", p, problemMalformed)
+	}
+}
+
+func TestParseJWS(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid_json", body: "invalid"},
+		{name: "invalid_protected", body: `{"protected":"!","payload":"","signature":""}`},
+		{name: "invalid_header", body: `{"protected":"aW52YWxpZA","payload":"","signature":""}`},
+		{name: "invalid_payload", body: `{"protected":"e30","payload":"!","signature":""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, p := parseJWS([]byte(tt.body)); p == nil || p.Type != problemMalformed {
+				t.Errorf("parseJWS() = %v, want %s", p, problemMalformed)
+			}
+		})
+	}
+}
+
+func testJWKPublicKey(t *testing.T, key crypto.Signer) crypto.PublicKey {
+	pub, err := testJWK(key.Public()).publicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
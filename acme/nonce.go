@@ -0,0 +1,75 @@
+package acme
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+const (
+	// nonceLifetime is the time a nonce stays valid after it was issued
+	nonceLifetime = time.Hour
+	// maxNonces is the number of outstanding nonces after which expired nonces are removed
+	maxNonces = 10000
+)
+
+// nonces keeps track of the issued nonces, every nonce can only be used once
+type nonces struct {
+	lock   sync.Mutex
+	issued map[string]time.Time
+	now    func() time.Time
+}
+
+func newNonces() *nonces {
+	return &nonces{
+		issued: make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// new issues a new nonce
+func (n *nonces) new() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	now := n.now()
+	if len(n.issued) >= maxNonces {
+		for k, issued := range n.issued {
+			if now.Sub(issued) > nonceLifetime {
+				delete(n.issued, k)
+			}
+		}
+	}
+
+	// Still full, drop an arbitrary nonce. Its client gets a badNonce error and retries with a new nonce.
+	for k := range n.issued {
+		if len(n.issued) < maxNonces {
+			break
+		}
+		delete(n.issued, k)
+	}
+
+	n.issued[nonce] = now
+	return nonce, nil
+}
+
+// use checks if the nonce was issued and has not expired or been used yet
+func (n *nonces) use(nonce string) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	issued, ok := n.issued[nonce]
+	if !ok {
+		return false
+	}
+
+	delete(n.issued, nonce)
+	return n.now().Sub(issued) <= nonceLifetime
+}
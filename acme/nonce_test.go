@@ -0,0 +1,49 @@
+package acme
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNonces(t *testing.T) {
+	n := newNonces()
+	now := time.Now()
+	n.now = func() time.Time { return now }
+
+	nonce, err := n.new()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.use("unknown") {
+		t.Errorf("use() of unknown nonce = true")
+	}
+	if !n.use(nonce) {
+		t.Errorf("use() of issued nonce = false")
+	}
+	if n.use(nonce) {
+		t.Errorf("use() of used nonce = true")
+	}
+
+	expired, err := n.new()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(nonceLifetime + time.Second)
+	if n.use(expired) {
+		t.Errorf("use() of expired nonce = true")
+	}
+}
+
+func TestNonces_max(t *testing.T) {
+	n := newNonces()
+	for i := 0; i < maxNonces+10; i++ {
+		if _, err := n.new(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(n.issued) != maxNonces {
+		t.Errorf("issued nonces = %d, want %d", len(n.issued), maxNonces)
+	}
+}
@@ -0,0 +1,680 @@
+package acme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"math/big"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// identifier is an ACME identifier, only the dns type is supported
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// authorization is an authorization of an order as stored in database.Order.Authorizations. For wildcard
+// identifiers the value of the identifier is the domain without the "*." prefix.
+type authorization struct {
+	Identifier identifier   `json:"identifier"`
+	Status     string       `json:"status"`
+	Wildcard   bool         `json:"wildcard,omitempty"`
+	Challenges []*challenge `json:"challenges"`
+}
+
+// challenge is a challenge of an authorization
+type challenge struct {
+	Type      string     `json:"type"`
+	Token     string     `json:"token"`
+	Status    string     `json:"status"`
+	Validated *time.Time `json:"validated,omitempty"`
+	Error     *problem   `json:"error,omitempty"`
+}
+
+// orderRequest is the payload of new-order requests (RFC 8555 section 7.4)
+type orderRequest struct {
+	Identifiers []identifier `json:"identifiers"`
+	NotBefore   string       `json:"notBefore"`
+	NotAfter    string       `json:"notAfter"`
+}
+
+// orderResponse is the order object (RFC 8555 section 7.1.3)
+type orderResponse struct {
+	Status         string       `json:"status"`
+	Expires        time.Time    `json:"expires"`
+	Identifiers    []identifier `json:"identifiers"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate,omitempty"`
+	Error          *problem     `json:"error,omitempty"`
+}
+
+// authorizationResponse is the authorization object (RFC 8555 section 7.1.4)
+type authorizationResponse struct {
+	Identifier identifier          `json:"identifier"`
+	Status     string              `json:"status"`
+	Expires    time.Time           `json:"expires"`
+	Challenges []challengeResponse `json:"challenges"`
+	Wildcard   bool                `json:"wildcard,omitempty"`
+}
+
+// challengeResponse is the challenge object (RFC 8555 section 8)
+type challengeResponse struct {
+	Type      string     `json:"type"`
+	URL       string     `json:"url"`
+	Status    string     `json:"status"`
+	Token     string     `json:"token"`
+	Validated *time.Time `json:"validated,omitempty"`
+	Error     *problem   `json:"error,omitempty"`
+}
+
+// finalizeRequest is the payload of finalize requests (RFC 8555 section 7.4)
+type finalizeRequest struct {
+	CSR string `json:"csr"`
+}
+
+func (s *Server) newOrderResponse(order *database.Order, authzs []*authorization) orderResponse {
+	res := orderResponse{
+		Status:         order.Status,
+		Expires:        order.Expires,
+		Identifiers:    []identifier{},
+		Authorizations: []string{},
+		Finalize:       s.finalizeURL(order.UUID),
+	}
+
+	for _, value := range strings.Split(order.Identifiers, ",") {
+		res.Identifiers = append(res.Identifiers, identifier{Type: "dns", Value: value})
+	}
+	for i := range authzs {
+		res.Authorizations = append(res.Authorizations, s.authorizationURL(order.UUID, i))
+	}
+
+	if order.Status == statusValid {
+		res.Certificate = s.certificateURL(order.UUID)
+	}
+
+	if order.Error != "" {
+		res.Error = &problem{}
+		if err := json.Unmarshal([]byte(order.Error), res.Error); err != nil {
+			res.Error = nil
+		}
+	}
+
+	return res
+}
+
+func (s *Server) newAuthorizationResponse(order *database.Order, idx int, authz *authorization) authorizationResponse {
+	res := authorizationResponse{
+		Identifier: authz.Identifier,
+		Status:     authz.Status,
+		Expires:    order.Expires,
+		Challenges: []challengeResponse{},
+		Wildcard:   authz.Wildcard,
+	}
+
+	for _, ch := range authz.Challenges {
+		res.Challenges = append(res.Challenges, s.newChallengeResponse(order, idx, ch))
+	}
+
+	return res
+}
+
+func (s *Server) newChallengeResponse(order *database.Order, idx int, ch *challenge) challengeResponse {
+	return challengeResponse{
+		Type:      ch.Type,
+		URL:       s.challengeURL(order.UUID, idx, ch.Type),
+		Status:    ch.Status,
+		Token:     ch.Token,
+		Validated: ch.Validated,
+		Error:     ch.Error,
+	}
+}
+
+func (s *Server) handleNewOrder(w http.ResponseWriter, r *http.Request) *problem {
+	req, p := s.verify(r, "/new-order")
+	if p != nil {
+		return p
+	}
+
+	var payload orderRequest
+	if p := req.decode(&payload); p != nil {
+		return p
+	}
+
+	if payload.NotBefore != "" || payload.NotAfter != "" {
+		return malformed("notBefore and notAfter are not supported")
+	}
+
+	if len(payload.Identifiers) == 0 {
+		return malformed("order has no identifiers")
+	}
+
+	values := []string{}
+	seen := make(map[string]bool)
+	for _, id := range payload.Identifiers {
+		if id.Type != "dns" {
+			return newProblem(problemUnsupportedIdentifier, http.StatusBadRequest, "unsupported identifier type %q", id.Type)
+		}
+
+		value := strings.ToLower(id.Value)
+		if !isValidDNSName(value) {
+			return newProblem(problemRejectedIdentifier, http.StatusBadRequest, "invalid dns identifier %q", id.Value)
+		}
+
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+
+	authzs := []*authorization{}
+	for _, value := range values {
+		authz, p := s.newAuthorization(value)
+		if p != nil {
+			return p
+		}
+		authzs = append(authzs, authz)
+	}
+
+	order := database.NewOrder()
+	order.AccountUUID = req.account.UUID
+	order.Status = statusPending
+	order.Identifiers = strings.Join(values, ",")
+	order.Expires = s.now().Add(orderLifetime).UTC().Truncate(time.Second)
+	if err := setAuthorizations(order, authzs); err != nil {
+		return serverInternal(err)
+	}
+
+	if err := s.db.GetOrderRepository().Create(order); err != nil {
+		return serverInternal(err)
+	}
+
+	w.Header().Set("Location", s.orderURL(order.UUID))
+	writeJSON(w, http.StatusCreated, s.newOrderResponse(order, authzs))
+	return nil
+}
+
+// newAuthorization creates a pending authorization for the dns identifier with a challenge of every supported type.
+// Wildcard identifiers can only be validated with dns-01.
+func (s *Server) newAuthorization(value string) (*authorization, *problem) {
+	authz := &authorization{
+		Identifier: identifier{Type: "dns", Value: strings.TrimPrefix(value, "*.")},
+		Status:     statusPending,
+		Wildcard:   strings.HasPrefix(value, "*."),
+		Challenges: []*challenge{},
+	}
+
+	for _, typ := range []string{ChallengeHTTP01, ChallengeDNS01} {
+		if _, ok := s.validators[typ]; !ok {
+			continue
+		}
+		if authz.Wildcard && typ != ChallengeDNS01 {
+			continue
+		}
+
+		token, err := newToken()
+		if err != nil {
+			return nil, serverInternal(err)
+		}
+
+		authz.Challenges = append(authz.Challenges, &challenge{Type: typ, Token: token, Status: statusPending})
+	}
+
+	if len(authz.Challenges) == 0 {
+		return nil, newProblem(problemRejectedIdentifier, http.StatusBadRequest, "no challenge available to validate %q", value)
+	}
+
+	return authz, nil
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request, id string) *problem {
+	req, p := s.verify(r, "/order/"+id)
+	if p != nil {
+		return p
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	order, authzs, p := s.loadOrder(req, id)
+	if p != nil {
+		return p
+	}
+
+	writeJSON(w, http.StatusOK, s.newOrderResponse(order, authzs))
+	return nil
+}
+
+// handleAuthorization returns the authorization, or deactivates it when the request has a payload
+func (s *Server) handleAuthorization(w http.ResponseWriter, r *http.Request, orderID string, index string) *problem {
+	req, p := s.verify(r, "/authz/"+orderID+"/"+index)
+	if p != nil {
+		return p
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	order, authzs, p := s.loadOrder(req, orderID)
+	if p != nil {
+		return p
+	}
+
+	idx, err := strconv.Atoi(index)
+	if err != nil || idx < 0 || idx >= len(authzs) {
+		return notFound()
+	}
+	authz := authzs[idx]
+
+	if !req.postAsGet() {
+		var payload struct {
+			Status string `json:"status"`
+		}
+		if p := req.decode(&payload); p != nil {
+			return p
+		}
+
+		if payload.Status != statusDeactivated {
+			return malformed("invalid authorization status %q", payload.Status)
+		}
+		if authz.Status != statusPending && authz.Status != statusValid {
+			return malformed("authorization is %s", authz.Status)
+		}
+
+		authz.Status = statusDeactivated
+		updateOrderStatus(order, authzs, s.now())
+		if p := s.saveOrder(order, authzs); p != nil {
+			return p
+		}
+	}
+
+	writeJSON(w, http.StatusOK, s.newAuthorizationResponse(order, idx, authz))
+	return nil
+}
+
+// handleChallenge returns the challenge, or validates it when the request has a payload. The validation is done
+// synchronously without holding the lock, the challenge is processing in the meantime.
+func (s *Server) handleChallenge(w http.ResponseWriter, r *http.Request, orderID string, index string, typ string) *problem {
+	req, p := s.verify(r, "/chall/"+orderID+"/"+index+"/"+typ)
+	if p != nil {
+		return p
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	order, authzs, p := s.loadOrder(req, orderID)
+	if p != nil {
+		return p
+	}
+
+	idx, err := strconv.Atoi(index)
+	if err != nil || idx < 0 || idx >= len(authzs) {
+		return notFound()
+	}
+	authz := authzs[idx]
+	ch := findChallenge(authz, typ)
+	if ch == nil {
+		return notFound()
+	}
+
+	if !req.postAsGet() && ch.Status == statusPending && authz.Status == statusPending {
+		ch.Status = statusProcessing
+		if p := s.saveOrder(order, authzs); p != nil {
+			return p
+		}
+
+		s.lock.Unlock()
+		err := s.validate(r.Context(), authz, ch, req.account.KeyID)
+		s.lock.Lock()
+
+		// Reload the order, it could have been changed during the validation
+		order, authzs, p = s.loadOrder(req, orderID)
+		if p != nil {
+			return p
+		}
+		authz = authzs[idx]
+		ch = findChallenge(authz, typ)
+
+		if ch.Status == statusProcessing {
+			now := s.now().UTC().Truncate(time.Second)
+			if err == nil {
+				ch.Status = statusValid
+				ch.Validated = &now
+				if authz.Status == statusPending {
+					authz.Status = statusValid
+				}
+			} else {
+				ch.Status = statusInvalid
+				ch.Error = challengeProblem(ch.Type, err)
+				if authz.Status == statusPending {
+					authz.Status = statusInvalid
+				}
+
+				if perr, err := json.Marshal(ch.Error); err == nil {
+					order.Error = string(perr)
+				}
+			}
+
+			updateOrderStatus(order, authzs, s.now())
+			if p := s.saveOrder(order, authzs); p != nil {
+				return p
+			}
+		}
+	}
+
+	w.Header().Add("Link", link(s.authorizationURL(orderID, idx), "up"))
+	writeJSON(w, http.StatusOK, s.newChallengeResponse(order, idx, ch))
+	return nil
+}
+
+// validate validates the challenge with the validator of its type
+func (s *Server) validate(ctx context.Context, authz *authorization, ch *challenge, keyID string) error {
+	ctx, cancel := context.WithTimeout(ctx, validationTimeout)
+	defer cancel()
+
+	return s.validators[ch.Type].Validate(ctx, authz.Identifier.Value, ch.Token, ch.Token+"."+keyID)
+}
+
+// challengeProblem converts a validation error into the problem of the challenge
+func challengeProblem(typ string, err error) *problem {
+	switch {
+	case errors.Is(err, ErrorIncorrectResponse):
+		return newProblem(problemIncorrectResponse, http.StatusForbidden, "%s", err.Error())
+	case typ == ChallengeDNS01:
+		return newProblem(problemDNS, http.StatusBadRequest, "%s", err.Error())
+	}
+	return newProblem(problemConnection, http.StatusBadRequest, "%s", err.Error())
+}
+
+// handleFinalize issues the certificate for the CSR of a ready order. The CSR has to contain exactly the
+// identifiers of the order.
+func (s *Server) handleFinalize(w http.ResponseWriter, r *http.Request, id string) *problem {
+	req, p := s.verify(r, "/finalize/"+id)
+	if p != nil {
+		return p
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	order, authzs, p := s.loadOrder(req, id)
+	if p != nil {
+		return p
+	}
+
+	if order.Status != statusReady {
+		return newProblem(problemOrderNotReady, http.StatusForbidden, "order is %s", order.Status)
+	}
+
+	var payload finalizeRequest
+	if p := req.decode(&payload); p != nil {
+		return p
+	}
+
+	der, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return badCSR("invalid csr encoding")
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return badCSR("invalid csr: %s", err.Error())
+	}
+
+	if len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return badCSR("csr may only contain dns names")
+	}
+
+	names := make(map[string]bool)
+	for _, name := range csr.DNSNames {
+		names[strings.ToLower(name)] = true
+	}
+	if csr.Subject.CommonName != "" {
+		names[strings.ToLower(csr.Subject.CommonName)] = true
+	}
+
+	identifiers := strings.Split(order.Identifiers, ",")
+	if len(names) != len(identifiers) {
+		return badCSR("csr names do not match the order identifiers")
+	}
+	for _, value := range identifiers {
+		if !names[value] {
+			return badCSR("csr names do not match the order identifiers")
+		}
+	}
+
+	now := s.now()
+	cr := cert.NewRequest()
+	cr.CommonName = identifiers[0]
+	cr.SubjectAltNames = identifiers
+	cr.NameSerialNumber = order.UUID
+	cr.NotBefore = now
+	cr.NotAfter = now.Add(s.validity)
+	cr.Policy = s.policy
+	cr.CAChain = s.caChain
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	repo := s.db.GetCertificateRepository()
+	err = database.WithUniqueSerialNumber(repo, s.serials, func(serialNumber *big.Int) error {
+		cr.SerialNumber = serialNumber
+		crt, err := cert.SignCSRWithSigner(cr, csrPEM, s.caCrt, s.caSigner)
+		if err != nil {
+			return err
+		}
+
+		DBCert := database.NewCertificate()
+		DBCert.Status = database.StatusValid
+		DBCert.NameSerialNumber = order.UUID
+		if err := DBCert.SetCertificate(crt); err != nil {
+			return err
+		}
+		return repo.Create(DBCert)
+	})
+	if err == cert.ErrorInvalidCSRSignature {
+		return badCSR("invalid csr signature")
+	}
+	if errors.Is(err, cert.ErrorPolicyViolation) || errors.Is(err, cert.ErrorNameConstraintViolation) {
+		return badCSR("%s", err.Error())
+	}
+	if err != nil {
+		return serverInternal(err)
+	}
+
+	order.Status = statusValid
+	order.CertificateSerialNumber = cr.SerialNumber.String()
+	if p := s.saveOrder(order, authzs); p != nil {
+		return p
+	}
+
+	w.Header().Set("Location", s.orderURL(order.UUID))
+	writeJSON(w, http.StatusOK, s.newOrderResponse(order, authzs))
+	return nil
+}
+
+// handleCertificate returns the certificate of a valid order followed by the CA chain
+func (s *Server) handleCertificate(w http.ResponseWriter, r *http.Request, id string) *problem {
+	req, p := s.verify(r, "/cert/"+id)
+	if p != nil {
+		return p
+	}
+
+	s.lock.Lock()
+	order, _, p := s.loadOrder(req, id)
+	s.lock.Unlock()
+	if p != nil {
+		return p
+	}
+
+	if order.Status != statusValid {
+		return notFound()
+	}
+
+	serialNumber, ok := new(big.Int).SetString(order.CertificateSerialNumber, 10)
+	if !ok {
+		return notFound()
+	}
+
+	crt, err := s.db.GetCertificateRepository().GetBySerialNumber(serialNumber)
+	if err == database.ErrorObjectNotFound {
+		return notFound()
+	}
+	if err != nil {
+		return serverInternal(err)
+	}
+
+	chain, err := cert.Chain([]byte(crt.CertificatePEM), s.caCrt, s.caChain)
+	if err != nil {
+		return serverInternal(err)
+	}
+
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	w.WriteHeader(http.StatusOK)
+	w.Write(chain)
+	return nil
+}
+
+// loadOrder gets the order of the account of the request and its authorizations. The status of the order is updated
+// when it has expired.
+func (s *Server) loadOrder(req *request, id string) (*database.Order, []*authorization, *problem) {
+	order, err := s.db.GetOrderRepository().GetByUUID(id)
+	if err == database.ErrorObjectNotFound {
+		return nil, nil, notFound()
+	}
+	if err != nil {
+		return nil, nil, serverInternal(err)
+	}
+
+	if order.AccountUUID != req.account.UUID {
+		return nil, nil, unauthorized("order belongs to another account")
+	}
+
+	authzs := []*authorization{}
+	if err := json.Unmarshal([]byte(order.Authorizations), &authzs); err != nil {
+		return nil, nil, serverInternal(err)
+	}
+
+	if updateOrderStatus(order, authzs, s.now()) {
+		if p := s.saveOrder(order, authzs); p != nil {
+			return nil, nil, p
+		}
+	}
+
+	return order, authzs, nil
+}
+
+// saveOrder stores the order with the given authorizations
+func (s *Server) saveOrder(order *database.Order, authzs []*authorization) *problem {
+	if err := setAuthorizations(order, authzs); err != nil {
+		return serverInternal(err)
+	}
+
+	if err := s.db.GetOrderRepository().Update(order); err != nil {
+		return serverInternal(err)
+	}
+	return nil
+}
+
+func setAuthorizations(order *database.Order, authzs []*authorization) error {
+	d, err := json.Marshal(authzs)
+	if err != nil {
+		return err
+	}
+
+	order.Authorizations = string(d)
+	return nil
+}
+
+// updateOrderStatus updates the status of a pending or ready order and its authorizations (RFC 8555 section 7.1.6)
+// and reports whether anything changed. The order becomes invalid when it expires or one of its authorizations fails,
+// and ready when all authorizations are valid.
+func updateOrderStatus(order *database.Order, authzs []*authorization, now time.Time) bool {
+	if order.Status != statusPending && order.Status != statusReady {
+		return false
+	}
+
+	status := order.Status
+	if now.After(order.Expires) {
+		for _, authz := range authzs {
+			if authz.Status == statusPending {
+				authz.Status = statusExpired
+			}
+		}
+		order.Status = statusInvalid
+		return true
+	}
+
+	ready := true
+	for _, authz := range authzs {
+		switch authz.Status {
+		case statusValid:
+		case statusPending:
+			ready = false
+		default:
+			order.Status = statusInvalid
+			return true
+		}
+	}
+
+	if ready {
+		order.Status = statusReady
+	}
+	return order.Status != status
+}
+
+func findChallenge(authz *authorization, typ string) *challenge {
+	for _, ch := range authz.Challenges {
+		if ch.Type == typ {
+			return ch
+		}
+	}
+	return nil
+}
+
+func badCSR(format string, args ...interface{}) *problem {
+	return newProblem(problemBadCSR, http.StatusBadRequest, format, args...)
+}
+
+// newToken creates a random challenge token
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isValidDNSName checks if the name is a valid DNS name, optionally prefixed with a "*." wildcard label. IP
+// addresses are not valid DNS names.
+func isValidDNSName(name string) bool {
+	name = strings.TrimPrefix(name, "*.")
+	if name == "" || len(name) > 253 || net.ParseIP(name) != nil {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
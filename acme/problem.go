@@ -0,0 +1,61 @@
+package acme
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Problem types of RFC 8555 section 6.7
+const (
+	problemAccountDoesNotExist   = "urn:ietf:params:acme:error:accountDoesNotExist"
+	problemBadCSR                = "urn:ietf:params:acme:error:badCSR"
+	problemBadNonce              = "urn:ietf:params:acme:error:badNonce"
+	problemBadPublicKey          = "urn:ietf:params:acme:error:badPublicKey"
+	problemBadSignatureAlgorithm = "urn:ietf:params:acme:error:badSignatureAlgorithm"
+	problemConnection            = "urn:ietf:params:acme:error:connection"
+	problemDNS                   = "urn:ietf:params:acme:error:dns"
+	problemIncorrectResponse     = "urn:ietf:params:acme:error:incorrectResponse"
+	problemInvalidContact        = "urn:ietf:params:acme:error:invalidContact"
+	problemMalformed             = "urn:ietf:params:acme:error:malformed"
+	problemOrderNotReady         = "urn:ietf:params:acme:error:orderNotReady"
+	problemRejectedIdentifier    = "urn:ietf:params:acme:error:rejectedIdentifier"
+	problemServerInternal        = "urn:ietf:params:acme:error:serverInternal"
+	problemUnauthorized          = "urn:ietf:params:acme:error:unauthorized"
+	problemUnsupportedContact    = "urn:ietf:params:acme:error:unsupportedContact"
+	problemUnsupportedIdentifier = "urn:ietf:params:acme:error:unsupportedIdentifier"
+)
+
+// problem is an RFC 7807 problem document as used by ACME
+type problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
+
+func (p *problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Type, p.Detail)
+}
+
+func newProblem(typ string, status int, format string, args ...interface{}) *problem {
+	return &problem{Type: typ, Status: status, Detail: fmt.Sprintf(format, args...)}
+}
+
+func malformed(format string, args ...interface{}) *problem {
+	return newProblem(problemMalformed, http.StatusBadRequest, format, args...)
+}
+
+func unauthorized(format string, args ...interface{}) *problem {
+	return newProblem(problemUnauthorized, http.StatusForbidden, format, args...)
+}
+
+func notFound() *problem {
+	return newProblem(problemMalformed, http.StatusNotFound, "resource not found")
+}
+
+func methodNotAllowed() *problem {
+	return newProblem(problemMalformed, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func serverInternal(err error) *problem {
+	return newProblem(problemServerInternal, http.StatusInternalServerError, "%s", err.Error())
+}
@@ -0,0 +1,305 @@
+// Package api implements a JSON REST API to issue, sign, look up and revoke certificates of the CA.
+//
+// Clients authenticate with a client certificate issued by the same CA. The organizational units of the client
+// certificate are the roles of the client, each role grants a set of permissions (see DefaultRoles). Client
+// certificates that are revoked in the CA database are refused. As clients choose the organizational unit of the
+// certificates they issue or sign, a certificate can only get a role when the client already has all its permissions.
+// The API is described in openapi.yaml.
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Permissions that can be granted to a role
+const (
+	// PermissionIssue allows issuing certificates with a key generated by the server
+	PermissionIssue = "issue"
+	// PermissionSign allows signing CSRs
+	PermissionSign = "sign"
+	// PermissionRead allows listing and looking up certificates
+	PermissionRead = "read"
+	// PermissionRevoke allows revoking certificates
+	PermissionRevoke = "revoke"
+)
+
+// maxRequestSize is the maximum size of a request body
+const maxRequestSize = 64 * 1024
+
+// DefaultRoles are the roles used when no roles are configured
+var DefaultRoles = map[string][]string{
+	"admin":  {PermissionIssue, PermissionSign, PermissionRead, PermissionRevoke},
+	"issuer": {PermissionIssue, PermissionSign, PermissionRead},
+	"reader": {PermissionRead},
+}
+
+var (
+	// ErrorInvalidCA is given if the CA certificate can not be parsed
+	ErrorInvalidCA = errors.New("invalid ca certificate")
+	// ErrorInvalidPermission is given if a role has an unknown permission
+	ErrorInvalidPermission = errors.New("invalid permission")
+
+	errorUnauthenticated = errors.New("a client certificate issued by the ca is required")
+	errorForbidden       = errors.New("permission denied")
+	errorForbiddenRole   = errors.New("permission denied for the role in the organizational unit")
+	errorRevoked         = errors.New("client certificate is revoked")
+	errorNotFound        = errors.New("not found")
+	errorMethod          = errors.New("method not allowed")
+)
+
+// permissionsKey is the context key of the permissions of the authenticated client
+type permissionsKey struct{}
+
+// Config is the configuration of an API server
+type Config struct {
+	// CACrt and CAKey are the PEM encoded CA pair signing the certificates, the CA certificate is trusted for client
+	// authentication as well
+	CACrt []byte
+	CAKey []byte
+	// CASigner signs the certificates instead of CAKey when set, for CA keys kept in an HSM or KMS
+	CASigner crypto.Signer
+	// CAChain holds the PEM encoded intermediates between the CA and the root, they are added to the chain in the
+	// responses
+	CAChain []byte
+
+	// DB stores the issued certificates
+	DB database.DB
+
+	// Roles maps the roles, given as organizational units in the client certificates, to their permissions. When nil
+	// the DefaultRoles are used.
+	Roles map[string][]string
+
+	// Validity is the validity of issued certificates when the request has no not_after, the default (0) is 30 days
+	Validity time.Duration
+
+	// Policy restricts the certificates that are issued and signed when not nil
+	Policy *cert.Policy
+
+	// Serials generates the serial numbers of issued certificates, random serial numbers are used when nil
+	Serials cert.SerialGenerator
+}
+
+// Server is an http.Handler serving the API
+type Server struct {
+	caCrt    []byte
+	caSigner crypto.Signer
+	caChain  []byte
+	ca       *x509.Certificate
+
+	db       database.DB
+	roles    map[string]map[string]bool
+	validity time.Duration
+	policy   *cert.Policy
+	serials  cert.SerialGenerator
+
+	now func() time.Time
+}
+
+// New creates a new API server for the given configuration
+func New(cfg Config) (*Server, error) {
+	if cfg.DB == nil {
+		return nil, database.ErrorNilConnection
+	}
+
+	ca, signer, err := parseCA(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := cfg.Roles
+	if roles == nil {
+		roles = DefaultRoles
+	}
+
+	permissions := make(map[string]map[string]bool, len(roles))
+	for role, perms := range roles {
+		permissions[role] = make(map[string]bool, len(perms))
+		for _, perm := range perms {
+			switch perm {
+			case PermissionIssue, PermissionSign, PermissionRead, PermissionRevoke:
+			default:
+				return nil, ErrorInvalidPermission
+			}
+			permissions[role][perm] = true
+		}
+	}
+
+	validity := cfg.Validity
+	if validity <= 0 {
+		validity = 30 * 24 * time.Hour
+	}
+
+	serials := cfg.Serials
+	if serials == nil {
+		serials = cert.RandomSerialGenerator{}
+	}
+
+	return &Server{
+		caCrt:    cfg.CACrt,
+		caSigner: signer,
+		caChain:  cfg.CAChain,
+		ca:       ca,
+		db:       cfg.DB,
+		roles:    permissions,
+		validity: validity,
+		policy:   cfg.Policy,
+		serials:  serials,
+		now:      time.Now,
+	}, nil
+}
+
+// parseCA parses the CA certificate and returns it with the signer of the configuration, which is created from the
+// CA key when no CASigner is given
+func parseCA(cfg Config) (*x509.Certificate, crypto.Signer, error) {
+	if cfg.CASigner == nil {
+		keyPair, err := tls.X509KeyPair(cfg.CACrt, cfg.CAKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		ca, err := x509.ParseCertificate(keyPair.Certificate[0])
+		if err != nil {
+			return nil, nil, ErrorInvalidCA
+		}
+		signer, ok := keyPair.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, nil, ErrorInvalidCA
+		}
+		return ca, signer, nil
+	}
+
+	if err := cert.CheckSigner(cfg.CACrt, cfg.CASigner); err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(cfg.CACrt)
+	ca, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, ErrorInvalidCA
+	}
+
+	return ca, cfg.CASigner, nil
+}
+
+// TLSConfig returns the TLS configuration requiring clients to present a certificate issued by the CA. The server
+// certificate still has to be configured.
+func (s *Server) TLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.ca)
+
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// ServeHTTP authenticates the client and routes the request to its endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	permissions, status, err := s.authenticate(r)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	var route func(w http.ResponseWriter, r *http.Request) (int, error)
+	var permission string
+	switch {
+	case path == "/v1/certificates" && r.Method == http.MethodGet:
+		route, permission = s.handleList, PermissionRead
+	case path == "/v1/certificates" && r.Method == http.MethodPost:
+		route, permission = s.handleIssue, PermissionIssue
+	case path == "/v1/certificates/sign" && r.Method == http.MethodPost:
+		route, permission = s.handleSign, PermissionSign
+	case len(parts) == 4 && parts[0] == "v1" && parts[1] == "certificates" && parts[2] == "name" && r.Method == http.MethodGet:
+		route, permission = s.handleGetByNameSerialNumber(parts[3]), PermissionRead
+	case len(parts) == 3 && parts[0] == "v1" && parts[1] == "certificates" && r.Method == http.MethodGet:
+		route, permission = s.handleGet(parts[2]), PermissionRead
+	case len(parts) == 4 && parts[0] == "v1" && parts[1] == "certificates" && parts[3] == "revoke" && r.Method == http.MethodPost:
+		route, permission = s.handleRevoke(parts[2]), PermissionRevoke
+	case strings.HasPrefix(path, "/v1/certificates"):
+		writeError(w, http.StatusMethodNotAllowed, errorMethod)
+		return
+	default:
+		writeError(w, http.StatusNotFound, errorNotFound)
+		return
+	}
+
+	if !permissions[permission] {
+		writeError(w, http.StatusForbidden, errorForbidden)
+		return
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), permissionsKey{}, permissions))
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if status, err := route(w, r); err != nil {
+		writeError(w, status, err)
+	}
+}
+
+// authenticate verifies the client certificate and returns the permissions of its roles
+func (s *Server) authenticate(r *http.Request) (map[string]bool, int, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, http.StatusUnauthorized, errorUnauthenticated
+	}
+
+	client := r.TLS.VerifiedChains[0][0]
+	if err := client.CheckSignatureFrom(s.ca); err != nil {
+		return nil, http.StatusUnauthorized, errorUnauthenticated
+	}
+
+	crt, err := s.db.GetCertificateRepository().GetBySerialNumber(client.SerialNumber)
+	switch {
+	case err == nil && crt.Status == database.StatusRevoked:
+		return nil, http.StatusUnauthorized, errorRevoked
+	case err != nil && err != database.ErrorObjectNotFound:
+		return nil, http.StatusInternalServerError, err
+	}
+
+	permissions := make(map[string]bool)
+	for _, role := range client.Subject.OrganizationalUnit {
+		for perm := range s.roles[role] {
+			permissions[perm] = true
+		}
+	}
+	return permissions, 0, nil
+}
+
+// checkRole checks that the client of the request has every permission of the role named by the organizational unit,
+// so clients can not issue certificates for roles they do not have
+func (s *Server) checkRole(r *http.Request, organizationalUnit string) error {
+	permissions, _ := r.Context().Value(permissionsKey{}).(map[string]bool)
+	for perm := range s.roles[organizationalUnit] {
+		if !permissions[perm] {
+			return errorForbiddenRole
+		}
+	}
+	return nil
+}
+
+// errorResponse is the body of error responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,475 @@
+package api
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/mvmaasakkers/certificates/database/file"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPair struct {
+	Crt []byte
+	Key []byte
+}
+
+type testEnv struct {
+	api     *Server
+	ca      *testPair
+	db      database.DB
+	server  *httptest.Server
+	clients map[string]*http.Client
+}
+
+func generateTestCA(t *testing.T, cn string) *testPair {
+	p := &testPair{}
+	var err error
+	p.Crt, p.Key, err = cert.GenerateCA(&cert.Request{CommonName: cn, KeyType: cert.KeyTypeECDSA, NotBefore: time.Now().Add(-time.Minute), NotAfter: time.Now().AddDate(0, 0, 7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func generateTestClient(t *testing.T, ca *testPair, ou string, serialNumber int64) *testPair {
+	p := &testPair{}
+	var err error
+	p.Crt, p.Key, err = cert.GenerateCertificate(&cert.Request{
+		CommonName:         ou + ".client.test.local",
+		OrganizationalUnit: ou,
+		KeyType:            cert.KeyTypeECDSA,
+		SerialNumber:       big.NewInt(serialNumber),
+		NotBefore:          time.Now().Add(-time.Minute),
+		NotAfter:           time.Now().Add(time.Hour),
+	}, ca.Crt, ca.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func newTestDB(t *testing.T) database.DB {
+	db := file.NewDB(filepath.Join(t.TempDir(), "file.db"))
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Provision(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestEnv starts an API server with clients for every default role, a client without role, a revoked admin client
+// and an admin client of another CA
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{
+		ca:      generateTestCA(t, "ca.test.local"),
+		db:      newTestDB(t),
+		clients: make(map[string]*http.Client),
+	}
+
+	s, err := New(Config{CACrt: env.ca.Crt, CAKey: env.ca.Key, DB: env.db})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env.api = s
+	env.server = httptest.NewUnstartedServer(s)
+	env.server.TLS = s.TLSConfig()
+	env.server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	env.server.StartTLS()
+	t.Cleanup(env.server.Close)
+
+	pairs := map[string]*testPair{
+		"admin":   generateTestClient(t, env.ca, "admin", 1),
+		"issuer":  generateTestClient(t, env.ca, "issuer", 2),
+		"reader":  generateTestClient(t, env.ca, "reader", 3),
+		"none":    generateTestClient(t, env.ca, "", 4),
+		"revoked": generateTestClient(t, env.ca, "admin", 5),
+		"other":   generateTestClient(t, generateTestCA(t, "other.test.local"), "admin", 6),
+	}
+
+	revoked := database.NewCertificate()
+	revoked.Status = database.StatusValid
+	revoked.NameSerialNumber = revoked.UUID
+	if err := revoked.SetCertificate(pairs["revoked"].Crt); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.db.GetCertificateRepository().Create(revoked); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.db.GetCertificateRepository().Revoke(big.NewInt(5), cert.ReasonKeyCompromise, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, pair := range pairs {
+		keyPair, err := tls.X509KeyPair(pair.Crt, pair.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		transport := env.server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = []tls.Certificate{keyPair}
+		env.clients[name] = &http.Client{Transport: transport}
+	}
+	env.clients["anonymous"] = env.server.Client()
+
+	return env
+}
+
+// do sends the request as the given client and decodes the JSON response into v when it is not nil
+func (env *testEnv) do(t *testing.T, client string, method string, path string, body interface{}, v interface{}) int {
+	var r *bytes.Reader
+	if body != nil {
+		d, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(d)
+	} else {
+		r = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, env.server.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := env.clients[client].Do(req)
+	if err != nil {
+		// The TLS handshake fails for clients without a valid certificate
+		return 0
+	}
+	defer res.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.StatusCode
+}
+
+func TestNew(t *testing.T) {
+	db := newTestDB(t)
+	ca := generateTestCA(t, "ca.test.local")
+	other := generateTestCA(t, "other.test.local")
+
+	caKeyPair, err := tls.X509KeyPair(ca.Crt, ca.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeyPair, err := tls.X509KeyPair(other.Crt, other.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "ok", cfg: Config{CACrt: ca.Crt, CAKey: ca.Key, DB: db}, wantErr: false},
+		{name: "custom_roles", cfg: Config{CACrt: ca.Crt, CAKey: ca.Key, DB: db, Roles: map[string][]string{"pipeline": {PermissionSign}}}, wantErr: false},
+		{name: "invalid_permission", cfg: Config{CACrt: ca.Crt, CAKey: ca.Key, DB: db, Roles: map[string][]string{"pipeline": {"delete"}}}, wantErr: true},
+		{name: "nil_db", cfg: Config{CACrt: ca.Crt, CAKey: ca.Key}, wantErr: true},
+		{name: "invalid_ca", cfg: Config{CACrt: []byte("invalid"), CAKey: ca.Key, DB: db}, wantErr: true},
+		{name: "mismatching_key", cfg: Config{CACrt: ca.Crt, CAKey: other.Key, DB: db}, wantErr: true},
+		{name: "signer", cfg: Config{CACrt: ca.Crt, CASigner: caKeyPair.PrivateKey.(crypto.Signer), DB: db}, wantErr: false},
+		{name: "mismatching_signer", cfg: Config{CACrt: ca.Crt, CASigner: otherKeyPair.PrivateKey.(crypto.Signer), DB: db}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_Issue(t *testing.T) {
+	env := newTestEnv(t)
+
+	notAfter := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	var res IssueResponse
+	status := env.do(t, "issuer", http.MethodPost, "/v1/certificates", IssueRequest{
+		CommonName:       "app.test.local",
+		SubjectAltNames:  []string{"app.test.local", "www.app.test.local"},
+		NameSerialNumber: "app-1",
+		KeyType:          cert.KeyTypeECDSA,
+		NotAfter:         &notAfter,
+	}, &res)
+	if status != http.StatusCreated {
+		t.Fatalf("issue status = %d, want %d", status, http.StatusCreated)
+	}
+
+	if res.Certificate.CommonName != "app.test.local" || res.Certificate.NameSerialNumber != "app-1" || len(res.Certificate.SubjectAltNames) != 2 {
+		t.Errorf("issued certificate = %+v", res.Certificate)
+	}
+	if !res.Certificate.ExpirationDate.Equal(notAfter) {
+		t.Errorf("expiration date = %s, want %s", res.Certificate.ExpirationDate, notAfter)
+	}
+	if _, err := tls.X509KeyPair([]byte(res.CertificatePEM), []byte(res.PrivateKeyPEM)); err != nil {
+		t.Errorf("issued pair is invalid: %s", err)
+	}
+
+	if _, err := env.db.GetCertificateRepository().GetByNameSerialNumber("app-1"); err != nil {
+		t.Errorf("issued certificate is not stored: %s", err)
+	}
+
+	// The name serial number has to be unique
+	if status := env.do(t, "issuer", http.MethodPost, "/v1/certificates", IssueRequest{CommonName: "app.test.local", NameSerialNumber: "app-1", KeyType: cert.KeyTypeECDSA}, nil); status != http.StatusConflict {
+		t.Errorf("duplicate issue status = %d, want %d", status, http.StatusConflict)
+	}
+
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{name: "missing_cn", body: IssueRequest{KeyType: cert.KeyTypeECDSA}},
+		{name: "invalid_key_type", body: IssueRequest{CommonName: "app.test.local", KeyType: "dsa"}},
+		{name: "invalid_validity", body: IssueRequest{CommonName: "app.test.local", KeyType: cert.KeyTypeECDSA, NotAfter: &time.Time{}}},
+		{name: "unknown_field", body: map[string]string{"cn": "app.test.local"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := env.do(t, "issuer", http.MethodPost, "/v1/certificates", tt.body, nil); status != http.StatusBadRequest {
+				t.Errorf("issue status = %d, want %d", status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestServer_Sign(t *testing.T) {
+	env := newTestEnv(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "csr.test.local"}, DNSNames: []string{"csr.test.local"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+
+	var res IssueResponse
+	if status := env.do(t, "issuer", http.MethodPost, "/v1/certificates/sign", SignRequest{CSR: csr}, &res); status != http.StatusCreated {
+		t.Fatalf("sign status = %d, want %d", status, http.StatusCreated)
+	}
+
+	if res.PrivateKeyPEM != "" {
+		t.Errorf("sign response contains a private key")
+	}
+	if res.Certificate.CommonName != "csr.test.local" || res.Certificate.NameSerialNumber == "" {
+		t.Errorf("signed certificate = %+v", res.Certificate)
+	}
+
+	block, _ := pem.Decode([]byte(res.CertificatePEM))
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey.Equal(crt.PublicKey) {
+		t.Errorf("signed certificate does not hold the csr key")
+	}
+
+	if status := env.do(t, "issuer", http.MethodPost, "/v1/certificates/sign", SignRequest{CSR: "invalid"}, nil); status != http.StatusBadRequest {
+		t.Errorf("invalid csr status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestServer_Policy(t *testing.T) {
+	env := newTestEnv(t)
+	env.api.policy = &cert.Policy{AllowedDomains: []string{"test.local"}, KeyTypes: []string{cert.KeyTypeECDSA}}
+
+	if status := env.do(t, "issuer", http.MethodPost, "/v1/certificates", IssueRequest{CommonName: "app.test.local", KeyType: cert.KeyTypeECDSA}, nil); status != http.StatusCreated {
+		t.Errorf("issue status = %d, want %d", status, http.StatusCreated)
+	}
+
+	var res errorResponse
+	if status := env.do(t, "issuer", http.MethodPost, "/v1/certificates", IssueRequest{CommonName: "app.example.com", KeyType: cert.KeyTypeECDSA}, &res); status != http.StatusForbidden {
+		t.Errorf("issue status = %d, want %d", status, http.StatusForbidden)
+	}
+	if !strings.Contains(res.Error, "app.example.com") {
+		t.Errorf("issue error = %q, want a description of the violation", res.Error)
+	}
+
+	csr, _, err := cert.GenerateCSR(&cert.Request{CommonName: "csr.test.local", KeyType: cert.KeyTypeEd25519})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := env.do(t, "issuer", http.MethodPost, "/v1/certificates/sign", SignRequest{CSR: string(csr)}, &res); status != http.StatusForbidden {
+		t.Errorf("sign status = %d, want %d", status, http.StatusForbidden)
+	}
+	if !strings.Contains(res.Error, "key type") {
+		t.Errorf("sign error = %q, want a description of the violation", res.Error)
+	}
+}
+
+func TestServer_LookupAndRevoke(t *testing.T) {
+	env := newTestEnv(t)
+
+	var issued IssueResponse
+	if status := env.do(t, "admin", http.MethodPost, "/v1/certificates", IssueRequest{CommonName: "app.test.local", NameSerialNumber: "app-1", KeyType: cert.KeyTypeECDSA}, &issued); status != http.StatusCreated {
+		t.Fatalf("issue status = %d", status)
+	}
+	serial := issued.Certificate.SerialNumber
+
+	var got Certificate
+	if status := env.do(t, "reader", http.MethodGet, "/v1/certificates/"+serial, nil, &got); status != http.StatusOK {
+		t.Fatalf("get status = %d", status)
+	}
+	if got.NameSerialNumber != "app-1" || got.CertificatePEM != issued.CertificatePEM {
+		t.Errorf("get certificate = %+v", got)
+	}
+
+	got = Certificate{}
+	if status := env.do(t, "reader", http.MethodGet, "/v1/certificates/name/app-1", nil, &got); status != http.StatusOK || got.SerialNumber != serial {
+		t.Errorf("get by name status = %d, serial = %s", status, got.SerialNumber)
+	}
+
+	var revoked Certificate
+	if status := env.do(t, "admin", http.MethodPost, "/v1/certificates/"+serial+"/revoke", RevokeRequest{Reason: "superseded"}, &revoked); status != http.StatusOK {
+		t.Fatalf("revoke status = %d", status)
+	}
+	if revoked.Status != database.StatusRevoked || revoked.RevocationReason != "superseded" || revoked.RevocationDate == nil {
+		t.Errorf("revoked certificate = %+v", revoked)
+	}
+
+	var list ListResponse
+	if status := env.do(t, "reader", http.MethodGet, "/v1/certificates?status=revoked&cn=app", nil, &list); status != http.StatusOK {
+		t.Fatalf("list status = %d", status)
+	}
+	if len(list.Certificates) != 1 || list.Certificates[0].SerialNumber != serial || list.Certificates[0].CertificatePEM != "" {
+		t.Errorf("list = %+v", list.Certificates)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		wantStatus int
+	}{
+		{name: "already_revoked", method: http.MethodPost, path: "/v1/certificates/" + serial + "/revoke", wantStatus: http.StatusConflict},
+		{name: "revoke_not_found", method: http.MethodPost, path: "/v1/certificates/4242/revoke", wantStatus: http.StatusNotFound},
+		{name: "revoke_invalid_reason", method: http.MethodPost, path: "/v1/certificates/" + serial + "/revoke", body: RevokeRequest{Reason: "bored"}, wantStatus: http.StatusBadRequest},
+		{name: "get_not_found", method: http.MethodGet, path: "/v1/certificates/0x4242", wantStatus: http.StatusNotFound},
+		{name: "get_invalid_serial", method: http.MethodGet, path: "/v1/certificates/invalid", wantStatus: http.StatusBadRequest},
+		{name: "get_zero_serial", method: http.MethodGet, path: "/v1/certificates/0", wantStatus: http.StatusBadRequest},
+		{name: "get_name_not_found", method: http.MethodGet, path: "/v1/certificates/name/unknown", wantStatus: http.StatusNotFound},
+		{name: "list_invalid_status", method: http.MethodGet, path: "/v1/certificates?status=expired", wantStatus: http.StatusBadRequest},
+		{name: "list_invalid_time", method: http.MethodGet, path: "/v1/certificates?issued_after=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "list_invalid_limit", method: http.MethodGet, path: "/v1/certificates?limit=-1", wantStatus: http.StatusBadRequest},
+		{name: "unknown_path", method: http.MethodGet, path: "/v2/certificates", wantStatus: http.StatusNotFound},
+		{name: "method_not_allowed", method: http.MethodDelete, path: "/v1/certificates/" + serial, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := env.do(t, "admin", tt.method, tt.path, tt.body, nil); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServer_Permissions(t *testing.T) {
+	env := newTestEnv(t)
+
+	issue := IssueRequest{CommonName: "app.test.local", KeyType: cert.KeyTypeECDSA}
+	tests := []struct {
+		client     string
+		method     string
+		path       string
+		body       interface{}
+		wantStatus int
+	}{
+		{client: "admin", method: http.MethodGet, path: "/v1/certificates", wantStatus: http.StatusOK},
+		{client: "admin", method: http.MethodPost, path: "/v1/certificates", body: issue, wantStatus: http.StatusCreated},
+		{client: "admin", method: http.MethodPost, path: "/v1/certificates/1/revoke", wantStatus: http.StatusNotFound},
+		{client: "issuer", method: http.MethodPost, path: "/v1/certificates", body: issue, wantStatus: http.StatusCreated},
+		{client: "issuer", method: http.MethodPost, path: "/v1/certificates/1/revoke", wantStatus: http.StatusForbidden},
+		{client: "reader", method: http.MethodGet, path: "/v1/certificates", wantStatus: http.StatusOK},
+		{client: "reader", method: http.MethodPost, path: "/v1/certificates", body: issue, wantStatus: http.StatusForbidden},
+		{client: "reader", method: http.MethodPost, path: "/v1/certificates/sign", body: SignRequest{}, wantStatus: http.StatusForbidden},
+		{client: "none", method: http.MethodGet, path: "/v1/certificates", wantStatus: http.StatusForbidden},
+		{client: "revoked", method: http.MethodGet, path: "/v1/certificates", wantStatus: http.StatusUnauthorized},
+		{client: "other", method: http.MethodGet, path: "/v1/certificates", wantStatus: 0},
+		{client: "anonymous", method: http.MethodGet, path: "/v1/certificates", wantStatus: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.client+"_"+tt.method+"_"+strings.ReplaceAll(tt.path, "/", "_"), func(t *testing.T) {
+			if status := env.do(t, tt.client, tt.method, tt.path, tt.body, nil); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServer_RoleEscalation(t *testing.T) {
+	env := newTestEnv(t)
+
+	csr := func(ou string) string {
+		csr, _, err := cert.GenerateCSR(&cert.Request{CommonName: "csr.test.local", OrganizationalUnit: ou, KeyType: cert.KeyTypeECDSA})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(csr)
+	}
+
+	tests := []struct {
+		name       string
+		client     string
+		path       string
+		body       interface{}
+		wantStatus int
+	}{
+		{name: "issue_admin", client: "issuer", path: "/v1/certificates", body: IssueRequest{CommonName: "app.test.local", OrganizationalUnit: "admin", KeyType: cert.KeyTypeECDSA}, wantStatus: http.StatusForbidden},
+		{name: "sign_admin", client: "issuer", path: "/v1/certificates/sign", body: SignRequest{CSR: csr("admin")}, wantStatus: http.StatusForbidden},
+		{name: "issue_issuer", client: "issuer", path: "/v1/certificates", body: IssueRequest{CommonName: "app.test.local", OrganizationalUnit: "issuer", KeyType: cert.KeyTypeECDSA}, wantStatus: http.StatusCreated},
+		{name: "issue_reader", client: "issuer", path: "/v1/certificates", body: IssueRequest{CommonName: "app.test.local", OrganizationalUnit: "reader", KeyType: cert.KeyTypeECDSA}, wantStatus: http.StatusCreated},
+		{name: "issue_other_unit", client: "issuer", path: "/v1/certificates", body: IssueRequest{CommonName: "app.test.local", OrganizationalUnit: "web", KeyType: cert.KeyTypeECDSA}, wantStatus: http.StatusCreated},
+		{name: "admin_issue_admin", client: "admin", path: "/v1/certificates", body: IssueRequest{CommonName: "app.test.local", OrganizationalUnit: "admin", KeyType: cert.KeyTypeECDSA}, wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := env.do(t, tt.client, http.MethodPost, tt.path, tt.body, nil); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServer_NoClientCertificate(t *testing.T) {
+	ca := generateTestCA(t, "ca.test.local")
+	s, err := New(Config{CACrt: ca.Crt, CAKey: ca.Key, DB: newTestDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/certificates", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("ServeHTTP() status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errorInvalidValidity = errors.New("not_after has to be after not_before")
+)
+
+// Certificate is the JSON representation of a certificate in the CA database
+type Certificate struct {
+	SerialNumber     string     `json:"serial_number"`
+	NameSerialNumber string     `json:"name_serial_number"`
+	CommonName       string     `json:"common_name"`
+	SubjectAltNames  []string   `json:"subject_alt_names"`
+	Issuer           string     `json:"issuer"`
+	KeyAlgorithm     string     `json:"key_algorithm"`
+	Fingerprint      string     `json:"fingerprint_sha256"`
+	SubjectKeyID     string     `json:"subject_key_id"`
+	Status           string     `json:"status"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	ExpirationDate   time.Time  `json:"expiration_date"`
+	RevocationDate   *time.Time `json:"revocation_date,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+	CertificatePEM   string     `json:"certificate_pem,omitempty"`
+}
+
+// IssueRequest is the body of an issue request. The key is generated by the server.
+type IssueRequest struct {
+	CommonName         string     `json:"common_name"`
+	Organization       string     `json:"organization"`
+	OrganizationalUnit string     `json:"organizational_unit"`
+	Country            string     `json:"country"`
+	Province           string     `json:"province"`
+	Locality           string     `json:"locality"`
+	StreetAddress      string     `json:"street_address"`
+	PostalCode         string     `json:"postal_code"`
+	SubjectAltNames    []string   `json:"subject_alt_names"`
+	NameSerialNumber   string     `json:"name_serial_number"`
+	KeyType            string     `json:"key_type"`
+	BitSize            int        `json:"bit_size"`
+	Curve              string     `json:"curve"`
+	NotBefore          *time.Time `json:"not_before"`
+	NotAfter           *time.Time `json:"not_after"`
+}
+
+// SignRequest is the body of a sign request. The subject and subject alt names are taken from the PEM encoded CSR.
+type SignRequest struct {
+	CSR              string     `json:"csr"`
+	NameSerialNumber string     `json:"name_serial_number"`
+	NotBefore        *time.Time `json:"not_before"`
+	NotAfter         *time.Time `json:"not_after"`
+}
+
+// IssueResponse is the response of issue and sign requests. The PrivateKeyPEM is only set when the key was
+// generated by the server. The ChainPEM holds the intermediates between the certificate and the root.
+type IssueResponse struct {
+	Certificate    Certificate `json:"certificate"`
+	CertificatePEM string      `json:"certificate_pem"`
+	PrivateKeyPEM  string      `json:"private_key_pem,omitempty"`
+	ChainPEM       string      `json:"chain_pem"`
+}
+
+// RevokeRequest is the body of a revoke request, the Reason is an RFC 5280 reason name or code
+type RevokeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListResponse is the response of a list request
+type ListResponse struct {
+	Certificates []Certificate `json:"certificates"`
+}
+
+func newCertificate(crt *database.Certificate, withPEM bool) Certificate {
+	c := Certificate{
+		NameSerialNumber: crt.NameSerialNumber,
+		CommonName:       crt.CommonName,
+		SubjectAltNames:  []string{},
+		Issuer:           crt.Issuer,
+		KeyAlgorithm:     crt.KeyAlgorithm,
+		Fingerprint:      crt.Fingerprint,
+		SubjectKeyID:     crt.SubjectKeyID,
+		Status:           crt.Status,
+		IssuedAt:         crt.CreatedAt,
+		ExpirationDate:   crt.ExpirationDate,
+		RevocationDate:   crt.RevocationDate,
+	}
+
+	if crt.SerialNumber != nil {
+		c.SerialNumber = crt.SerialNumber.String()
+	}
+	if crt.SubjectAltNames != "" {
+		c.SubjectAltNames = strings.Split(crt.SubjectAltNames, ",")
+	}
+	if crt.Status == database.StatusRevoked {
+		c.RevocationReason = cert.RevocationReasonName(crt.RevocationReason)
+	}
+	if withPEM {
+		c.CertificatePEM = crt.CertificatePEM
+	}
+
+	return c
+}
+
+func (s *Server) handleIssue(w http.ResponseWriter, r *http.Request) (int, error) {
+	var req IssueRequest
+	if err := decode(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	cr := cert.NewRequest()
+	cr.CommonName = req.CommonName
+	cr.Organization = req.Organization
+	cr.OrganizationalUnit = req.OrganizationalUnit
+	cr.Country = req.Country
+	cr.Province = req.Province
+	cr.Locality = req.Locality
+	cr.StreetAddress = req.StreetAddress
+	cr.PostalCode = req.PostalCode
+	cr.SubjectAltNames = req.SubjectAltNames
+	cr.NameSerialNumber = req.NameSerialNumber
+	cr.KeyType = req.KeyType
+	cr.BitSize = req.BitSize
+	cr.Curve = req.Curve
+
+	if status, err := s.prepareRequest(r, cr, req.NotBefore, req.NotAfter); err != nil {
+		return status, err
+	}
+
+	return s.issue(w, cr, func() ([]byte, []byte, error) {
+		return cert.GenerateCertificateWithSigner(cr, s.caCrt, s.caSigner)
+	})
+}
+
+func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) (int, error) {
+	var req SignRequest
+	if err := decode(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	cr, err := cert.ReadCSR([]byte(req.CSR))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if req.NameSerialNumber != "" {
+		cr.NameSerialNumber = req.NameSerialNumber
+	}
+
+	if status, err := s.prepareRequest(r, cr, req.NotBefore, req.NotAfter); err != nil {
+		return status, err
+	}
+
+	return s.issue(w, cr, func() ([]byte, []byte, error) {
+		crt, err := cert.SignCSRWithSigner(cr, []byte(req.CSR), s.caCrt, s.caSigner)
+		return crt, nil, err
+	})
+}
+
+// prepareRequest checks the role in the organizational unit, sets the validity and name serial number of the request
+// and validates it
+func (s *Server) prepareRequest(r *http.Request, cr *cert.Request, notBefore *time.Time, notAfter *time.Time) (int, error) {
+	if err := s.checkRole(r, cr.OrganizationalUnit); err != nil {
+		return http.StatusForbidden, err
+	}
+
+	now := s.now()
+	cr.NotBefore = now
+	if notBefore != nil {
+		cr.NotBefore = *notBefore
+	}
+	cr.NotAfter = cr.NotBefore.Add(s.validity)
+	if notAfter != nil {
+		cr.NotAfter = *notAfter
+	}
+	if !cr.NotAfter.After(cr.NotBefore) {
+		return http.StatusBadRequest, errorInvalidValidity
+	}
+
+	cr.Policy = s.policy
+	cr.CAChain = s.caChain
+
+	if cr.NameSerialNumber == "" {
+		sn, err := uuid.NewRandom()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		cr.NameSerialNumber = sn.String()
+	}
+
+	if err := cr.Validate(); err != nil {
+		return http.StatusBadRequest, err
+	}
+	return 0, nil
+}
+
+// issue issues the certificate of the request with sign, stores it in the CA database and writes the issue response.
+// The request gets a serial number that is not taken yet, sign is called again when it turns out to be taken.
+func (s *Server) issue(w http.ResponseWriter, cr *cert.Request, sign func() ([]byte, []byte, error)) (int, error) {
+	repo := s.db.GetCertificateRepository()
+
+	var crt, key []byte
+	var DBCert *database.Certificate
+	err := database.WithUniqueSerialNumber(repo, s.serials, func(serialNumber *big.Int) error {
+		cr.SerialNumber = serialNumber
+
+		var err error
+		crt, key, err = sign()
+		if err != nil {
+			return err
+		}
+
+		DBCert = database.NewCertificate()
+		DBCert.Status = database.StatusValid
+		DBCert.NameSerialNumber = cr.NameSerialNumber
+		if err := DBCert.SetCertificate(crt); err != nil {
+			return err
+		}
+		return repo.Create(DBCert)
+	})
+	switch {
+	case err == nil:
+	case err == cert.ErrorInvalidCSR, err == cert.ErrorInvalidCSRSignature:
+		return http.StatusBadRequest, err
+	case errors.Is(err, cert.ErrorPolicyViolation), errors.Is(err, cert.ErrorNameConstraintViolation):
+		return http.StatusForbidden, err
+	case err == database.ErrorDuplicateObject:
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, err
+	}
+
+	chain, err := cert.Chain(s.caCrt, s.caChain)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	writeJSON(w, http.StatusCreated, IssueResponse{
+		Certificate:    newCertificate(DBCert, false),
+		CertificatePEM: string(crt),
+		PrivateKeyPEM:  string(key),
+		ChainPEM:       string(chain),
+	})
+	return 0, nil
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) (int, error) {
+	query := r.URL.Query()
+	filter := database.CertificateFilter{
+		Status:     query.Get("status"),
+		CommonName: query.Get("cn"),
+	}
+
+	switch filter.Status {
+	case "", database.StatusValid, database.StatusRevoked:
+	default:
+		return http.StatusBadRequest, fmt.Errorf("invalid status %q", filter.Status)
+	}
+
+	for name, field := range map[string]**time.Time{"expires_before": &filter.ExpiresBefore, "issued_after": &filter.IssuedAfter} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return http.StatusBadRequest, fmt.Errorf("invalid %s: %s", name, err.Error())
+			}
+			*field = &t
+		}
+	}
+
+	for name, field := range map[string]*int{"offset": &filter.Offset, "limit": &filter.Limit} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return http.StatusBadRequest, fmt.Errorf("invalid %s", name)
+			}
+			*field = n
+		}
+	}
+
+	crts, err := s.db.GetCertificateRepository().List(filter)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	res := ListResponse{Certificates: make([]Certificate, 0, len(crts))}
+	for _, crt := range crts {
+		res.Certificates = append(res.Certificates, newCertificate(crt, false))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+	return 0, nil
+}
+
+func (s *Server) handleGet(serial string) func(w http.ResponseWriter, r *http.Request) (int, error) {
+	return func(w http.ResponseWriter, r *http.Request) (int, error) {
+		serialNumber, err := cert.ParseSerialNumber(serial)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		crt, err := s.db.GetCertificateRepository().GetBySerialNumber(serialNumber)
+		return s.writeCertificate(w, crt, err)
+	}
+}
+
+func (s *Server) handleGetByNameSerialNumber(nameSerialNumber string) func(w http.ResponseWriter, r *http.Request) (int, error) {
+	return func(w http.ResponseWriter, r *http.Request) (int, error) {
+		crt, err := s.db.GetCertificateRepository().GetByNameSerialNumber(nameSerialNumber)
+		return s.writeCertificate(w, crt, err)
+	}
+}
+
+func (s *Server) handleRevoke(serial string) func(w http.ResponseWriter, r *http.Request) (int, error) {
+	return func(w http.ResponseWriter, r *http.Request) (int, error) {
+		serialNumber, err := cert.ParseSerialNumber(serial)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		req := RevokeRequest{Reason: "unspecified"}
+		if r.ContentLength != 0 {
+			if err := decode(r, &req); err != nil {
+				return http.StatusBadRequest, err
+			}
+		}
+
+		reason, err := cert.ParseRevocationReason(req.Reason)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		repo := s.db.GetCertificateRepository()
+		switch err := repo.Revoke(serialNumber, reason, s.now()); err {
+		case nil:
+		case database.ErrorObjectNotFound:
+			return http.StatusNotFound, err
+		case database.ErrorAlreadyRevoked:
+			return http.StatusConflict, err
+		default:
+			return http.StatusInternalServerError, err
+		}
+
+		crt, err := repo.GetBySerialNumber(serialNumber)
+		return s.writeCertificate(w, crt, err)
+	}
+}
+
+// writeCertificate writes the certificate found by a repository lookup, or the status of the lookup error
+func (s *Server) writeCertificate(w http.ResponseWriter, crt *database.Certificate, err error) (int, error) {
+	switch err {
+	case nil:
+	case database.ErrorObjectNotFound:
+		return http.StatusNotFound, err
+	default:
+		return http.StatusInternalServerError, err
+	}
+
+	writeJSON(w, http.StatusOK, newCertificate(crt, true))
+	return 0, nil
+}
+
+// decode decodes the JSON request body into v, unknown fields are not allowed
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request: %s", err.Error())
+	}
+	return nil
+}
@@ -2,6 +2,9 @@ package cert
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -10,6 +13,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 )
 
@@ -32,12 +36,35 @@ type Request struct {
 	NotBefore time.Time
 	NotAfter  time.Time
 
+	KeyType string
 	BitSize int
+	Curve   string
+
+	// MaxPathLen and MaxPathLenZero are only used for CA certificates and follow the semantics of the
+	// x509.Certificate fields with the same name.
+	MaxPathLen     int
+	MaxPathLenZero bool
+
+	// NameConstraints restrict the names of the certificates issued by a CA when not nil, they are only used for CA
+	// certificates
+	NameConstraints *NameConstraints
+	// CAChain holds the PEM encoded CAs above the issuing CA. The names of the certificate, or the name constraints
+	// of an intermediate CA, are checked against the name constraints of these CAs as well as the issuing CA.
+	CAChain []byte
+
+	// Profile is the name of the profile setting the key usages of end entity certificates, the default profile is
+	// used when empty. Profiles holds the available profiles, the DefaultProfiles are used when nil (see
+	// LoadProfiles). Both are not used for CA certificates.
+	Profile  string
+	Profiles map[string]*Profile
+
+	// Policy is checked before signing end entity certificates when not nil
+	Policy *Policy
 }
 
 const defaultBitSize = 4096
 
-var bitSizeOptions = []int{2048, 4096}
+var bitSizeOptions = []int{2048, 3072, 4096}
 
 func isValidBitSizeOption(option int) bool {
 	for _, v := range bitSizeOptions {
@@ -54,7 +81,9 @@ func NewRequest() *Request {
 	return &Request{
 		NotBefore: time.Now(),
 		NotAfter:  time.Now().AddDate(0, 0, 1),
+		KeyType:   defaultKeyType,
 		BitSize:   defaultBitSize,
+		Curve:     defaultCurve,
 	}
 }
 
@@ -62,23 +91,63 @@ func NewRequest() *Request {
 //
 // The checks are:
 // - A Common Name is mandatory
-// - If a list of SubjectAltNames is given, none of them can be empty
+// - The KeyType must be rsa (default), ecdsa or ed25519
+// - For rsa keys the BitSize must be 2048, 3072 or 4096 (default)
+// - For ecdsa keys the Curve must be P-256 (default), P-384 or P-521
+// - If a list of SubjectAltNames is given, all of them must be valid (see ParseSubjectAltNames)
+// - The Profile must be one of the Profiles
+// - The NotAfter must not be before the NotBefore
+// - If NameConstraints are given, all of them must be valid (see NameConstraints)
 func (req *Request) Validate() error {
 	if req.CommonName == "" {
 		return ErrorInvalidCommonName
 	}
 
-	if req.BitSize == 0 {
-		req.BitSize = defaultBitSize
+	if req.NotAfter.Before(req.NotBefore) {
+		return ErrorInvalidValidity
 	}
 
-	if !isValidBitSizeOption(req.BitSize) {
-		return ErrorInvalidBitSize
+	if req.KeyType == "" {
+		req.KeyType = defaultKeyType
 	}
 
-	for _, n := range req.SubjectAltNames {
-		if n == "" {
-			return ErrorInvalidSubjectAltName
+	req.KeyType = strings.ToLower(req.KeyType)
+	if !isValidKeyTypeOption(req.KeyType) {
+		return ErrorInvalidKeyType
+	}
+
+	switch req.KeyType {
+	case KeyTypeRSA:
+		if req.BitSize == 0 {
+			req.BitSize = defaultBitSize
+		}
+
+		if !isValidBitSizeOption(req.BitSize) {
+			return ErrorInvalidBitSize
+		}
+	case KeyTypeECDSA:
+		if req.Curve == "" {
+			req.Curve = defaultCurve
+		}
+
+		curve := normalizeCurve(req.Curve)
+		if curve == "" {
+			return ErrorInvalidCurve
+		}
+		req.Curve = curve
+	}
+
+	if _, err := ParseSubjectAltNames(req.SubjectAltNames); err != nil {
+		return err
+	}
+
+	if _, err := req.profile(); err != nil {
+		return err
+	}
+
+	if req.NameConstraints != nil {
+		if err := req.NameConstraints.apply(&x509.Certificate{}); err != nil {
+			return err
 		}
 	}
 
@@ -133,7 +202,8 @@ func (req *Request) GetPKIXName() pkix.Name {
 	return name
 }
 
-// ReadCSR reads csr into a x509.CertificateRequest and converts it into a Request
+// ReadCSR reads csr into a x509.CertificateRequest and converts it into a Request. The signature of the CSR is
+// checked to make sure the requester owns the private key.
 func ReadCSR(csrFile []byte) (*Request, error) {
 	block, _ := pem.Decode(csrFile)
 	if block == nil || block.Type != "CERTIFICATE REQUEST" {
@@ -145,29 +215,11 @@ func ReadCSR(csrFile []byte) (*Request, error) {
 		return nil, err
 	}
 
-	request := NewRequest()
-	if len(csr.Subject.Organization) > 0 {
-		request.Organization = csr.Subject.Organization[0]
+	if err := csr.CheckSignature(); err != nil {
+		return nil, ErrorInvalidCSRSignature
 	}
-	if len(csr.Subject.Country) > 0 {
-		request.Country = csr.Subject.Country[0]
-	}
-	if len(csr.Subject.Province) > 0 {
-		request.Province = csr.Subject.Province[0]
-	}
-	if len(csr.Subject.Locality) > 0 {
-		request.Locality = csr.Subject.Locality[0]
-	}
-	if len(csr.Subject.StreetAddress) > 0 {
-		request.StreetAddress = csr.Subject.StreetAddress[0]
-	}
-	if len(csr.Subject.PostalCode) > 0 {
-		request.PostalCode = csr.Subject.PostalCode[0]
-	}
-	request.CommonName = csr.Subject.CommonName
-	request.NameSerialNumber = csr.Subject.SerialNumber
-	request.SubjectAltNames = csr.DNSNames
 
+	request := requestFromCSR(csr)
 	if request.SerialNumber == nil {
 		randInt, err := GenerateRandomBigInt()
 		if err != nil {
@@ -180,31 +232,196 @@ func ReadCSR(csrFile []byte) (*Request, error) {
 	return request, nil
 }
 
+// ReadCertificate reads the PEM encoded certificate and converts it into a Request to renew the certificate. The
+// Request gets the subject and subject alt names of the certificate, a key type, bit size and curve matching its
+// public key and a validity of the same length starting now. The serial number and profile are left for the renewal
+// to decide.
+func ReadCertificate(crtFile []byte) (*Request, error) {
+	block, _ := pem.Decode(crtFile)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, ErrorInvalidCertificate
+	}
+
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	request := requestFromName(crt.Subject, &SubjectAltNames{
+		DNSNames:       crt.DNSNames,
+		IPAddresses:    crt.IPAddresses,
+		EmailAddresses: crt.EmailAddresses,
+		URIs:           crt.URIs,
+	})
+
+	request.NotBefore = time.Now()
+	request.NotAfter = request.NotBefore.Add(crt.NotAfter.Sub(crt.NotBefore))
+
+	switch pub := crt.PublicKey.(type) {
+	case *rsa.PublicKey:
+		request.KeyType = KeyTypeRSA
+		request.BitSize = pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		request.KeyType = KeyTypeECDSA
+		request.Curve = pub.Curve.Params().Name
+	case ed25519.PublicKey:
+		request.KeyType = KeyTypeEd25519
+	}
+
+	return request, nil
+}
+
+// requestFromCSR returns a Request with the subject and subject alt names of the CSR
+func requestFromCSR(csr *x509.CertificateRequest) *Request {
+	return requestFromName(csr.Subject, &SubjectAltNames{
+		DNSNames:       csr.DNSNames,
+		IPAddresses:    csr.IPAddresses,
+		EmailAddresses: csr.EmailAddresses,
+		URIs:           csr.URIs,
+	})
+}
+
+// requestFromName returns a Request with the subject and subject alt names
+func requestFromName(subject pkix.Name, sans *SubjectAltNames) *Request {
+	request := NewRequest()
+	if len(subject.Organization) > 0 {
+		request.Organization = subject.Organization[0]
+	}
+	if len(subject.OrganizationalUnit) > 0 {
+		request.OrganizationalUnit = subject.OrganizationalUnit[0]
+	}
+	if len(subject.Country) > 0 {
+		request.Country = subject.Country[0]
+	}
+	if len(subject.Province) > 0 {
+		request.Province = subject.Province[0]
+	}
+	if len(subject.Locality) > 0 {
+		request.Locality = subject.Locality[0]
+	}
+	if len(subject.StreetAddress) > 0 {
+		request.StreetAddress = subject.StreetAddress[0]
+	}
+	if len(subject.PostalCode) > 0 {
+		request.PostalCode = subject.PostalCode[0]
+	}
+	request.CommonName = subject.CommonName
+	request.NameSerialNumber = subject.SerialNumber
+	request.SubjectAltNames = sans.Strings()
+
+	return request
+}
+
 // GenerateCertificate will generate a signed certificate pair and will return certificate, key and a possible error
-// The Generated key will be in RSA format and has a bit size of 4096 and output of the Certificate
-// and Key will be returned in PEM format as bytes.
+// The Generated key will be of the KeyType given in the Request (RSA with a bit size of 4096 by default) and output
+// of the Certificate and Key will be returned in PEM format as bytes.
 //
 // The certificate will be signed by the given CA Certificate pair (caCrt and caKey). Validity of the CA Certificate
-// pair is checked.
+// pair is checked. The NotAfter of the certificate is capped to the NotAfter of the CA Certificate.
 func GenerateCertificate(req *Request, caCrt []byte, caKey []byte) ([]byte, []byte, error) {
 	if err := req.Validate(); err != nil {
 		return nil, nil, err
 	}
 
-	catls, err := tls.X509KeyPair(caCrt, caKey)
+	ca, caPriv, err := parseKeyPair(caCrt, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return generateCertificate(req, ca, caPriv)
+}
+
+// generateCertificate generates a certificate pair for the (validated) request signed by the CA using the profile of
+// the request
+func generateCertificate(req *Request, ca *x509.Certificate, caPriv crypto.Signer) ([]byte, []byte, error) {
+	priv, err := generateKey(req)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	ca, err := x509.ParseCertificate(catls.Certificate[0])
+	crt, err := issueCertificate(req, priv.Public(), ca, caPriv)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	keyBlock, err := encodeKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pemKeyOut := bytes.NewBuffer([]byte{})
+	if err := pem.Encode(pemKeyOut, keyBlock); err != nil {
+		return nil, nil, err
+	}
+
+	return crt, pemKeyOut.Bytes(), nil
+}
+
+// SignCSR will sign the public key of the given PEM encoded CSR and will return the certificate and a possible error.
+// The signature of the CSR is checked to make sure the requester owns the private key. The subject, subject alt
+// names, serial number and validity of the certificate are taken from the Request (see ReadCSR), the key settings
+// of the Request are not used.
+//
+// The certificate will be signed by the given CA Certificate pair (caCrt and caKey).
+func SignCSR(req *Request, csr []byte, caCrt []byte, caKey []byte) ([]byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	ca, caPriv, err := parseKeyPair(caCrt, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return signCSR(req, csr, ca, caPriv)
+}
+
+// SignPublicKey will sign the public key and will return the certificate and a possible error. It works like
+// SignCSR for a key that is already known to belong to the requester, such as the key of a certificate that is
+// renewed.
+//
+// The certificate will be signed by the given CA Certificate pair (caCrt and caKey).
+func SignPublicKey(req *Request, pub crypto.PublicKey, caCrt []byte, caKey []byte) ([]byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	ca, caPriv, err := parseKeyPair(caCrt, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueCertificate(req, pub, ca, caPriv)
+}
+
+// signCSR checks the signature of the CSR and issues a certificate for its public key signed by the CA
+func signCSR(req *Request, csr []byte, ca *x509.Certificate, caPriv crypto.Signer) ([]byte, error) {
+	block, _ := pem.Decode(csr)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, ErrorInvalidCSR
+	}
+
+	csrReq, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csrReq.CheckSignature(); err != nil {
+		return nil, ErrorInvalidCSRSignature
+	}
+
+	return issueCertificate(req, csrReq.PublicKey, ca, caPriv)
+}
+
+// issueCertificate issues a PEM encoded certificate for the public key signed by the CA using the profile of the
+// request. The certificate does not outlive the CA, ErrorCAExpired is returned if the CA expires before the
+// certificate becomes valid. The subject alt names are checked against the name constraints of the CA and the CAs
+// in the CAChain of the request.
+func issueCertificate(req *Request, pub crypto.PublicKey, ca *x509.Certificate, caPriv crypto.Signer) ([]byte, error) {
 	if req.SerialNumber == nil {
 		randInt, err := GenerateRandomBigInt()
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		req.SerialNumber = randInt
@@ -215,46 +432,158 @@ func GenerateCertificate(req *Request, caCrt []byte, caKey []byte) ([]byte, []by
 		Subject:      req.GetPKIXName(),
 		NotBefore:    req.NotBefore,
 		NotAfter:     req.NotAfter,
-		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:     x509.KeyUsageDigitalSignature,
 	}
 
-	if len(req.SubjectAltNames) > 0 {
-		cert.DNSNames = req.SubjectAltNames
+	// A certificate can not outlive the CA that issued it
+	if cert.NotAfter.After(ca.NotAfter) {
+		if cert.NotBefore.After(ca.NotAfter) {
+			return nil, ErrorCAExpired
+		}
+		cert.NotAfter = ca.NotAfter
+	}
+
+	if req.Policy != nil {
+		if err := req.Policy.Check(req, pub); err != nil {
+			return nil, err
+		}
 	}
 
-	priv, err := rsa.GenerateKey(rand.Reader, req.BitSize)
+	profile, err := req.profile()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if err := profile.apply(cert, pub); err != nil {
+		return nil, err
 	}
-	pub := &priv.PublicKey
 
-	certB, err := x509.CreateCertificate(rand.Reader, cert, ca, pub, catls.PrivateKey)
+	sans, err := ParseSubjectAltNames(req.SubjectAltNames)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if err := checkChainNameConstraints(ca, req.CAChain, sans); err != nil {
+		return nil, err
+	}
+	cert.DNSNames = sans.DNSNames
+	cert.IPAddresses = sans.IPAddresses
+	cert.EmailAddresses = sans.EmailAddresses
+	cert.URIs = sans.URIs
+
+	cert.SubjectKeyId, err = subjectKeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	certB, err := x509.CreateCertificate(rand.Reader, cert, ca, pub, caPriv)
+	if err != nil {
+		return nil, err
 	}
 
 	pemOut := bytes.NewBuffer([]byte{})
 	if err := pem.Encode(pemOut, &pem.Block{Type: "CERTIFICATE", Bytes: certB}); err != nil {
+		return nil, err
+	}
+
+	return pemOut.Bytes(), nil
+}
+
+// GenerateCA will generate a CA certificate pair and will return certificate, key and a possible error
+// The Generated key will be of the KeyType given in the Request (RSA with a bit size of 4096 by default) and output
+// of the Certificate and Key will be returned in PEM format as bytes.
+func GenerateCA(req *Request) ([]byte, []byte, error) {
+	if err := req.Validate(); err != nil {
 		return nil, nil, err
 	}
 
-	pemKeyOut := bytes.NewBuffer([]byte{})
-	if err := pem.Encode(pemKeyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}); err != nil {
+	if req.SerialNumber == nil {
+		randInt, err := GenerateRandomBigInt()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req.SerialNumber = randInt
+	}
+
+	ca := &x509.Certificate{
+		SerialNumber:          req.SerialNumber,
+		Subject:               req.GetPKIXName(),
+		NotBefore:             req.NotBefore,
+		NotAfter:              req.NotAfter,
+		IsCA:                  true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		MaxPathLen:            req.MaxPathLen,
+		MaxPathLenZero:        req.MaxPathLenZero,
+	}
+
+	if req.NameConstraints != nil {
+		if err := req.NameConstraints.apply(ca); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	priv, err := generateKey(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ca.SubjectKeyId, err = subjectKeyID(priv.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caB, err := x509.CreateCertificate(rand.Reader, ca, ca, priv.Public(), priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	crtB := bytes.NewBuffer([]byte{})
+	if err := pem.Encode(crtB, &pem.Block{Type: "CERTIFICATE", Bytes: caB}); err != nil {
+		return nil, nil, err
+	}
+
+	keyBlock, err := encodeKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyB := bytes.NewBuffer([]byte{})
+	if err := pem.Encode(keyB, keyBlock); err != nil {
 		return nil, nil, err
 	}
 
-	return pemOut.Bytes(), pemKeyOut.Bytes(), nil
+	return crtB.Bytes(), keyB.Bytes(), nil
 }
 
-// GenerateCA will generate a CA certificate pair and will return certificate, key and a possible error
-// The Generated key will be in RSA format and has a bit size of 4096 and output of the Certificate
-// and Key will be returned in PEM format as bytes.
-func GenerateCA(req *Request) ([]byte, []byte, error) {
+// GenerateIntermediateCA will generate an intermediate CA certificate pair signed by the given parent CA certificate
+// pair (parentCrt and parentKey) and will return certificate, key and a possible error. The parent can be a root CA
+// or another intermediate CA. The path length of the intermediate is set using MaxPathLen and MaxPathLenZero of the
+// Request and has to be within the bounds of the path length of the parent. The names the intermediate can issue
+// certificates for are restricted with the NameConstraints of the Request, which have to be within the name
+// constraints of the parent and the CAs in the CAChain of the Request.
+func GenerateIntermediateCA(req *Request, parentCrt []byte, parentKey []byte) ([]byte, []byte, error) {
 	if err := req.Validate(); err != nil {
 		return nil, nil, err
 	}
 
+	parent, parentPriv, err := parseKeyPair(parentCrt, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return generateIntermediateCA(req, parent, parentPriv)
+}
+
+// generateIntermediateCA generates an intermediate CA pair for the (validated) request signed by the parent CA
+func generateIntermediateCA(req *Request, parent *x509.Certificate, parentPriv crypto.Signer) ([]byte, []byte, error) {
+	if !parent.IsCA {
+		return nil, nil, ErrorParentNotCA
+	}
+
+	if err := checkPathLen(parent, req); err != nil {
+		return nil, nil, err
+	}
+
 	if req.SerialNumber == nil {
 		randInt, err := GenerateRandomBigInt()
 		if err != nil {
@@ -270,17 +599,40 @@ func GenerateCA(req *Request) ([]byte, []byte, error) {
 		NotBefore:             req.NotBefore,
 		NotAfter:              req.NotAfter,
 		IsCA:                  true,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 		BasicConstraintsValid: true,
+		MaxPathLen:            req.MaxPathLen,
+		MaxPathLenZero:        req.MaxPathLenZero,
+	}
+
+	if req.NameConstraints != nil {
+		if err := req.NameConstraints.apply(ca); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The intermediate can only issue names that the CAs above it permit
+	cas, err := issuingCAs(parent, req.CAChain)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range cas {
+		if err := checkSubtrees(c, ca); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	priv, err := rsa.GenerateKey(rand.Reader, 4096)
+	priv, err := generateKey(req)
 	if err != nil {
 		return nil, nil, err
 	}
-	pub := &priv.PublicKey
-	caB, err := x509.CreateCertificate(rand.Reader, ca, ca, pub, priv)
+
+	ca.SubjectKeyId, err = subjectKeyID(priv.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caB, err := x509.CreateCertificate(rand.Reader, ca, parent, priv.Public(), parentPriv)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -290,10 +642,54 @@ func GenerateCA(req *Request) ([]byte, []byte, error) {
 		return nil, nil, err
 	}
 
+	keyBlock, err := encodeKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	keyB := bytes.NewBuffer([]byte{})
-	if err := pem.Encode(keyB, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}); err != nil {
+	if err := pem.Encode(keyB, keyBlock); err != nil {
 		return nil, nil, err
 	}
 
 	return crtB.Bytes(), keyB.Bytes(), nil
 }
+
+// checkPathLen checks if the parent CA is allowed to issue a CA with the path length given in the Request
+func checkPathLen(parent *x509.Certificate, req *Request) error {
+	parentUnset := parent.MaxPathLen < 0 || (parent.MaxPathLen == 0 && !parent.MaxPathLenZero)
+	if parentUnset {
+		return nil
+	}
+
+	if parent.MaxPathLen == 0 {
+		return ErrorParentPathLenExceeded
+	}
+
+	reqUnset := req.MaxPathLen < 0 || (req.MaxPathLen == 0 && !req.MaxPathLenZero)
+	if reqUnset || req.MaxPathLen >= parent.MaxPathLen {
+		return ErrorInvalidMaxPathLen
+	}
+
+	return nil
+}
+
+// parseKeyPair parses a PEM encoded certificate and key pair and returns the parsed certificate and private key
+func parseKeyPair(crt []byte, key []byte) (*x509.Certificate, crypto.Signer, error) {
+	keyPair, err := tls.X509KeyPair(crt, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, ErrorInvalidKeyType
+	}
+
+	return parsed, signer, nil
+}
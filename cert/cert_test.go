@@ -1,7 +1,10 @@
 package cert
 
 import (
+	"crypto/rand"
+	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -79,6 +82,15 @@ func TestCertRequest_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "notafter_before_notbefore",
+			fields: fields{
+				CommonName: "valid.common.name",
+				NotBefore:  time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				NotAfter:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -116,7 +128,9 @@ func TestCertRequest_GenerateCertificate(t *testing.T) {
 		SubjectAltNames  []string
 		NotBefore        time.Time
 		NotAfter         time.Time
+		KeyType          string
 		BitSize          int
+		Curve            string
 	}
 	type args struct {
 		caCrt []byte
@@ -247,6 +261,66 @@ func TestCertRequest_GenerateCertificate(t *testing.T) {
 			want1:   nil,
 			wantErr: true,
 		},
+		{
+			name: "valid_certificate_ecdsa",
+			fields: fields{
+				CommonName:      "valid.test.local",
+				SubjectAltNames: []string{"valid.subject.alt.name"},
+				KeyType:         KeyTypeECDSA,
+				Curve:           "P384",
+			},
+			args: args{
+				caCrt: testCA.Crt,
+				caKey: testCA.Key,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: false,
+		},
+		{
+			name: "valid_certificate_ed25519",
+			fields: fields{
+				CommonName:      "valid.test.local",
+				SubjectAltNames: []string{"valid.subject.alt.name"},
+				KeyType:         KeyTypeEd25519,
+			},
+			args: args{
+				caCrt: testCA.Crt,
+				caKey: testCA.Key,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: false,
+		},
+		{
+			name: "invalid_key_type",
+			fields: fields{
+				CommonName: "valid.test.local",
+				KeyType:    "dsa",
+			},
+			args: args{
+				caCrt: testCA.Crt,
+				caKey: testCA.Key,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid_curve",
+			fields: fields{
+				CommonName: "valid.test.local",
+				KeyType:    KeyTypeECDSA,
+				Curve:      "P-192",
+			},
+			args: args{
+				caCrt: testCA.Crt,
+				caKey: testCA.Key,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: true,
+		},
 		{
 			name: "invalid_ca_key",
 			fields: fields{
@@ -285,7 +359,9 @@ func TestCertRequest_GenerateCertificate(t *testing.T) {
 				SubjectAltNames:  tt.fields.SubjectAltNames,
 				NotBefore:        tt.fields.NotBefore,
 				NotAfter:         tt.fields.NotAfter,
+				KeyType:          tt.fields.KeyType,
 				BitSize:          tt.fields.BitSize,
+				Curve:            tt.fields.Curve,
 			}
 			got, got1, err := GenerateCertificate(req, tt.args.caCrt, tt.args.caKey)
 			if (err != nil) != tt.wantErr {
@@ -318,6 +394,9 @@ func TestCARequest_GenerateCA(t *testing.T) {
 		CommonName    string
 		NotBefore     time.Time
 		NotAfter      time.Time
+		KeyType       string
+		BitSize       int
+		Curve         string
 	}
 	tests := []struct {
 		name    string
@@ -343,6 +422,47 @@ func TestCARequest_GenerateCA(t *testing.T) {
 			want1:   nil,
 			wantErr: true,
 		},
+		{
+			name: "valid_ca_2048",
+			fields: fields{
+				CommonName: "ca.test.local",
+				BitSize:    2048,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: false,
+		},
+		{
+			name: "valid_ca_ecdsa",
+			fields: fields{
+				CommonName: "ca.test.local",
+				KeyType:    KeyTypeECDSA,
+				Curve:      CurveP521,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: false,
+		},
+		{
+			name: "valid_ca_ed25519",
+			fields: fields{
+				CommonName: "ca.test.local",
+				KeyType:    KeyTypeEd25519,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: false,
+		},
+		{
+			name: "invalid_ca_bitsize",
+			fields: fields{
+				CommonName: "ca.test.local",
+				BitSize:    1024,
+			},
+			want:    nil,
+			want1:   nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -356,6 +476,9 @@ func TestCARequest_GenerateCA(t *testing.T) {
 				CommonName:    tt.fields.CommonName,
 				NotBefore:     tt.fields.NotBefore,
 				NotAfter:      tt.fields.NotAfter,
+				KeyType:       tt.fields.KeyType,
+				BitSize:       tt.fields.BitSize,
+				Curve:         tt.fields.Curve,
 			}
 			got, got1, err := GenerateCA(req)
 			if (err != nil) != tt.wantErr {
@@ -672,3 +795,336 @@ func TestReadCSR(t *testing.T) {
 		})
 	}
 }
+
+func TestSignCSR(t *testing.T) {
+	priv, err := generateKey(&Request{KeyType: KeyTypeECDSA, Curve: CurveP256})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "csr.test.local"}}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	tampered := make([]byte, len(csrDER))
+	copy(tampered, csrDER)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		req     *Request
+		csr     []byte
+		wantErr error
+	}{
+		{
+			name: "valid",
+			req:  &Request{CommonName: "csr.test.local", SubjectAltNames: []string{"csr.test.local"}},
+			csr:  csr,
+		},
+		{
+			name:    "invalid_common_name",
+			req:     &Request{},
+			csr:     csr,
+			wantErr: ErrorInvalidCommonName,
+		},
+		{
+			name:    "invalid_csr",
+			req:     &Request{CommonName: "csr.test.local"},
+			csr:     []byte("random_character"),
+			wantErr: ErrorInvalidCSR,
+		},
+		{
+			name:    "invalid_signature",
+			req:     &Request{CommonName: "csr.test.local"},
+			csr:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: tampered}),
+			wantErr: ErrorInvalidCSRSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SignCSR(tt.req, tt.csr, testCA.Crt, testCA.Key)
+			if err != tt.wantErr {
+				t.Errorf("SignCSR() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			block, _ := pem.Decode(got)
+			crt, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Errorf("SignCSR() returned invalid certificate: %v", err)
+				return
+			}
+			if !reflect.DeepEqual(crt.PublicKey, priv.Public()) {
+				t.Errorf("SignCSR() public key = %v, want the public key of the CSR", crt.PublicKey)
+			}
+			if !reflect.DeepEqual(crt.DNSNames, tt.req.SubjectAltNames) {
+				t.Errorf("SignCSR() DNSNames = %v, want %v", crt.DNSNames, tt.req.SubjectAltNames)
+			}
+		})
+	}
+}
+
+func TestReadCertificate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *Request
+		want    *Request
+		wantErr error
+	}{
+		{
+			name: "rsa",
+			req:  &Request{CommonName: "rsa.test.local", Organization: "Test", Country: "NL", NameSerialNumber: "rsa-serial", SubjectAltNames: []string{"rsa.test.local", "127.0.0.1"}, KeyType: KeyTypeRSA, BitSize: 2048},
+			want: &Request{CommonName: "rsa.test.local", Organization: "Test", Country: "NL", NameSerialNumber: "rsa-serial", SubjectAltNames: []string{"rsa.test.local", "127.0.0.1"}, KeyType: KeyTypeRSA, BitSize: 2048, Curve: defaultCurve},
+		},
+		{
+			name: "ecdsa",
+			req:  &Request{CommonName: "ecdsa.test.local", KeyType: KeyTypeECDSA, Curve: CurveP384},
+			want: &Request{CommonName: "ecdsa.test.local", KeyType: KeyTypeECDSA, BitSize: defaultBitSize, Curve: CurveP384},
+		},
+		{
+			name: "ed25519",
+			req:  &Request{CommonName: "ed25519.test.local", KeyType: KeyTypeEd25519},
+			want: &Request{CommonName: "ed25519.test.local", KeyType: KeyTypeEd25519, BitSize: defaultBitSize, Curve: defaultCurve},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.NotBefore = time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+			tt.req.NotAfter = tt.req.NotBefore.Add(72 * time.Hour)
+			crt, _, err := GenerateCertificate(tt.req, testCA.Crt, testCA.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadCertificate(crt)
+			if err != nil {
+				t.Fatalf("ReadCertificate() error = %v", err)
+			}
+
+			// The renewal is valid from now for as long as the certificate was valid
+			if time.Since(got.NotBefore) > time.Minute || got.NotAfter.Sub(got.NotBefore) != 72*time.Hour {
+				t.Errorf("ReadCertificate() validity = %s - %s, want 72h from now", got.NotBefore, got.NotAfter)
+			}
+			got.NotBefore, got.NotAfter = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadCertificate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadCertificate(testCSRFile); err != ErrorInvalidCertificate {
+		t.Errorf("ReadCertificate() error = %v, want %v", err, ErrorInvalidCertificate)
+	}
+}
+
+func TestSignPublicKey(t *testing.T) {
+	priv, err := generateKey(&Request{KeyType: KeyTypeEd25519})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crt, err := SignPublicKey(&Request{CommonName: "key.test.local"}, priv.Public(), testCA.Crt, testCA.Key)
+	if err != nil {
+		t.Fatalf("SignPublicKey() error = %v", err)
+	}
+
+	block, _ := pem.Decode(crt)
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.PublicKey, priv.Public()) {
+		t.Errorf("SignPublicKey() public key = %v, want %v", parsed.PublicKey, priv.Public())
+	}
+
+	if _, err := SignPublicKey(&Request{}, priv.Public(), testCA.Crt, testCA.Key); err != ErrorInvalidCommonName {
+		t.Errorf("SignPublicKey() error = %v, want %v", err, ErrorInvalidCommonName)
+	}
+}
+
+func TestGenerateCertificate_CAValidity(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	caCrt, caKey, err := GenerateCA(&Request{CommonName: "ca.test.local", KeyType: KeyTypeECDSA, NotBefore: now.Add(-time.Hour), NotAfter: now.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		notBefore    time.Time
+		notAfter     time.Time
+		wantNotAfter time.Time
+		wantErr      error
+	}{
+		{name: "within_ca", notBefore: now, notAfter: now.Add(time.Hour), wantNotAfter: now.Add(time.Hour)},
+		{name: "capped", notBefore: now, notAfter: now.AddDate(1, 0, 0), wantNotAfter: now.Add(24 * time.Hour)},
+		{name: "ca_expired", notBefore: now.Add(48 * time.Hour), notAfter: now.AddDate(1, 0, 0), wantErr: ErrorCAExpired},
+		{name: "inverted", notBefore: now, notAfter: now.Add(-time.Hour), wantErr: ErrorInvalidValidity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crt, _, err := GenerateCertificate(&Request{CommonName: "www.test.local", KeyType: KeyTypeECDSA, NotBefore: tt.notBefore, NotAfter: tt.notAfter}, caCrt, caKey)
+			if err != tt.wantErr {
+				t.Fatalf("GenerateCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			block, _ := pem.Decode(crt)
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsed.NotAfter.Equal(tt.wantNotAfter) {
+				t.Errorf("GenerateCertificate() NotAfter = %s, want %s", parsed.NotAfter, tt.wantNotAfter)
+			}
+		})
+	}
+}
+
+func TestGenerateIntermediateCA(t *testing.T) {
+	leafCrt, leafKey, err := GenerateCertificate(&Request{CommonName: "leaf.test.local", BitSize: 2048}, testCA.Crt, testCA.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limitedCrt, limitedKey, err := GenerateIntermediateCA(&Request{CommonName: "limited.test.local", BitSize: 2048, MaxPathLen: 0, MaxPathLenZero: true}, testCA.Crt, testCA.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type fields struct {
+		CommonName     string
+		MaxPathLen     int
+		MaxPathLenZero bool
+	}
+	type args struct {
+		parentCrt []byte
+		parentKey []byte
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "valid_intermediate",
+			fields:  fields{CommonName: "intermediate.test.local", MaxPathLen: 0, MaxPathLenZero: true},
+			args:    args{parentCrt: testCA.Crt, parentKey: testCA.Key},
+			wantErr: nil,
+		},
+		{
+			name:    "valid_intermediate_unlimited",
+			fields:  fields{CommonName: "intermediate.test.local", MaxPathLen: -1},
+			args:    args{parentCrt: testCA.Crt, parentKey: testCA.Key},
+			wantErr: nil,
+		},
+		{
+			name:    "invalid_common_name",
+			fields:  fields{CommonName: ""},
+			args:    args{parentCrt: testCA.Crt, parentKey: testCA.Key},
+			wantErr: ErrorInvalidCommonName,
+		},
+		{
+			name:    "parent_not_ca",
+			fields:  fields{CommonName: "intermediate.test.local"},
+			args:    args{parentCrt: leafCrt, parentKey: leafKey},
+			wantErr: ErrorParentNotCA,
+		},
+		{
+			name:    "parent_path_len_exceeded",
+			fields:  fields{CommonName: "intermediate.test.local", MaxPathLen: 0, MaxPathLenZero: true},
+			args:    args{parentCrt: limitedCrt, parentKey: limitedKey},
+			wantErr: ErrorParentPathLenExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{
+				CommonName:     tt.fields.CommonName,
+				BitSize:        2048,
+				MaxPathLen:     tt.fields.MaxPathLen,
+				MaxPathLenZero: tt.fields.MaxPathLenZero,
+			}
+			got, got1, err := GenerateIntermediateCA(req, tt.args.parentCrt, tt.args.parentKey)
+			if err != tt.wantErr {
+				t.Errorf("GenerateIntermediateCA() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			crt, _, err := parseKeyPair(got, got1)
+			if err != nil {
+				t.Errorf("GenerateIntermediateCA() returned invalid pair: %v", err)
+				return
+			}
+			if !crt.IsCA {
+				t.Errorf("GenerateIntermediateCA() IsCA = %v, want %v", crt.IsCA, true)
+			}
+			if crt.MaxPathLenZero != tt.fields.MaxPathLenZero {
+				t.Errorf("GenerateIntermediateCA() MaxPathLenZero = %v, want %v", crt.MaxPathLenZero, tt.fields.MaxPathLenZero)
+			}
+
+			// The intermediate must be able to sign a certificate itself
+			if _, _, err := GenerateCertificate(&Request{CommonName: "leaf.test.local", BitSize: 2048}, got, got1); err != nil {
+				t.Errorf("GenerateCertificate() with intermediate error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckPathLen(t *testing.T) {
+	tests := []struct {
+		name    string
+		parent  *x509.Certificate
+		req     *Request
+		wantErr error
+	}{
+		{
+			name:    "parent_unset",
+			parent:  &x509.Certificate{MaxPathLen: -1},
+			req:     &Request{MaxPathLen: -1},
+			wantErr: nil,
+		},
+		{
+			name:    "parent_zero",
+			parent:  &x509.Certificate{MaxPathLen: 0, MaxPathLenZero: true},
+			req:     &Request{MaxPathLen: 0, MaxPathLenZero: true},
+			wantErr: ErrorParentPathLenExceeded,
+		},
+		{
+			name:    "smaller",
+			parent:  &x509.Certificate{MaxPathLen: 2},
+			req:     &Request{MaxPathLen: 1},
+			wantErr: nil,
+		},
+		{
+			name:    "equal",
+			parent:  &x509.Certificate{MaxPathLen: 1},
+			req:     &Request{MaxPathLen: 1},
+			wantErr: ErrorInvalidMaxPathLen,
+		},
+		{
+			name:    "unset_under_limited_parent",
+			parent:  &x509.Certificate{MaxPathLen: 1},
+			req:     &Request{MaxPathLen: -1},
+			wantErr: ErrorInvalidMaxPathLen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPathLen(tt.parent, tt.req); err != tt.wantErr {
+				t.Errorf("checkPathLen() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// Chain builds a PEM encoded certificate chain out of the given PEM encoded certificates (or bundles of
+// certificates) in the given order. Self-signed root certificates are left out of the chain, as clients are
+// expected to have those in their trust store already.
+//
+// To create a full chain pass the leaf certificate first, followed by the issuing CA and its intermediates.
+// The result can be empty when only root certificates are given.
+func Chain(certs ...[]byte) ([]byte, error) {
+	out := bytes.NewBuffer([]byte{})
+	found := false
+
+	for _, data := range certs {
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			found = true
+
+			crt, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			if isSelfSigned(crt) {
+				continue
+			}
+
+			if err := pem.Encode(out, block); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !found {
+		return nil, ErrorEmptyChain
+	}
+
+	return out.Bytes(), nil
+}
+
+// isSelfSigned checks if the certificate is signed by its own key
+func isSelfSigned(crt *x509.Certificate) bool {
+	if !bytes.Equal(crt.RawIssuer, crt.RawSubject) {
+		return false
+	}
+
+	return crt.CheckSignatureFrom(crt) == nil
+}
@@ -0,0 +1,82 @@
+package cert
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	intermediateCrt, intermediateKey, err := GenerateIntermediateCA(&Request{CommonName: "intermediate.test.local", BitSize: 2048, MaxPathLenZero: true}, testCA.Crt, testCA.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafCrt, _, err := GenerateCertificate(&Request{CommonName: "leaf.test.local", BitSize: 2048}, intermediateCrt, intermediateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		certs     [][]byte
+		wantCount int
+		wantFirst []byte
+		wantErr   bool
+	}{
+		{
+			name:      "fullchain",
+			certs:     [][]byte{leafCrt, intermediateCrt, testCA.Crt},
+			wantCount: 2,
+			wantFirst: leafCrt,
+			wantErr:   false,
+		},
+		{
+			name:      "chain_bundle",
+			certs:     [][]byte{append(append([]byte{}, intermediateCrt...), testCA.Crt...)},
+			wantCount: 1,
+			wantFirst: intermediateCrt,
+			wantErr:   false,
+		},
+		{
+			name:      "root_only",
+			certs:     [][]byte{testCA.Crt},
+			wantCount: 0,
+			wantErr:   false,
+		},
+		{
+			name:    "empty",
+			certs:   [][]byte{[]byte("")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Chain(tt.certs...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Chain() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			count := 0
+			rest := got
+			for {
+				var block *pem.Block
+				block, rest = pem.Decode(rest)
+				if block == nil {
+					break
+				}
+				count++
+			}
+			if count != tt.wantCount {
+				t.Errorf("Chain() count = %v, want %v", count, tt.wantCount)
+			}
+			if tt.wantFirst != nil && !bytes.HasPrefix(got, tt.wantFirst) {
+				t.Errorf("Chain() does not start with the expected certificate")
+			}
+		})
+	}
+}
@@ -9,4 +9,56 @@ var (
 	ErrorInvalidSubjectAltName = errors.New("invalid subject alt name")
 	// ErrorInvalidBitSize is given if an invalid bitsize is given
 	ErrorInvalidBitSize = errors.New("invalid bit size")
+	// ErrorInvalidKeyType is given if an unsupported key type is given
+	ErrorInvalidKeyType = errors.New("invalid key type")
+	// ErrorInvalidCurve is given if an unsupported elliptic curve is given
+	ErrorInvalidCurve = errors.New("invalid curve")
+	// ErrorInvalidValidity is given if the NotAfter of a request is before its NotBefore
+	ErrorInvalidValidity = errors.New("invalid validity, not after is before not before")
+	// ErrorCAExpired is given if the CA certificate expires before the certificate it signs becomes valid
+	ErrorCAExpired = errors.New("ca certificate expires before the certificate is valid")
+	// ErrorParentNotCA is given if the parent certificate used to sign an intermediate CA is not a CA
+	ErrorParentNotCA = errors.New("parent certificate is not a ca")
+	// ErrorParentPathLenExceeded is given if the parent CA does not allow issuing intermediate CAs
+	ErrorParentPathLenExceeded = errors.New("parent ca does not allow intermediate cas")
+	// ErrorInvalidMaxPathLen is given if the max path length is not smaller than the max path length of the parent CA
+	ErrorInvalidMaxPathLen = errors.New("invalid max path length")
+	// ErrorEmptyChain is given if no certificates could be found to build a chain
+	ErrorEmptyChain = errors.New("empty certificate chain")
+	// ErrorInvalidRevocationReason is given if an unknown or unusable revocation reason is given
+	ErrorInvalidRevocationReason = errors.New("invalid revocation reason")
+	// ErrorInvalidSerialNumber is given if a serial number is missing or invalid
+	ErrorInvalidSerialNumber = errors.New("invalid serial number")
+	// ErrorInvalidCRLNumber is given if the CRL number is missing or negative
+	ErrorInvalidCRLNumber = errors.New("invalid crl number")
+	// ErrorInvalidNextUpdate is given if the next update of a CRL is not in the future
+	ErrorInvalidNextUpdate = errors.New("invalid next update")
+	// ErrorInvalidCSR is given if a CSR can not be decoded
+	ErrorInvalidCSR = errors.New("invalid certificate request")
+	// ErrorInvalidCSRSignature is given if the signature of a CSR does not match its public key
+	ErrorInvalidCSRSignature = errors.New("invalid certificate request signature")
+	// ErrorInvalidProfile is given if an unknown profile is requested
+	ErrorInvalidProfile = errors.New("invalid profile")
+	// ErrorInvalidKeyUsage is given if a profile has an unknown key usage or extended key usage
+	ErrorInvalidKeyUsage = errors.New("invalid key usage")
+	// ErrorPolicyViolation is given if a request does not comply with the Policy of the CA
+	ErrorPolicyViolation = errors.New("policy violation")
+	// ErrorInvalidKey is given if a PEM encoded private key can not be parsed
+	ErrorInvalidKey = errors.New("invalid private key")
+	// ErrorUnsupportedKeyEncryption is given if an encrypted private key uses an unsupported algorithm
+	ErrorUnsupportedKeyEncryption = errors.New("unsupported private key encryption")
+	// ErrorEmptyPassphrase is given if a private key is encrypted or decrypted with an empty passphrase
+	ErrorEmptyPassphrase = errors.New("empty passphrase")
+	// ErrorIncorrectPassphrase is given if an encrypted private key can not be decrypted with the passphrase
+	ErrorIncorrectPassphrase = errors.New("incorrect passphrase")
+	// ErrorInvalidCertificate is given if a PEM encoded certificate can not be found
+	ErrorInvalidCertificate = errors.New("invalid certificate")
+	// ErrorSignerMismatch is given if the public key of a signer does not match the certificate it signs for
+	ErrorSignerMismatch = errors.New("signer does not match the certificate")
+	// ErrorInvalidNameConstraint is given if a name constraint of a CA request can not be parsed
+	ErrorInvalidNameConstraint = errors.New("invalid name constraint")
+	// ErrorNameConstraintViolation is given if a name of a certificate is not allowed by the name constraints of the CA
+	ErrorNameConstraintViolation = errors.New("name constraint violation")
+	// ErrorUnknownObject is given if no certificate, CSR, CRL or key can be found to inspect
+	ErrorUnknownObject = errors.New("no certificate, certificate request, crl or key found")
 )
@@ -0,0 +1,317 @@
+package cert
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NameConstraints restrict the names of the certificates issued below a CA (RFC 5280 section 4.2.1.10). A name
+// matching one of the excluded constraints is not allowed, when there are permitted constraints of its type a name
+// has to match one of them.
+//
+// DNS and URI constraints are domains, "payments.internal" matches the domain and its subdomains while
+// ".payments.internal" or "*.payments.internal" only matches subdomains. IP constraints are CIDR ranges like
+// 10.20.0.0/16. Email constraints are a single address, a host or a domain with a leading period.
+type NameConstraints struct {
+	// Critical marks the name constraints extension as critical, so clients that do not support it reject the
+	// certificates
+	Critical bool
+
+	PermittedDNSDomains     []string
+	ExcludedDNSDomains      []string
+	PermittedIPRanges       []string
+	ExcludedIPRanges        []string
+	PermittedEmailAddresses []string
+	ExcludedEmailAddresses  []string
+	PermittedURIDomains     []string
+	ExcludedURIDomains      []string
+}
+
+// apply sets the name constraints on the CA certificate, ErrorInvalidNameConstraint is returned for a constraint
+// that can not be parsed
+func (nc *NameConstraints) apply(ca *x509.Certificate) error {
+	var err error
+
+	ca.PermittedDNSDomainsCritical = nc.Critical
+	if ca.PermittedDNSDomains, err = parseDomainConstraints(nc.PermittedDNSDomains); err != nil {
+		return err
+	}
+	if ca.ExcludedDNSDomains, err = parseDomainConstraints(nc.ExcludedDNSDomains); err != nil {
+		return err
+	}
+	if ca.PermittedIPRanges, err = parseIPConstraints(nc.PermittedIPRanges); err != nil {
+		return err
+	}
+	if ca.ExcludedIPRanges, err = parseIPConstraints(nc.ExcludedIPRanges); err != nil {
+		return err
+	}
+	if ca.PermittedEmailAddresses, err = parseEmailConstraints(nc.PermittedEmailAddresses); err != nil {
+		return err
+	}
+	if ca.ExcludedEmailAddresses, err = parseEmailConstraints(nc.ExcludedEmailAddresses); err != nil {
+		return err
+	}
+	if ca.PermittedURIDomains, err = parseDomainConstraints(nc.PermittedURIDomains); err != nil {
+		return err
+	}
+	if ca.ExcludedURIDomains, err = parseDomainConstraints(nc.ExcludedURIDomains); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseDomainConstraints parses DNS or URI domain constraints, a leading wildcard label is written as a period
+func parseDomainConstraints(constraints []string) ([]string, error) {
+	var domains []string
+	for _, constraint := range constraints {
+		domain := strings.ToLower(constraint)
+		if strings.HasPrefix(domain, "*.") {
+			domain = domain[1:]
+		}
+		if strings.Contains(domain, "*") || !isValidDNSName(strings.TrimPrefix(domain, ".")) {
+			return nil, fmt.Errorf("%w: %q", ErrorInvalidNameConstraint, constraint)
+		}
+		domains = append(domains, domain)
+	}
+	return domains, nil
+}
+
+// parseIPConstraints parses IP range constraints in CIDR notation
+func parseIPConstraints(constraints []string) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, constraint := range constraints {
+		_, ipNet, err := net.ParseCIDR(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrorInvalidNameConstraint, constraint)
+		}
+		ranges = append(ranges, ipNet)
+	}
+	return ranges, nil
+}
+
+// parseEmailConstraints parses email constraints, which are either an address or a (leading period) domain
+func parseEmailConstraints(constraints []string) ([]string, error) {
+	var emails []string
+	for _, constraint := range constraints {
+		i := strings.LastIndex(constraint, "@")
+		if i == 0 || !isValidDNSName(strings.TrimPrefix(constraint[i+1:], ".")) {
+			return nil, fmt.Errorf("%w: %q", ErrorInvalidNameConstraint, constraint)
+		}
+		emails = append(emails, constraint)
+	}
+	return emails, nil
+}
+
+// issuingCAs returns the issuing CA followed by the CAs of the PEM encoded chain above it, clients check the names in
+// a certificate against the name constraints of all of them
+func issuingCAs(ca *x509.Certificate, chain []byte) ([]*x509.Certificate, error) {
+	cas, err := parseCertificates(chain)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*x509.Certificate{ca}, cas...), nil
+}
+
+// checkChainNameConstraints checks the subject alt names of a certificate against the name constraints of the
+// issuing CA and the CAs of the chain above it
+func checkChainNameConstraints(ca *x509.Certificate, chain []byte, sans *SubjectAltNames) error {
+	cas, err := issuingCAs(ca, chain)
+	if err != nil {
+		return err
+	}
+	for _, c := range cas {
+		if err := checkNameConstraints(c, sans); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNameConstraints checks the subject alt names of a certificate against the name constraints of the issuing
+// CA, so a violation is reported before signing instead of by clients verifying the certificate. Violations are
+// reported as errors wrapping ErrorNameConstraintViolation.
+func checkNameConstraints(ca *x509.Certificate, sans *SubjectAltNames) error {
+	for _, name := range sans.DNSNames {
+		if err := checkConstraint("dns name", name, ca.PermittedDNSDomains, ca.ExcludedDNSDomains, matchDomain); err != nil {
+			return err
+		}
+	}
+
+	for _, ip := range sans.IPAddresses {
+		if err := checkConstraint("ip address", ip.String(), ipRangeStrings(ca.PermittedIPRanges), ipRangeStrings(ca.ExcludedIPRanges), matchIP); err != nil {
+			return err
+		}
+	}
+
+	for _, email := range sans.EmailAddresses {
+		if err := checkConstraint("email address", email, ca.PermittedEmailAddresses, ca.ExcludedEmailAddresses, matchEmail); err != nil {
+			return err
+		}
+	}
+
+	for _, uri := range sans.URIs {
+		if err := checkConstraint("uri", uri.String(), ca.PermittedURIDomains, ca.ExcludedURIDomains, matchURI); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkConstraint checks a name against the permitted and excluded constraints of its type
+func checkConstraint(kind string, name string, permitted []string, excluded []string, match func(name string, constraint string) bool) error {
+	for _, constraint := range excluded {
+		if match(name, constraint) {
+			return fmt.Errorf("%w: %s %q is excluded by the ca (%s)", ErrorNameConstraintViolation, kind, name, constraint)
+		}
+	}
+
+	if len(permitted) == 0 {
+		return nil
+	}
+	for _, constraint := range permitted {
+		if match(name, constraint) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s %q is not permitted by the ca (%s)", ErrorNameConstraintViolation, kind, name, strings.Join(permitted, ", "))
+}
+
+// checkSubtrees checks the name constraints of a new intermediate CA against those of a CA above it. When the CA
+// permits a type of name the intermediate has to permit a part of it as well, otherwise the certificates the
+// intermediate issues would not be limited to the permitted names. Permitted subtrees that the CA excludes are not
+// allowed. Violations are reported as errors wrapping ErrorNameConstraintViolation.
+func checkSubtrees(ca *x509.Certificate, intermediate *x509.Certificate) error {
+	if err := checkSubtree("dns", ca.PermittedDNSDomains, ca.ExcludedDNSDomains, intermediate.PermittedDNSDomains, containsDomain); err != nil {
+		return err
+	}
+	if err := checkSubtree("ip", ipRangeStrings(ca.PermittedIPRanges), ipRangeStrings(ca.ExcludedIPRanges), ipRangeStrings(intermediate.PermittedIPRanges), containsIPRange); err != nil {
+		return err
+	}
+	if err := checkSubtree("email", ca.PermittedEmailAddresses, ca.ExcludedEmailAddresses, intermediate.PermittedEmailAddresses, containsEmail); err != nil {
+		return err
+	}
+	return checkSubtree("uri", ca.PermittedURIDomains, ca.ExcludedURIDomains, intermediate.PermittedURIDomains, containsDomain)
+}
+
+// checkSubtree checks the permitted subtrees of an intermediate CA against the permitted and excluded subtrees of
+// the same type of a CA above it
+func checkSubtree(kind string, permitted []string, excluded []string, subtrees []string, contains func(constraint string, subtree string) bool) error {
+	if len(permitted) > 0 && len(subtrees) == 0 {
+		return fmt.Errorf("%w: the ca only permits %s names in %s, the intermediate has to be constrained to them", ErrorNameConstraintViolation, kind, strings.Join(permitted, ", "))
+	}
+
+	for _, subtree := range subtrees {
+		for _, constraint := range excluded {
+			if contains(constraint, subtree) {
+				return fmt.Errorf("%w: %s constraint %q is excluded by the ca (%s)", ErrorNameConstraintViolation, kind, subtree, constraint)
+			}
+		}
+
+		if len(permitted) == 0 {
+			continue
+		}
+		found := false
+		for _, constraint := range permitted {
+			found = found || contains(constraint, subtree)
+		}
+		if !found {
+			return fmt.Errorf("%w: %s constraint %q is not permitted by the ca (%s)", ErrorNameConstraintViolation, kind, subtree, strings.Join(permitted, ", "))
+		}
+	}
+
+	return nil
+}
+
+// containsDomain checks if all names matching the subtree domain constraint match the domain constraint as well
+func containsDomain(constraint string, subtree string) bool {
+	if strings.EqualFold(constraint, subtree) {
+		return true
+	}
+	// A subtree with a leading period holds the subdomains of its domain only
+	return matchDomain(strings.TrimPrefix(subtree, "."), constraint)
+}
+
+// containsIPRange checks if the subtree IP range in CIDR notation is within the IP range constraint
+func containsIPRange(constraint string, subtree string) bool {
+	_, outer, err := net.ParseCIDR(constraint)
+	if err != nil {
+		return false
+	}
+	_, inner, err := net.ParseCIDR(subtree)
+	if err != nil {
+		return false
+	}
+
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// containsEmail checks if all addresses matching the subtree email constraint match the email constraint as well
+func containsEmail(constraint string, subtree string) bool {
+	if strings.Contains(subtree, "@") {
+		return matchEmail(subtree, constraint)
+	}
+	if strings.Contains(constraint, "@") {
+		return false
+	}
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(strings.ToLower(subtree), strings.ToLower(constraint))
+	}
+	// A host constraint only matches the host itself
+	return strings.EqualFold(subtree, constraint)
+}
+
+// matchDomain matches a DNS name against a domain constraint, a constraint with a leading period only matches
+// subdomains
+func matchDomain(name string, constraint string) bool {
+	name, constraint = strings.ToLower(name), strings.ToLower(constraint)
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(name, constraint)
+	}
+	return name == constraint || strings.HasSuffix(name, "."+constraint)
+}
+
+// ipRangeStrings formats the IP ranges in CIDR notation
+func ipRangeStrings(ranges []*net.IPNet) []string {
+	var out []string
+	for _, r := range ranges {
+		out = append(out, r.String())
+	}
+	return out
+}
+
+// matchIP matches an IP address against an IP range constraint in CIDR notation
+func matchIP(ip string, constraint string) bool {
+	_, ipNet, err := net.ParseCIDR(constraint)
+	return err == nil && ipNet.Contains(net.ParseIP(ip))
+}
+
+// matchEmail matches an email address against an address, host or (leading period) domain constraint
+func matchEmail(email string, constraint string) bool {
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+
+	host := email[strings.LastIndex(email, "@")+1:]
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(strings.ToLower(host), strings.ToLower(constraint))
+	}
+	return strings.EqualFold(host, constraint)
+}
+
+// matchURI matches the host of a URI against a domain constraint like matchDomain
+func matchURI(uri string, constraint string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+
+	return parsed.Hostname() != "" && matchDomain(parsed.Hostname(), constraint)
+}
@@ -0,0 +1,160 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestNameConstraints(t *testing.T) {
+	now := time.Now()
+	intermediateCrt, intermediateKey, err := GenerateIntermediateCA(&Request{
+		CommonName: "payments.test.local",
+		KeyType:    KeyTypeECDSA,
+		NotBefore:  now.Add(-time.Hour),
+		NotAfter:   now.AddDate(0, 1, 0),
+		NameConstraints: &NameConstraints{
+			Critical:                true,
+			PermittedDNSDomains:     []string{"*.payments.internal"},
+			ExcludedDNSDomains:      []string{"secret.payments.internal"},
+			PermittedIPRanges:       []string{"10.20.0.0/16"},
+			PermittedEmailAddresses: []string{".payments.internal"},
+			PermittedURIDomains:     []string{"payments.internal"},
+		},
+	}, testCA.Crt, testCA.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(intermediateCrt)
+	intermediate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !intermediate.PermittedDNSDomainsCritical || len(intermediate.PermittedDNSDomains) != 1 || intermediate.PermittedDNSDomains[0] != ".payments.internal" {
+		t.Errorf("GenerateIntermediateCA() name constraints = %v (critical %v)", intermediate.PermittedDNSDomains, intermediate.PermittedDNSDomainsCritical)
+	}
+
+	tests := []struct {
+		name    string
+		sans    []string
+		wantErr error
+	}{
+		{name: "permitted", sans: []string{"api.payments.internal", "*.web.payments.internal", "10.20.1.1", "email:ops@mail.payments.internal", "uri:spiffe://payments.internal/api"}},
+		{name: "uri_subdomain_permitted", sans: []string{"uri:spiffe://api.payments.internal/web"}},
+		{name: "apex_not_permitted", sans: []string{"payments.internal"}, wantErr: ErrorNameConstraintViolation},
+		{name: "dns_not_permitted", sans: []string{"api.payments.internal", "www.test.local"}, wantErr: ErrorNameConstraintViolation},
+		{name: "dns_excluded", sans: []string{"db.secret.payments.internal"}, wantErr: ErrorNameConstraintViolation},
+		{name: "ip_not_permitted", sans: []string{"10.30.0.1"}, wantErr: ErrorNameConstraintViolation},
+		{name: "email_not_permitted", sans: []string{"email:ops@test.local"}, wantErr: ErrorNameConstraintViolation},
+		{name: "uri_not_permitted", sans: []string{"uri:spiffe://test.local/api"}, wantErr: ErrorNameConstraintViolation},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crt, _, err := GenerateCertificate(&Request{
+				CommonName:      "leaf.payments.internal",
+				SubjectAltNames: tt.sans,
+				SerialNumber:    big.NewInt(int64(i + 1)),
+				KeyType:         KeyTypeECDSA,
+				NotBefore:       now.Add(-time.Hour),
+				NotAfter:        now.AddDate(0, 0, 7),
+			}, intermediateCrt, intermediateKey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// The certificates that are issued have to pass the name constraints checks of crypto/x509 as well
+			report, err := Verify(crt, intermediateCrt, testCA.Crt, VerifyOptions{})
+			if err != nil || !report.Valid {
+				t.Errorf("Verify() = %+v, error %v", report, err)
+			}
+		})
+	}
+}
+
+func TestNameConstraints_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints *NameConstraints
+	}{
+		{name: "ip_without_mask", constraints: &NameConstraints{PermittedIPRanges: []string{"10.20.0.0"}}},
+		{name: "dns_wildcard", constraints: &NameConstraints{PermittedDNSDomains: []string{"*.*.payments.internal"}}},
+		{name: "dns_empty", constraints: &NameConstraints{ExcludedDNSDomains: []string{""}}},
+		{name: "email_without_local_part", constraints: &NameConstraints{PermittedEmailAddresses: []string{"@payments.internal"}}},
+		{name: "uri_invalid", constraints: &NameConstraints{PermittedURIDomains: []string{"spiffe://payments.internal"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{CommonName: "ca.test.local", KeyType: KeyTypeECDSA, NameConstraints: tt.constraints}
+			if err := req.Validate(); !errors.Is(err, ErrorInvalidNameConstraint) {
+				t.Errorf("Request.Validate() error = %v, want %v", err, ErrorInvalidNameConstraint)
+			}
+		})
+	}
+}
+
+func TestNameConstraints_Intermediate(t *testing.T) {
+	now := time.Now()
+	intermediate := func(constraints *NameConstraints, chain []byte, caCrt []byte, caKey []byte) ([]byte, []byte, error) {
+		return GenerateIntermediateCA(&Request{
+			CommonName:      "intermediate.test.local",
+			KeyType:         KeyTypeECDSA,
+			NotBefore:       now.Add(-time.Hour),
+			NotAfter:        now.AddDate(0, 1, 0),
+			NameConstraints: constraints,
+			CAChain:         chain,
+		}, caCrt, caKey)
+	}
+
+	paymentsCrt, paymentsKey, err := intermediate(&NameConstraints{
+		PermittedDNSDomains: []string{"payments.internal"},
+		ExcludedDNSDomains:  []string{"secret.payments.internal"},
+		PermittedIPRanges:   []string{"10.20.0.0/16"},
+	}, nil, testCA.Crt, testCA.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		constraints *NameConstraints
+		wantErr     error
+	}{
+		{name: "within", constraints: &NameConstraints{PermittedDNSDomains: []string{"*.api.payments.internal", "web.payments.internal"}, PermittedIPRanges: []string{"10.20.5.0/24"}, PermittedEmailAddresses: []string{"test.local"}}},
+		{name: "same", constraints: &NameConstraints{PermittedDNSDomains: []string{"payments.internal"}, PermittedIPRanges: []string{"10.20.0.0/16"}}},
+		{name: "unconstrained", wantErr: ErrorNameConstraintViolation},
+		{name: "missing_ip", constraints: &NameConstraints{PermittedDNSDomains: []string{"api.payments.internal"}}, wantErr: ErrorNameConstraintViolation},
+		{name: "dns_outside", constraints: &NameConstraints{PermittedDNSDomains: []string{"test.local"}, PermittedIPRanges: []string{"10.20.5.0/24"}}, wantErr: ErrorNameConstraintViolation},
+		{name: "dns_excluded", constraints: &NameConstraints{PermittedDNSDomains: []string{"db.secret.payments.internal"}, PermittedIPRanges: []string{"10.20.5.0/24"}}, wantErr: ErrorNameConstraintViolation},
+		{name: "ip_wider", constraints: &NameConstraints{PermittedDNSDomains: []string{"api.payments.internal"}, PermittedIPRanges: []string{"10.0.0.0/8"}}, wantErr: ErrorNameConstraintViolation},
+		{name: "ip_other_family", constraints: &NameConstraints{PermittedDNSDomains: []string{"api.payments.internal"}, PermittedIPRanges: []string{"::/0"}}, wantErr: ErrorNameConstraintViolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := intermediate(tt.constraints, nil, paymentsCrt, paymentsKey); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GenerateIntermediateCA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Below a sub-intermediate the names are checked against the CAs in the chain as well
+	subCrt, subKey, err := intermediate(&NameConstraints{PermittedDNSDomains: []string{"payments.internal"}, PermittedIPRanges: []string{"10.20.0.0/16"}}, nil, paymentsCrt, paymentsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := &Request{CommonName: "db.secret.payments.internal", SubjectAltNames: []string{"db.secret.payments.internal"}, KeyType: KeyTypeECDSA, NotBefore: now.Add(-time.Hour), NotAfter: now.AddDate(0, 0, 7), CAChain: paymentsCrt}
+	if _, _, err := GenerateCertificate(leaf, subCrt, subKey); !errors.Is(err, ErrorNameConstraintViolation) {
+		t.Errorf("GenerateCertificate() error = %v, want %v", err, ErrorNameConstraintViolation)
+	}
+
+	// The chain of an intermediate is checked as well
+	if _, _, err := intermediate(&NameConstraints{PermittedDNSDomains: []string{"secret.payments.internal"}, PermittedIPRanges: []string{"10.20.0.0/16"}}, paymentsCrt, subCrt, subKey); !errors.Is(err, ErrorNameConstraintViolation) {
+		t.Errorf("GenerateIntermediateCA() error = %v, want %v", err, ErrorNameConstraintViolation)
+	}
+}
@@ -0,0 +1,91 @@
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"time"
+)
+
+// oidExtensionReasonCode is the object identifier of the CRL entry reason code extension (RFC 5280 section 5.3.1)
+var oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// RevokedEntry is the struct needed to add a revoked certificate to a CRL
+type RevokedEntry struct {
+	SerialNumber   *big.Int
+	RevocationTime time.Time
+	// Reason is the RFC 5280 reason code, the reason code extension is left out for ReasonUnspecified
+	Reason int
+}
+
+// GenerateCRL will generate a CRL signed by the given CA Certificate pair (caCrt and caKey) containing the revoked
+// entries and will return the CRL in PEM format as bytes. The number is the (monotonically increasing) CRL number
+// and nextUpdate the time at which the next CRL will be published.
+//
+// The CA Certificate needs the CRL signing key usage, which is set by GenerateCA and GenerateIntermediateCA.
+func GenerateCRL(caCrt []byte, caKey []byte, revoked []RevokedEntry, number *big.Int, nextUpdate time.Time) ([]byte, error) {
+	ca, caPriv, err := parseKeyPair(caCrt, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateCRL(ca, caPriv, revoked, number, nextUpdate)
+}
+
+// generateCRL generates a PEM encoded CRL signed by the CA
+func generateCRL(ca *x509.Certificate, caPriv crypto.Signer, revoked []RevokedEntry, number *big.Int, nextUpdate time.Time) ([]byte, error) {
+	if number == nil || number.Sign() < 0 {
+		return nil, ErrorInvalidCRLNumber
+	}
+
+	now := time.Now()
+	if !nextUpdate.After(now) {
+		return nil, ErrorInvalidNextUpdate
+	}
+
+	revokedCertificates := make([]pkix.RevokedCertificate, 0, len(revoked))
+	for _, entry := range revoked {
+		if entry.SerialNumber == nil {
+			return nil, ErrorInvalidSerialNumber
+		}
+
+		revokedCertificate := pkix.RevokedCertificate{
+			SerialNumber:   entry.SerialNumber,
+			RevocationTime: entry.RevocationTime.UTC(),
+		}
+
+		if entry.Reason != ReasonUnspecified {
+			reason, err := asn1.Marshal(asn1.Enumerated(entry.Reason))
+			if err != nil {
+				return nil, err
+			}
+			revokedCertificate.Extensions = []pkix.Extension{{Id: oidExtensionReasonCode, Value: reason}}
+		}
+
+		revokedCertificates = append(revokedCertificates, revokedCertificate)
+	}
+
+	template := &x509.RevocationList{
+		RevokedCertificates: revokedCertificates,
+		Number:              number,
+		ThisUpdate:          now,
+		NextUpdate:          nextUpdate,
+	}
+
+	crlB, err := x509.CreateRevocationList(rand.Reader, template, ca, caPriv)
+	if err != nil {
+		return nil, err
+	}
+
+	crlOut := bytes.NewBuffer([]byte{})
+	if err := pem.Encode(crlOut, &pem.Block{Type: "X509 CRL", Bytes: crlB}); err != nil {
+		return nil, err
+	}
+
+	return crlOut.Bytes(), nil
+}
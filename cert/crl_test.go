@@ -0,0 +1,153 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestGenerateCRL(t *testing.T) {
+	revokedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	type args struct {
+		caCrt      []byte
+		caKey      []byte
+		revoked    []RevokedEntry
+		number     *big.Int
+		nextUpdate time.Time
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "valid_crl",
+			args: args{
+				caCrt: testCA.Crt,
+				caKey: testCA.Key,
+				revoked: []RevokedEntry{
+					{SerialNumber: big.NewInt(1), RevocationTime: revokedAt, Reason: ReasonKeyCompromise},
+					{SerialNumber: big.NewInt(2), RevocationTime: revokedAt, Reason: ReasonUnspecified},
+				},
+				number:     big.NewInt(1),
+				nextUpdate: time.Now().Add(24 * time.Hour),
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid_empty_crl",
+			args: args{
+				caCrt:      testCA.Crt,
+				caKey:      testCA.Key,
+				revoked:    nil,
+				number:     big.NewInt(2),
+				nextUpdate: time.Now().Add(24 * time.Hour),
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid_ca_key",
+			args: args{
+				caCrt:      testCA.Crt,
+				caKey:      []byte("invalid_key"),
+				number:     big.NewInt(1),
+				nextUpdate: time.Now().Add(24 * time.Hour),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_number",
+			args: args{
+				caCrt:      testCA.Crt,
+				caKey:      testCA.Key,
+				number:     nil,
+				nextUpdate: time.Now().Add(24 * time.Hour),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_next_update",
+			args: args{
+				caCrt:      testCA.Crt,
+				caKey:      testCA.Key,
+				number:     big.NewInt(1),
+				nextUpdate: time.Now().Add(-time.Hour),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_serial_number",
+			args: args{
+				caCrt:      testCA.Crt,
+				caKey:      testCA.Key,
+				revoked:    []RevokedEntry{{SerialNumber: nil, RevocationTime: revokedAt}},
+				number:     big.NewInt(1),
+				nextUpdate: time.Now().Add(24 * time.Hour),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCRL(tt.args.caCrt, tt.args.caKey, tt.args.revoked, tt.args.number, tt.args.nextUpdate)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateCRL() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			block, _ := pem.Decode(got)
+			if block == nil || block.Type != "X509 CRL" {
+				t.Errorf("GenerateCRL() did not return a PEM encoded CRL")
+				return
+			}
+
+			crl, err := x509.ParseCRL(block.Bytes)
+			if err != nil {
+				t.Errorf("GenerateCRL() returned an invalid CRL: %v", err)
+				return
+			}
+
+			ca, _, _ := parseKeyPair(tt.args.caCrt, tt.args.caKey)
+			if err := ca.CheckCRLSignature(crl); err != nil {
+				t.Errorf("GenerateCRL() returned a CRL with an invalid signature: %v", err)
+			}
+
+			entries := crl.TBSCertList.RevokedCertificates
+			if len(entries) != len(tt.args.revoked) {
+				t.Errorf("GenerateCRL() entries = %d, want %d", len(entries), len(tt.args.revoked))
+				return
+			}
+
+			for i, entry := range entries {
+				want := tt.args.revoked[i]
+				if entry.SerialNumber.Cmp(want.SerialNumber) != 0 {
+					t.Errorf("GenerateCRL() serial = %s, want %s", entry.SerialNumber, want.SerialNumber)
+				}
+				if !entry.RevocationTime.Equal(want.RevocationTime) {
+					t.Errorf("GenerateCRL() revocation time = %s, want %s", entry.RevocationTime, want.RevocationTime)
+				}
+
+				reason := ReasonUnspecified
+				for _, ext := range entry.Extensions {
+					if ext.Id.Equal(oidExtensionReasonCode) {
+						var code asn1.Enumerated
+						if _, err := asn1.Unmarshal(ext.Value, &code); err != nil {
+							t.Errorf("GenerateCRL() invalid reason code: %v", err)
+						}
+						reason = int(code)
+					}
+				}
+				if reason != want.Reason {
+					t.Errorf("GenerateCRL() reason = %d, want %d", reason, want.Reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// GenerateCSR will generate a certificate signing request and its key and will return the CSR, key and a possible
+// error. The key will be of the KeyType given in the Request (RSA with a bit size of 4096 by default), the CSR
+// holds the subject and subject alt names of the Request. Both are returned in PEM format as bytes.
+//
+// The serial number, validity and profile of the Request are not part of a CSR and are decided by the signing CA.
+func GenerateCSR(req *Request) ([]byte, []byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	sans, err := ParseSubjectAltNames(req.SubjectAltNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	priv, err := generateKey(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csrB, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:        req.GetPKIXName(),
+		DNSNames:       sans.DNSNames,
+		IPAddresses:    sans.IPAddresses,
+		EmailAddresses: sans.EmailAddresses,
+		URIs:           sans.URIs,
+	}, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBlock, err := encodeKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pemCSROut := bytes.NewBuffer([]byte{})
+	if err := pem.Encode(pemCSROut, &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrB}); err != nil {
+		return nil, nil, err
+	}
+
+	pemKeyOut := bytes.NewBuffer([]byte{})
+	if err := pem.Encode(pemKeyOut, keyBlock); err != nil {
+		return nil, nil, err
+	}
+
+	return pemCSROut.Bytes(), pemKeyOut.Bytes(), nil
+}
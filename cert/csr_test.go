@@ -0,0 +1,129 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGenerateCSR(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *Request
+		wantErr bool
+	}{
+		{
+			name: "rsa",
+			req:  &Request{CommonName: "csr.example.com", Organization: "Example", KeyType: KeyTypeRSA, BitSize: 2048},
+		},
+		{
+			name: "ecdsa",
+			req: &Request{
+				CommonName:       "csr.example.com",
+				NameSerialNumber: "name-serial",
+				KeyType:          KeyTypeECDSA,
+				SubjectAltNames:  []string{"csr.example.com", "192.0.2.1", "email:admin@example.com", "uri:spiffe://example.com/csr"},
+			},
+		},
+		{
+			name: "ed25519",
+			req:  &Request{CommonName: "csr.example.com", KeyType: KeyTypeEd25519},
+		},
+		{
+			name:    "missing-common-name",
+			req:     &Request{KeyType: KeyTypeECDSA},
+			wantErr: true,
+		},
+		{
+			name:    "invalid-subject-alt-name",
+			req:     &Request{CommonName: "csr.example.com", KeyType: KeyTypeECDSA, SubjectAltNames: []string{"email:admin"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr, key, err := GenerateCSR(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCSR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			block, _ := pem.Decode(csr)
+			if block == nil || block.Type != "CERTIFICATE REQUEST" {
+				t.Fatalf("GenerateCSR() returned no certificate request")
+			}
+			csrReq, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := csrReq.CheckSignature(); err != nil {
+				t.Errorf("GenerateCSR() signature error = %v", err)
+			}
+
+			got, err := ReadCSR(csr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.CommonName != tt.req.CommonName || got.Organization != tt.req.Organization || got.NameSerialNumber != tt.req.NameSerialNumber {
+				t.Errorf("ReadCSR() got = %v, want %v", got, tt.req)
+			}
+			if !reflect.DeepEqual(got.SubjectAltNames, tt.req.SubjectAltNames) {
+				t.Errorf("ReadCSR() SubjectAltNames = %v, want %v", got.SubjectAltNames, tt.req.SubjectAltNames)
+			}
+
+			caCrt, caKey, err := GenerateCA(&Request{
+				CommonName: "ca.example.com",
+				NotBefore:  time.Now(),
+				NotAfter:   time.Now().Add(time.Hour),
+				KeyType:    KeyTypeECDSA,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got.NotBefore = time.Now()
+			got.NotAfter = time.Now().Add(time.Hour)
+			crt, err := SignCSR(got, csr, caCrt, caKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tls.X509KeyPair(crt, key); err != nil {
+				t.Errorf("GenerateCSR() key does not match the signed certificate: %v", err)
+			}
+		})
+	}
+}
+
+func TestReadCSR_InvalidSignature(t *testing.T) {
+	csr, _, err := GenerateCSR(&Request{CommonName: "csr.example.com", KeyType: KeyTypeECDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(csr)
+	csrReq, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the key with another one, the signature no longer matches
+	other, err := generateKey(&Request{KeyType: KeyTypeECDSA, Curve: CurveP256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, err := x509.MarshalPKIXPublicKey(other.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(block.Bytes, csrReq.RawSubjectPublicKeyInfo, otherPub, 1)
+
+	if _, err := ReadCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: tampered})); err != ErrorInvalidCSRSignature {
+		t.Errorf("ReadCSR() error = %v, want %v", err, ErrorInvalidCSRSignature)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
 type testData struct {
@@ -23,6 +24,8 @@ func runTests(m *testing.M) int {
 	testCA = &testData{
 		Request: &Request{
 			CommonName: "test.local",
+			NotBefore:  time.Now().Add(-time.Hour),
+			NotAfter:   time.Now().AddDate(1, 0, 0),
 		},
 	}
 	var err error
@@ -0,0 +1,421 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"time"
+)
+
+// Types of the objects found by Inspect
+const (
+	ObjectCertificate  = "certificate"
+	ObjectCSR          = "certificate request"
+	ObjectCRL          = "crl"
+	ObjectPrivateKey   = "private key"
+	ObjectEncryptedKey = "encrypted private key"
+	ObjectPublicKey    = "public key"
+)
+
+// Inspection describes a certificate, CSR, CRL or key found by Inspect. Fields that do not apply to the type of the
+// object are left empty.
+type Inspection struct {
+	Type               string     `json:"type"`
+	Subject            string     `json:"subject,omitempty"`
+	Issuer             string     `json:"issuer,omitempty"`
+	SerialNumber       string     `json:"serial_number,omitempty"`
+	SubjectAltNames    []string   `json:"subject_alt_names,omitempty"`
+	NotBefore          *time.Time `json:"not_before,omitempty"`
+	NotAfter           *time.Time `json:"not_after,omitempty"`
+	ThisUpdate         *time.Time `json:"this_update,omitempty"`
+	NextUpdate         *time.Time `json:"next_update,omitempty"`
+	CRLNumber          string     `json:"crl_number,omitempty"`
+	RevokedSerials     []string   `json:"revoked_serial_numbers,omitempty"`
+	KeyType            string     `json:"key_type,omitempty"`
+	KeySize            int        `json:"key_size,omitempty"`
+	Curve              string     `json:"curve,omitempty"`
+	SignatureAlgorithm string     `json:"signature_algorithm,omitempty"`
+	// SignatureValid is set for CSRs, which are signed by their own key
+	SignatureValid *bool                `json:"signature_valid,omitempty"`
+	IsCA           bool                 `json:"is_ca,omitempty"`
+	KeyUsage       []string             `json:"key_usage,omitempty"`
+	ExtKeyUsage    []string             `json:"ext_key_usage,omitempty"`
+	Extensions     []InspectedExtension `json:"extensions,omitempty"`
+	SubjectKeyID   string               `json:"subject_key_id,omitempty"`
+	AuthorityKeyID string               `json:"authority_key_id,omitempty"`
+	// The fingerprints are the lowercase hex SHA-1 and SHA-256 hashes of the DER encoded certificate, CSR or CRL
+	FingerprintSHA1   string `json:"fingerprint_sha1,omitempty"`
+	FingerprintSHA256 string `json:"fingerprint_sha256,omitempty"`
+	// PublicKeySHA256 is the lowercase hex SHA-256 hash of the DER encoded public key (SubjectPublicKeyInfo)
+	PublicKeySHA256 string `json:"public_key_sha256,omitempty"`
+	// KeyMatches is set when a certificate is given to Inspect and tells if the public key matches it
+	KeyMatches *bool `json:"key_matches,omitempty"`
+
+	PublicKey crypto.PublicKey `json:"-"`
+}
+
+// InspectedExtension is an extension of a certificate, CSR or CRL
+type InspectedExtension struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name,omitempty"`
+	Critical bool   `json:"critical"`
+}
+
+// extensionNames are the names of the well-known extensions
+var extensionNames = map[string]string{
+	"2.5.29.14":               "Subject Key Identifier",
+	"2.5.29.15":               "Key Usage",
+	"2.5.29.17":               "Subject Alternative Name",
+	"2.5.29.18":               "Issuer Alternative Name",
+	"2.5.29.19":               "Basic Constraints",
+	"2.5.29.20":               "CRL Number",
+	"2.5.29.21":               "Reason Code",
+	"2.5.29.30":               "Name Constraints",
+	"2.5.29.31":               "CRL Distribution Points",
+	"2.5.29.32":               "Certificate Policies",
+	"2.5.29.35":               "Authority Key Identifier",
+	"2.5.29.37":               "Extended Key Usage",
+	"1.3.6.1.5.5.7.1.1":       "Authority Information Access",
+	"1.3.6.1.5.5.7.48.1.5":    "OCSP No Check",
+	"1.3.6.1.4.1.11129.2.4.2": "Certificate Transparency SCTs",
+}
+
+// keyUsageNames are the names of all key usages in the order of their bits
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, KeyUsageDigitalSignature},
+	{x509.KeyUsageContentCommitment, KeyUsageContentCommitment},
+	{x509.KeyUsageKeyEncipherment, KeyUsageKeyEncipherment},
+	{x509.KeyUsageDataEncipherment, KeyUsageDataEncipherment},
+	{x509.KeyUsageKeyAgreement, KeyUsageKeyAgreement},
+	{x509.KeyUsageCertSign, "cert-sign"},
+	{x509.KeyUsageCRLSign, "crl-sign"},
+	{x509.KeyUsageEncipherOnly, "encipher-only"},
+	{x509.KeyUsageDecipherOnly, "decipher-only"},
+}
+
+// extKeyUsageNames are the names of the extended key usages that can not be used in a Profile
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "any",
+	x509.ExtKeyUsageIPSECEndSystem:                 "ipsec-end-system",
+	x509.ExtKeyUsageIPSECTunnel:                    "ipsec-tunnel",
+	x509.ExtKeyUsageIPSECUser:                      "ipsec-user",
+	x509.ExtKeyUsageTimeStamping:                   "time-stamping",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "microsoft-server-gated-crypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "netscape-server-gated-crypto",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "microsoft-commercial-code-signing",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "microsoft-kernel-code-signing",
+}
+
+// signatureAlgorithmNames are the names of the signature algorithms of CRLs, which are not parsed by crypto/x509
+var signatureAlgorithmNames = map[string]x509.SignatureAlgorithm{
+	"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+	"1.2.840.113549.1.1.10": x509.SHA256WithRSAPSS,
+	"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+	"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+	"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+	"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+	"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+	"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+	"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+	"1.3.101.112":           x509.PureEd25519,
+}
+
+// Inspect parses the certificates, CSRs, CRLs and keys in data and describes each of them. The data is either one or
+// more PEM blocks or a single DER encoded object. PEM blocks of other types are skipped and ErrorUnknownObject is
+// returned when nothing could be parsed. An encrypted private key is only reported as such.
+//
+// When crt holds a PEM or DER encoded certificate the KeyMatches field of every object with a public key tells if
+// that public key is the public key of the certificate.
+func Inspect(data []byte, crt []byte) ([]*Inspection, error) {
+	var match crypto.PublicKey
+	if len(crt) > 0 {
+		der := crt
+		if block, _ := pem.Decode(crt); block != nil {
+			if block.Type != "CERTIFICATE" {
+				return nil, ErrorInvalidCertificate
+			}
+			der = block.Bytes
+		}
+
+		parsed, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, ErrorInvalidCertificate
+		}
+		match = parsed.PublicKey
+	}
+
+	var inspections []*Inspection
+	if block, _ := pem.Decode(data); block == nil {
+		inspection, err := inspectDER(data)
+		if err != nil {
+			return nil, err
+		}
+		inspections = append(inspections, inspection)
+	} else {
+		for rest := data; ; {
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+
+			inspection, err := inspectBlock(block)
+			if err != nil {
+				return nil, err
+			}
+			if inspection != nil {
+				inspections = append(inspections, inspection)
+			}
+		}
+	}
+
+	if len(inspections) == 0 {
+		return nil, ErrorUnknownObject
+	}
+
+	if match != nil {
+		for _, inspection := range inspections {
+			if inspection.PublicKey != nil {
+				matches := publicKeysEqual(inspection.PublicKey, match)
+				inspection.KeyMatches = &matches
+			}
+		}
+	}
+
+	return inspections, nil
+}
+
+// inspectBlock describes the object in the PEM block, it returns nil for blocks of an unknown type
+func inspectBlock(block *pem.Block) (*Inspection, error) {
+	switch block.Type {
+	case "CERTIFICATE":
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return inspectCertificate(crt), nil
+	case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return nil, ErrorInvalidCSR
+		}
+		return inspectCSR(csr), nil
+	case "X509 CRL":
+		crl, err := x509.ParseDERCRL(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return inspectCRL(crl, block.Bytes), nil
+	case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY":
+		priv, err := parsePrivateKey(pem.EncodeToMemory(block))
+		if err != nil {
+			return nil, err
+		}
+		return inspectPrivateKey(priv)
+	case "ENCRYPTED PRIVATE KEY":
+		return &Inspection{Type: ObjectEncryptedKey}, nil
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return inspectPublicKey(ObjectPublicKey, pub), nil
+	}
+
+	return nil, nil
+}
+
+// inspectDER describes the DER encoded object, trying every type Inspect supports
+func inspectDER(der []byte) (*Inspection, error) {
+	if crt, err := x509.ParseCertificate(der); err == nil {
+		return inspectCertificate(crt), nil
+	}
+	if csr, err := x509.ParseCertificateRequest(der); err == nil {
+		return inspectCSR(csr), nil
+	}
+	if crl, err := x509.ParseDERCRL(der); err == nil {
+		return inspectCRL(crl, der), nil
+	}
+	if priv, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return inspectPrivateKey(priv)
+	}
+	if priv, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return inspectPrivateKey(priv)
+	}
+	if priv, err := x509.ParseECPrivateKey(der); err == nil {
+		return inspectPrivateKey(priv)
+	}
+	if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return inspectPublicKey(ObjectPublicKey, pub), nil
+	}
+
+	return nil, ErrorUnknownObject
+}
+
+// inspectCertificate describes the certificate
+func inspectCertificate(crt *x509.Certificate) *Inspection {
+	inspection := inspectPublicKey(ObjectCertificate, crt.PublicKey)
+	inspection.Subject = crt.Subject.String()
+	inspection.Issuer = crt.Issuer.String()
+	inspection.SerialNumber = crt.SerialNumber.String()
+	inspection.SubjectAltNames = (&SubjectAltNames{
+		DNSNames:       crt.DNSNames,
+		IPAddresses:    crt.IPAddresses,
+		EmailAddresses: crt.EmailAddresses,
+		URIs:           crt.URIs,
+	}).Strings()
+	inspection.NotBefore = &crt.NotBefore
+	inspection.NotAfter = &crt.NotAfter
+	inspection.SignatureAlgorithm = crt.SignatureAlgorithm.String()
+	inspection.IsCA = crt.BasicConstraintsValid && crt.IsCA
+	inspection.Extensions = inspectExtensions(crt.Extensions)
+	inspection.SubjectKeyID = hex.EncodeToString(crt.SubjectKeyId)
+	inspection.AuthorityKeyID = hex.EncodeToString(crt.AuthorityKeyId)
+	inspection.FingerprintSHA1, inspection.FingerprintSHA256 = fingerprints(crt.Raw)
+
+	for _, usage := range keyUsageNames {
+		if crt.KeyUsage&usage.usage != 0 {
+			inspection.KeyUsage = append(inspection.KeyUsage, usage.name)
+		}
+	}
+	for _, usage := range crt.ExtKeyUsage {
+		inspection.ExtKeyUsage = append(inspection.ExtKeyUsage, extKeyUsageName(usage))
+	}
+	for _, oid := range crt.UnknownExtKeyUsage {
+		inspection.ExtKeyUsage = append(inspection.ExtKeyUsage, oid.String())
+	}
+
+	return inspection
+}
+
+// inspectCSR describes the CSR, the subject alt names are read the same way as ReadCSR does
+func inspectCSR(csr *x509.CertificateRequest) *Inspection {
+	valid := csr.CheckSignature() == nil
+
+	inspection := inspectPublicKey(ObjectCSR, csr.PublicKey)
+	inspection.Subject = csr.Subject.String()
+	inspection.SubjectAltNames = requestFromCSR(csr).SubjectAltNames
+	inspection.SignatureAlgorithm = csr.SignatureAlgorithm.String()
+	inspection.SignatureValid = &valid
+	inspection.Extensions = inspectExtensions(csr.Extensions)
+	inspection.FingerprintSHA1, inspection.FingerprintSHA256 = fingerprints(csr.Raw)
+
+	return inspection
+}
+
+// inspectCRL describes the DER encoded CRL
+func inspectCRL(crl *pkix.CertificateList, der []byte) *Inspection {
+	var issuer pkix.Name
+	issuer.FillFromRDNSequence(&crl.TBSCertList.Issuer)
+
+	inspection := &Inspection{
+		Type:       ObjectCRL,
+		Issuer:     issuer.String(),
+		ThisUpdate: &crl.TBSCertList.ThisUpdate,
+		Extensions: inspectExtensions(crl.TBSCertList.Extensions),
+	}
+	if !crl.TBSCertList.NextUpdate.IsZero() {
+		inspection.NextUpdate = &crl.TBSCertList.NextUpdate
+	}
+
+	oid := crl.SignatureAlgorithm.Algorithm.String()
+	inspection.SignatureAlgorithm = oid
+	if algorithm, ok := signatureAlgorithmNames[oid]; ok {
+		inspection.SignatureAlgorithm = algorithm.String()
+	}
+
+	for _, extension := range crl.TBSCertList.Extensions {
+		var number *big.Int
+		if extension.Id.String() == "2.5.29.20" {
+			if _, err := asn1.Unmarshal(extension.Value, &number); err == nil {
+				inspection.CRLNumber = number.String()
+			}
+		}
+	}
+
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		inspection.RevokedSerials = append(inspection.RevokedSerials, revoked.SerialNumber.String())
+	}
+	inspection.FingerprintSHA1, inspection.FingerprintSHA256 = fingerprints(der)
+
+	return inspection
+}
+
+// inspectPrivateKey describes the private key by its public key
+func inspectPrivateKey(priv interface{}) (*Inspection, error) {
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, ErrorInvalidKey
+	}
+
+	return inspectPublicKey(ObjectPrivateKey, signer.Public()), nil
+}
+
+// inspectPublicKey returns an Inspection of the type describing the public key
+func inspectPublicKey(objectType string, pub crypto.PublicKey) *Inspection {
+	inspection := &Inspection{Type: objectType, PublicKey: pub}
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		inspection.KeyType = KeyTypeRSA
+		inspection.KeySize = pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		inspection.KeyType = KeyTypeECDSA
+		inspection.KeySize = pub.Curve.Params().BitSize
+		inspection.Curve = pub.Curve.Params().Name
+	case ed25519.PublicKey:
+		inspection.KeyType = KeyTypeEd25519
+		inspection.KeySize = 256
+	}
+
+	if der, err := x509.MarshalPKIXPublicKey(pub); err == nil {
+		sum := sha256.Sum256(der)
+		inspection.PublicKeySHA256 = hex.EncodeToString(sum[:])
+	}
+
+	return inspection
+}
+
+// inspectExtensions describes the extensions
+func inspectExtensions(extensions []pkix.Extension) []InspectedExtension {
+	var inspected []InspectedExtension
+	for _, extension := range extensions {
+		oid := extension.Id.String()
+		inspected = append(inspected, InspectedExtension{OID: oid, Name: extensionNames[oid], Critical: extension.Critical})
+	}
+
+	return inspected
+}
+
+// extKeyUsageName returns the name of the extended key usage, the names used in a Profile are preferred
+func extKeyUsageName(usage x509.ExtKeyUsage) string {
+	for name, profileUsage := range extKeyUsages {
+		if profileUsage == usage {
+			return name
+		}
+	}
+	if name, ok := extKeyUsageNames[usage]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// fingerprints returns the lowercase hex SHA-1 and SHA-256 hashes of the DER encoded object
+func fingerprints(der []byte) (string, string) {
+	sha1Sum := sha1.Sum(der)
+	sha256Sum := sha256.Sum256(der)
+
+	return hex.EncodeToString(sha1Sum[:]), hex.EncodeToString(sha256Sum[:])
+}
@@ -0,0 +1,182 @@
+package cert
+
+import (
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	crt, key, err := GenerateCertificate(&Request{
+		CommonName:      "www.test.local",
+		SubjectAltNames: []string{"www.test.local", "127.0.0.1"},
+		SerialNumber:    big.NewInt(42),
+		KeyType:         KeyTypeECDSA,
+	}, testCA.Crt, testCA.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, csrKey, err := GenerateCSR(&Request{CommonName: "csr.test.local", SubjectAltNames: []string{"csr.test.local"}, KeyType: KeyTypeEd25519})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := GenerateCRL(testCA.Crt, testCA.Key, []RevokedEntry{{SerialNumber: big.NewInt(42), RevocationTime: time.Now()}}, big.NewInt(7), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedKey, err := EncryptKey(key, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crtBlock, _ := pem.Decode(crt)
+
+	yes, no := true, false
+	tests := []struct {
+		name    string
+		data    []byte
+		crt     []byte
+		check   func(t *testing.T, got []*Inspection)
+		wantErr error
+	}{
+		{
+			name: "certificate",
+			data: crt,
+			check: func(t *testing.T, got []*Inspection) {
+				i := got[0]
+				if i.Type != ObjectCertificate || i.Subject != "CN=www.test.local" || i.Issuer != "CN=test.local" || i.SerialNumber != "42" {
+					t.Errorf("Inspect() = %+v", i)
+				}
+				if !reflect.DeepEqual(i.SubjectAltNames, []string{"www.test.local", "127.0.0.1"}) {
+					t.Errorf("Inspect() SubjectAltNames = %v", i.SubjectAltNames)
+				}
+				if i.KeyType != KeyTypeECDSA || i.KeySize != 256 || i.Curve != "P-256" || i.IsCA {
+					t.Errorf("Inspect() key = %s %d %s, ca %v", i.KeyType, i.KeySize, i.Curve, i.IsCA)
+				}
+				if !reflect.DeepEqual(i.KeyUsage, []string{KeyUsageDigitalSignature}) || len(i.ExtKeyUsage) != 2 {
+					t.Errorf("Inspect() usages = %v %v", i.KeyUsage, i.ExtKeyUsage)
+				}
+				if len(i.FingerprintSHA256) != 64 || len(i.FingerprintSHA1) != 40 || i.AuthorityKeyID == "" || len(i.Extensions) == 0 {
+					t.Errorf("Inspect() = %+v", i)
+				}
+				if i.KeyMatches != nil {
+					t.Errorf("Inspect() KeyMatches = %v, want nil", *i.KeyMatches)
+				}
+			},
+		},
+		{
+			name: "der_certificate",
+			data: crtBlock.Bytes,
+			check: func(t *testing.T, got []*Inspection) {
+				if got[0].Type != ObjectCertificate || got[0].SerialNumber != "42" {
+					t.Errorf("Inspect() = %+v", got[0])
+				}
+			},
+		},
+		{
+			name: "ca_certificate",
+			data: testCA.Crt,
+			check: func(t *testing.T, got []*Inspection) {
+				if !got[0].IsCA || !reflect.DeepEqual(got[0].KeyUsage, []string{KeyUsageDigitalSignature, "cert-sign", "crl-sign"}) {
+					t.Errorf("Inspect() = %+v", got[0])
+				}
+				if got[0].KeyType != KeyTypeRSA || got[0].KeySize != 4096 {
+					t.Errorf("Inspect() key = %s %d", got[0].KeyType, got[0].KeySize)
+				}
+			},
+		},
+		{
+			name: "chain",
+			data: append(append([]byte{}, crt...), testCA.Crt...),
+			crt:  crt,
+			check: func(t *testing.T, got []*Inspection) {
+				if len(got) != 2 || !reflect.DeepEqual(got[0].KeyMatches, &yes) || !reflect.DeepEqual(got[1].KeyMatches, &no) {
+					t.Errorf("Inspect() = %+v", got)
+				}
+			},
+		},
+		{
+			name: "csr",
+			data: csr,
+			check: func(t *testing.T, got []*Inspection) {
+				i := got[0]
+				if i.Type != ObjectCSR || i.Subject != "CN=csr.test.local" || i.KeyType != KeyTypeEd25519 || !reflect.DeepEqual(i.SignatureValid, &yes) {
+					t.Errorf("Inspect() = %+v", i)
+				}
+				if !reflect.DeepEqual(i.SubjectAltNames, []string{"csr.test.local"}) {
+					t.Errorf("Inspect() SubjectAltNames = %v", i.SubjectAltNames)
+				}
+			},
+		},
+		{
+			name: "crl",
+			data: crl,
+			check: func(t *testing.T, got []*Inspection) {
+				i := got[0]
+				if i.Type != ObjectCRL || i.Issuer != "CN=test.local" || i.CRLNumber != "7" || i.NextUpdate == nil || i.SignatureAlgorithm != "SHA256-RSA" {
+					t.Errorf("Inspect() = %+v", i)
+				}
+				if !reflect.DeepEqual(i.RevokedSerials, []string{"42"}) {
+					t.Errorf("Inspect() RevokedSerials = %v", i.RevokedSerials)
+				}
+			},
+		},
+		{
+			name: "key_matching",
+			data: key,
+			crt:  crt,
+			check: func(t *testing.T, got []*Inspection) {
+				if got[0].Type != ObjectPrivateKey || got[0].KeyType != KeyTypeECDSA || !reflect.DeepEqual(got[0].KeyMatches, &yes) {
+					t.Errorf("Inspect() = %+v", got[0])
+				}
+			},
+		},
+		{
+			name: "key_not_matching",
+			data: csrKey,
+			crt:  crt,
+			check: func(t *testing.T, got []*Inspection) {
+				if !reflect.DeepEqual(got[0].KeyMatches, &no) {
+					t.Errorf("Inspect() = %+v", got[0])
+				}
+			},
+		},
+		{
+			name: "encrypted_key",
+			data: encryptedKey,
+			check: func(t *testing.T, got []*Inspection) {
+				if got[0].Type != ObjectEncryptedKey || got[0].KeyType != "" {
+					t.Errorf("Inspect() = %+v", got[0])
+				}
+			},
+		},
+		{
+			name:    "unknown_pem",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{1}}),
+			wantErr: ErrorUnknownObject,
+		},
+		{
+			name:    "garbage",
+			data:    []byte("garbage"),
+			wantErr: ErrorUnknownObject,
+		},
+		{
+			name:    "invalid_match_certificate",
+			data:    key,
+			crt:     key,
+			wantErr: ErrorInvalidCertificate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inspect(tt.data, tt.crt)
+			if err != tt.wantErr {
+				t.Fatalf("Inspect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, got)
+			}
+		})
+	}
+}
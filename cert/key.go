@@ -0,0 +1,134 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"strings"
+)
+
+const (
+	// KeyTypeRSA generates RSA keys with the bit size given in Request.BitSize
+	KeyTypeRSA = "rsa"
+	// KeyTypeECDSA generates ECDSA keys on the curve given in Request.Curve
+	KeyTypeECDSA = "ecdsa"
+	// KeyTypeEd25519 generates Ed25519 keys
+	KeyTypeEd25519 = "ed25519"
+)
+
+const (
+	// CurveP256 is the NIST P-256 curve
+	CurveP256 = "P-256"
+	// CurveP384 is the NIST P-384 curve
+	CurveP384 = "P-384"
+	// CurveP521 is the NIST P-521 curve
+	CurveP521 = "P-521"
+)
+
+const defaultKeyType = KeyTypeRSA
+
+const defaultCurve = CurveP256
+
+var keyTypeOptions = []string{KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519}
+
+func isValidKeyTypeOption(option string) bool {
+	for _, v := range keyTypeOptions {
+		if v == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeCurve accepts the common spellings of a curve name (P256, p-256, prime256v1, ...) and returns
+// the canonical name or an empty string when the curve is unknown.
+func normalizeCurve(curve string) string {
+	switch strings.ToUpper(strings.ReplaceAll(curve, "-", "")) {
+	case "P256", "PRIME256V1", "SECP256R1":
+		return CurveP256
+	case "P384", "SECP384R1":
+		return CurveP384
+	case "P521", "SECP521R1":
+		return CurveP521
+	}
+
+	return ""
+}
+
+func ellipticCurve(curve string) elliptic.Curve {
+	switch curve {
+	case CurveP256:
+		return elliptic.P256()
+	case CurveP384:
+		return elliptic.P384()
+	case CurveP521:
+		return elliptic.P521()
+	}
+
+	return nil
+}
+
+// generateKey generates a private key based on the KeyType, BitSize and Curve of the (validated) Request
+func generateKey(req *Request) (crypto.Signer, error) {
+	switch req.KeyType {
+	case KeyTypeECDSA:
+		return ecdsa.GenerateKey(ellipticCurve(req.Curve), rand.Reader)
+	case KeyTypeEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return rsa.GenerateKey(rand.Reader, req.BitSize)
+	}
+}
+
+// encodeKey encodes a private key into a PEM block. RSA keys are encoded as PKCS#1 ("RSA PRIVATE KEY"),
+// ECDSA keys as SEC 1 ("EC PRIVATE KEY") and Ed25519 keys as PKCS#8 ("PRIVATE KEY").
+func encodeKey(priv crypto.PrivateKey) (*pem.Block, error) {
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}, nil
+	case *ecdsa.PrivateKey:
+		b, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}, nil
+	case ed25519.PrivateKey:
+		b, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: b}, nil
+	}
+
+	return nil, ErrorInvalidKeyType
+}
+
+// subjectKeyID calculates the Subject Key Identifier of the public key as the SHA-1 hash of the subject public key
+// bit string (RFC 5280 section 4.2.1.2, method 1)
+func subjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &publicKeyInfo); err != nil {
+		return nil, err
+	}
+
+	h := sha1.Sum(publicKeyInfo.PublicKey.Bytes)
+	return h[:], nil
+}
@@ -0,0 +1,127 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestNormalizeCurve(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve string
+		want  string
+	}{
+		{name: "p256", curve: "P256", want: CurveP256},
+		{name: "p-256", curve: "p-256", want: CurveP256},
+		{name: "prime256v1", curve: "prime256v1", want: CurveP256},
+		{name: "p384", curve: "P-384", want: CurveP384},
+		{name: "secp521r1", curve: "secp521r1", want: CurveP521},
+		{name: "unknown", curve: "P-192", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeCurve(tt.curve); got != tt.want {
+				t.Errorf("normalizeCurve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *Request
+		wantBlock string
+	}{
+		{
+			name:      "rsa",
+			req:       &Request{KeyType: KeyTypeRSA, BitSize: 2048},
+			wantBlock: "RSA PRIVATE KEY",
+		},
+		{
+			name:      "ecdsa",
+			req:       &Request{KeyType: KeyTypeECDSA, Curve: CurveP256},
+			wantBlock: "EC PRIVATE KEY",
+		},
+		{
+			name:      "ed25519",
+			req:       &Request{KeyType: KeyTypeEd25519},
+			wantBlock: "PRIVATE KEY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priv, err := generateKey(tt.req)
+			if err != nil {
+				t.Errorf("generateKey() error = %v", err)
+				return
+			}
+
+			switch priv.(type) {
+			case *rsa.PrivateKey:
+				if tt.req.KeyType != KeyTypeRSA {
+					t.Errorf("generateKey() = %T, want %s", priv, tt.req.KeyType)
+				}
+			case *ecdsa.PrivateKey:
+				if tt.req.KeyType != KeyTypeECDSA {
+					t.Errorf("generateKey() = %T, want %s", priv, tt.req.KeyType)
+				}
+			case ed25519.PrivateKey:
+				if tt.req.KeyType != KeyTypeEd25519 {
+					t.Errorf("generateKey() = %T, want %s", priv, tt.req.KeyType)
+				}
+			}
+
+			block, err := encodeKey(priv)
+			if err != nil {
+				t.Errorf("encodeKey() error = %v", err)
+				return
+			}
+			if block.Type != tt.wantBlock {
+				t.Errorf("encodeKey() block type = %v, want %v", block.Type, tt.wantBlock)
+			}
+		})
+	}
+}
+
+func TestSubjectKeyID(t *testing.T) {
+	for _, keyType := range []string{KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519} {
+		t.Run(keyType, func(t *testing.T) {
+			caCrt, caKey, err := GenerateCA(&Request{CommonName: "ca.test.local", KeyType: keyType, BitSize: 2048})
+			if err != nil {
+				t.Fatal(err)
+			}
+			crt, _, err := GenerateCertificate(&Request{CommonName: "leaf.test.local", KeyType: keyType, BitSize: 2048}, caCrt, caKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ca, _ := pem.Decode(caCrt)
+			caCert, err := x509.ParseCertificate(ca.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaf, _ := pem.Decode(crt)
+			leafCert, err := x509.ParseCertificate(leaf.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := subjectKeyID(leafCert.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(leafCert.SubjectKeyId, want) {
+				t.Errorf("subjectKeyID() = %x, want %x", leafCert.SubjectKeyId, want)
+			}
+			if len(caCert.SubjectKeyId) == 0 || !bytes.Equal(leafCert.AuthorityKeyId, caCert.SubjectKeyId) {
+				t.Errorf("AuthorityKeyId = %x, want %x", leafCert.AuthorityKeyId, caCert.SubjectKeyId)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package cert
+
+// GenerateOCSPSigner will generate a delegated OCSP signing certificate pair and will return certificate, key and
+// a possible error. The certificate is signed by the given CA Certificate pair (caCrt and caKey) and always uses
+// the built-in ocsp-signing profile: it has the OCSP signing extended key usage and carries the OCSP no check
+// extension, so clients do not check the revocation status of the responder itself. Keep the validity of the OCSP
+// signer short for that reason.
+func GenerateOCSPSigner(req *Request, caCrt []byte, caKey []byte) ([]byte, []byte, error) {
+	req.Profile = ProfileOCSPSigning
+	req.Profiles = nil
+
+	return GenerateCertificate(req, caCrt, caKey)
+}
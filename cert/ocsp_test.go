@@ -0,0 +1,66 @@
+package cert
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestGenerateOCSPSigner(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *Request
+		caKey   []byte
+		wantErr bool
+	}{
+		{
+			name:    "valid_signer",
+			req:     &Request{CommonName: "ocsp.test.local", KeyType: KeyTypeECDSA},
+			caKey:   testCA.Key,
+			wantErr: false,
+		},
+		{
+			name:    "invalid_common_name",
+			req:     &Request{KeyType: KeyTypeECDSA},
+			caKey:   testCA.Key,
+			wantErr: true,
+		},
+		{
+			name:    "invalid_ca_key",
+			req:     &Request{CommonName: "ocsp.test.local", KeyType: KeyTypeECDSA},
+			caKey:   []byte("invalid_key"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1, err := GenerateOCSPSigner(tt.req, testCA.Crt, tt.caKey)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateOCSPSigner() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			crt, _, err := parseKeyPair(got, got1)
+			if err != nil {
+				t.Errorf("GenerateOCSPSigner() returned invalid pair: %v", err)
+				return
+			}
+
+			if len(crt.ExtKeyUsage) != 1 || crt.ExtKeyUsage[0] != x509.ExtKeyUsageOCSPSigning {
+				t.Errorf("GenerateOCSPSigner() ExtKeyUsage = %v, want %v", crt.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning)
+			}
+
+			noCheck := false
+			for _, ext := range crt.Extensions {
+				if ext.Id.Equal(oidExtensionOCSPNoCheck) {
+					noCheck = true
+				}
+			}
+			if !noCheck {
+				t.Errorf("GenerateOCSPSigner() is missing the ocsp no check extension")
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"golang.org/x/crypto/pbkdf2"
+	"hash"
+)
+
+// Encrypted private keys are PKCS#8 EncryptedPrivateKeyInfo structures using PBES2 (RFC 8018) with PBKDF2 and AES-CBC,
+// the same format "openssl pkcs8 -topk8 -v2 aes-256-cbc" writes.
+const (
+	// pbkdf2Iterations is the number of PBKDF2-HMAC-SHA256 iterations used to derive the encryption key
+	pbkdf2Iterations = 600000
+	pbkdf2SaltSize   = 16
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// EncryptKey encrypts a PEM encoded private key with the passphrase and returns it as a PEM encoded PKCS#8
+// "ENCRYPTED PRIVATE KEY". The key is encrypted with AES-256-CBC using a key derived with PBKDF2-HMAC-SHA256.
+func EncryptKey(key []byte, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrorEmptyPassphrase
+	}
+
+	priv, err := parsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, pbkdf2SaltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, salt, pbkdf2Iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	encrypted := append(der, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		KeyLength:      32,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: b}), nil
+}
+
+// DecryptKey decrypts a PEM encoded PKCS#8 "ENCRYPTED PRIVATE KEY" with the passphrase. The key is returned PEM
+// encoded the same way generated keys are. Keys encrypted with PBES2 using PBKDF2 (HMAC-SHA1, -SHA256 or -SHA512)
+// and AES-CBC are supported.
+func DecryptKey(key []byte, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrorEmptyPassphrase
+	}
+
+	block, _ := pem.Decode(key)
+	if block == nil || block.Type != "ENCRYPTED PRIVATE KEY" {
+		return nil, ErrorInvalidKey
+	}
+
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, ErrorInvalidKey
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, ErrorUnsupportedKeyEncryption
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, ErrorInvalidKey
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, ErrorUnsupportedKeyEncryption
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, ErrorInvalidKey
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdfParams.PRF.Algorithm) == 0, kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, ErrorUnsupportedKeyEncryption
+	}
+
+	var keyLength int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLength = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keyLength = 24
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLength = 32
+	default:
+		return nil, ErrorUnsupportedKeyEncryption
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, ErrorInvalidKey
+	}
+	if kdfParams.IterationCount < 1 || len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, ErrorInvalidKey
+	}
+
+	c, err := aes.NewCipher(pbkdf2.Key(passphrase, kdfParams.Salt, kdfParams.IterationCount, keyLength, prf))
+	if err != nil {
+		return nil, err
+	}
+
+	der := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(c, iv).CryptBlocks(der, info.EncryptedData)
+
+	// A wrong passphrase shows as invalid padding or, when the padding happens to be valid, as an invalid key
+	padding := int(der[len(der)-1])
+	if padding < 1 || padding > aes.BlockSize || !bytes.Equal(der[len(der)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, ErrorIncorrectPassphrase
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(der[:len(der)-padding])
+	if err != nil {
+		return nil, ErrorIncorrectPassphrase
+	}
+
+	decrypted, err := encodeKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(decrypted), nil
+}
+
+// IsEncryptedKey reports whether the PEM encoded private key is a PKCS#8 "ENCRYPTED PRIVATE KEY"
+func IsEncryptedKey(key []byte) bool {
+	block, _ := pem.Decode(key)
+	return block != nil && block.Type == "ENCRYPTED PRIVATE KEY"
+}
+
+// parsePrivateKey parses a PEM encoded PKCS#1, SEC 1 or PKCS#8 private key
+func parsePrivateKey(key []byte) (interface{}, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, ErrorInvalidKey
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		if priv, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return priv, nil
+		}
+	case "EC PRIVATE KEY":
+		if priv, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+			return priv, nil
+		}
+	case "PRIVATE KEY":
+		if priv, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			return priv, nil
+		}
+	}
+
+	return nil, ErrorInvalidKey
+}
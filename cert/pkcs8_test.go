@@ -0,0 +1,79 @@
+package cert
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+)
+
+func TestEncryptKey(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *Request
+	}{
+		{name: "rsa", req: &Request{KeyType: KeyTypeRSA, BitSize: 2048}},
+		{name: "ecdsa", req: &Request{KeyType: KeyTypeECDSA, Curve: CurveP384}},
+		{name: "ed25519", req: &Request{KeyType: KeyTypeEd25519}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priv, err := generateKey(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, err := encodeKey(priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := pem.EncodeToMemory(block)
+
+			encrypted, err := EncryptKey(key, []byte("secret"))
+			if err != nil {
+				t.Fatalf("EncryptKey() error = %v", err)
+			}
+			if !IsEncryptedKey(encrypted) || IsEncryptedKey(key) {
+				t.Fatalf("IsEncryptedKey() does not detect the encrypted key")
+			}
+			if bytes.Contains(encrypted, block.Bytes) {
+				t.Fatalf("EncryptKey() returned the key unencrypted")
+			}
+
+			decrypted, err := DecryptKey(encrypted, []byte("secret"))
+			if err != nil {
+				t.Fatalf("DecryptKey() error = %v", err)
+			}
+			if !bytes.Equal(decrypted, key) {
+				t.Errorf("DecryptKey() = %s, want %s", decrypted, key)
+			}
+
+			if _, err := DecryptKey(encrypted, []byte("wrong")); err != ErrorIncorrectPassphrase {
+				t.Errorf("DecryptKey() error = %v, want %v", err, ErrorIncorrectPassphrase)
+			}
+		})
+	}
+}
+
+func TestEncryptKey_Errors(t *testing.T) {
+	priv, err := generateKey(&Request{KeyType: KeyTypeEd25519})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := encodeKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := pem.EncodeToMemory(block)
+
+	if _, err := EncryptKey(key, nil); err != ErrorEmptyPassphrase {
+		t.Errorf("EncryptKey() error = %v, want %v", err, ErrorEmptyPassphrase)
+	}
+	if _, err := EncryptKey([]byte("not a key"), []byte("secret")); err != ErrorInvalidKey {
+		t.Errorf("EncryptKey() error = %v, want %v", err, ErrorInvalidKey)
+	}
+	if _, err := DecryptKey(key, []byte("secret")); err != ErrorInvalidKey {
+		t.Errorf("DecryptKey() error = %v, want %v", err, ErrorInvalidKey)
+	}
+	if _, err := DecryptKey(key, nil); err != ErrorEmptyPassphrase {
+		t.Errorf("DecryptKey() error = %v, want %v", err, ErrorEmptyPassphrase)
+	}
+}
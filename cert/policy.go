@@ -0,0 +1,172 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Policy restricts the certificates a CA issues. The zero value allows everything. When a Request has a Policy it
+// is checked before the certificate is signed, violations are reported as errors wrapping ErrorPolicyViolation with
+// a description of the violation.
+type Policy struct {
+	// AllowedDomains are the domains the common name, DNS subject alt names, the domains of email addresses and the
+	// hosts of URIs must be in, either the domain itself or a subdomain. A common name that is not a DNS name, such
+	// as the name of a person, is not checked. All names are allowed when empty.
+	AllowedDomains []string `json:"allowed_domains"`
+	// AllowIPAddresses allows IP address subject alt names when there are AllowedDomains, which reject them otherwise
+	AllowIPAddresses bool `json:"allow_ip_addresses"`
+	// ForbidWildcards rejects wildcard names, any common name or DNS subject alt name with a "*" in it
+	ForbidWildcards bool `json:"forbid_wildcards"`
+	// MaxValidity is the maximum time between NotBefore and NotAfter, in JSON as a duration string such as "2160h"
+	MaxValidity time.Duration `json:"-"`
+	// Organization is the organization the subject must have
+	Organization string `json:"organization"`
+	// KeyTypes are the allowed key types (rsa, ecdsa and ed25519), all are allowed when empty
+	KeyTypes []string `json:"key_types"`
+	// MinRSABitSize and MinECDSABitSize are the minimum key sizes, for ecdsa keys the size of the curve
+	MinRSABitSize   int `json:"min_rsa_bit_size"`
+	MinECDSABitSize int `json:"min_ecdsa_bit_size"`
+}
+
+// LoadPolicy parses a JSON encoded Policy
+func LoadPolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// UnmarshalJSON decodes the policy and parses its max_validity
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	type policy Policy
+	aux := struct {
+		*policy
+		MaxValidity string `json:"max_validity"`
+	}{policy: (*policy)(p)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.MaxValidity != "" {
+		maxValidity, err := time.ParseDuration(aux.MaxValidity)
+		if err != nil {
+			return err
+		}
+		p.MaxValidity = maxValidity
+	}
+
+	return nil
+}
+
+// Check checks the request and the public key of the certificate against the policy
+func (p *Policy) Check(req *Request, pub crypto.PublicKey) error {
+	if p.ForbidWildcards && strings.Contains(req.CommonName, "*") {
+		return fmt.Errorf("%w: wildcard name %q is not allowed", ErrorPolicyViolation, req.CommonName)
+	}
+
+	sans, err := ParseSubjectAltNames(req.SubjectAltNames)
+	if err != nil {
+		return err
+	}
+
+	// The common name is only checked against the allowed domains when it is a DNS name, a common name like the
+	// name of a person in a client certificate is not a name in a domain
+	var names []string
+	if isValidDNSName(req.CommonName) {
+		names = append(names, req.CommonName)
+	}
+	names = append(names, sans.DNSNames...)
+
+	for _, name := range names {
+		if p.ForbidWildcards && strings.Contains(name, "*") {
+			return fmt.Errorf("%w: wildcard name %q is not allowed", ErrorPolicyViolation, name)
+		}
+		if !p.isAllowedDomain(name) {
+			return fmt.Errorf("%w: name %q is not in an allowed domain", ErrorPolicyViolation, name)
+		}
+	}
+
+	if len(p.AllowedDomains) > 0 && !p.AllowIPAddresses && len(sans.IPAddresses) > 0 {
+		return fmt.Errorf("%w: ip address %q is not allowed", ErrorPolicyViolation, sans.IPAddresses[0].String())
+	}
+
+	for _, email := range sans.EmailAddresses {
+		if !p.isAllowedDomain(email[strings.LastIndex(email, "@")+1:]) {
+			return fmt.Errorf("%w: email address %q is not in an allowed domain", ErrorPolicyViolation, email)
+		}
+	}
+
+	for _, uri := range sans.URIs {
+		if !p.isAllowedDomain(uri.Hostname()) {
+			return fmt.Errorf("%w: uri %q is not in an allowed domain", ErrorPolicyViolation, uri.String())
+		}
+	}
+
+	if p.MaxValidity > 0 && req.NotAfter.Sub(req.NotBefore) > p.MaxValidity {
+		return fmt.Errorf("%w: validity of %s exceeds the maximum of %s", ErrorPolicyViolation, req.NotAfter.Sub(req.NotBefore), p.MaxValidity)
+	}
+
+	if p.Organization != "" && req.Organization != p.Organization {
+		return fmt.Errorf("%w: organization %q is not %q", ErrorPolicyViolation, req.Organization, p.Organization)
+	}
+
+	return p.checkKey(pub)
+}
+
+// isAllowedDomain checks if the name is one of the allowed domains or a subdomain of one
+func (p *Policy) isAllowedDomain(name string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+
+	name = strings.ToLower(strings.TrimPrefix(name, "*."))
+	for _, domain := range p.AllowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkKey checks the type and size of the public key
+func (p *Policy) checkKey(pub crypto.PublicKey) error {
+	var keyType string
+	var size, minSize int
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		keyType, size, minSize = KeyTypeRSA, k.N.BitLen(), p.MinRSABitSize
+	case *ecdsa.PublicKey:
+		keyType, size, minSize = KeyTypeECDSA, k.Curve.Params().BitSize, p.MinECDSABitSize
+	case ed25519.PublicKey:
+		keyType = KeyTypeEd25519
+	default:
+		return fmt.Errorf("%w: unsupported key", ErrorPolicyViolation)
+	}
+
+	if len(p.KeyTypes) > 0 {
+		allowed := false
+		for _, t := range p.KeyTypes {
+			allowed = allowed || t == keyType
+		}
+		if !allowed {
+			return fmt.Errorf("%w: key type %s is not allowed", ErrorPolicyViolation, keyType)
+		}
+	}
+
+	if size < minSize {
+		return fmt.Errorf("%w: %s key size of %d bits is below the minimum of %d", ErrorPolicyViolation, keyType, size, minSize)
+	}
+
+	return nil
+}
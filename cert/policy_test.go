@@ -0,0 +1,278 @@
+package cert
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	rsa2048, err := generateKey(&Request{KeyType: KeyTypeRSA, BitSize: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := generateKey(&Request{KeyType: KeyTypeECDSA, Curve: CurveP256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := generateKey(&Request{KeyType: KeyTypeEd25519})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadPolicy([]byte(`{
+		"allowed_domains": ["example.com", ".example.org"],
+		"allow_ip_addresses": true,
+		"forbid_wildcards": true,
+		"max_validity": "720h",
+		"organization": "Example",
+		"key_types": ["rsa", "ecdsa"],
+		"min_rsa_bit_size": 3072,
+		"min_ecdsa_bit_size": 256
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		policy    *Policy
+		req       *Request
+		pub       crypto.PublicKey
+		wantErr   bool
+		wantErrIs error
+	}{
+		{
+			name:   "empty-policy",
+			policy: &Policy{},
+			req:    &Request{CommonName: "*.anything.test", NotBefore: now, NotAfter: now.AddDate(10, 0, 0)},
+			pub:    ed.Public(),
+		},
+		{
+			name:   "valid",
+			policy: policy,
+			req:    &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"example.com", "api.example.org", "192.0.2.1", "email:ops@example.com", "uri:spiffe://example.org/web"}, NotBefore: now, NotAfter: now.Add(24 * time.Hour)},
+			pub:    p256.Public(),
+		},
+		{
+			name:   "ip-address-without-allowed-domains",
+			policy: &Policy{},
+			req:    &Request{CommonName: "www.example.com", SubjectAltNames: []string{"192.0.2.1"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:    p256.Public(),
+		},
+		{
+			name:    "ip-address-not-allowed",
+			policy:  &Policy{AllowedDomains: []string{"example.com"}},
+			req:     &Request{CommonName: "www.example.com", SubjectAltNames: []string{"192.0.2.1"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "email-not-allowed",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"email:x@evil.test"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "email-suffix-not-a-subdomain",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"email:x@notexample.com"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "uri-not-allowed",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"uri:https://evil.test/example.com"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "common-name-not-allowed",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.net", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "suffix-not-a-subdomain",
+			policy:  policy,
+			req:     &Request{CommonName: "www.notexample.com", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "subject-alt-name-not-allowed",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"evil.test"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "wildcard",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"*.example.com"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "wildcard-common-name",
+			policy:  policy,
+			req:     &Request{CommonName: "*.example.com", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "partial-wildcard-common-name",
+			policy:  policy,
+			req:     &Request{CommonName: "foo*.example.com", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "inner-wildcard-common-name",
+			policy:  policy,
+			req:     &Request{CommonName: "a.*.example.com", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:      "partial-wildcard",
+			policy:    policy,
+			req:       &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"foo*.example.com"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:       p256.Public(),
+			wantErr:   true,
+			wantErrIs: ErrorInvalidSubjectAltName,
+		},
+		{
+			name:      "inner-wildcard",
+			policy:    policy,
+			req:       &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"a.*.example.com"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:       p256.Public(),
+			wantErr:   true,
+			wantErrIs: ErrorInvalidSubjectAltName,
+		},
+		{
+			name:   "common-name-not-a-dns-name",
+			policy: policy,
+			req:    &Request{CommonName: "John Doe", Organization: "Example", SubjectAltNames: []string{"email:john@example.com"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:    p256.Public(),
+		},
+		{
+			name:    "common-name-not-a-dns-name-with-wildcard",
+			policy:  policy,
+			req:     &Request{CommonName: "John * Doe", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "max-validity",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", NotBefore: now, NotAfter: now.Add(31 * 24 * time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "organization",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Other", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "key-type",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     ed.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "uri-without-host",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", SubjectAltNames: []string{"uri:urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66"}, NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "rsa-key-size",
+			policy:  policy,
+			req:     &Request{CommonName: "www.example.com", Organization: "Example", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     rsa2048.Public(),
+			wantErr: true,
+		},
+		{
+			name:    "ecdsa-key-size",
+			policy:  &Policy{MinECDSABitSize: 384},
+			req:     &Request{CommonName: "www.example.com", NotBefore: now, NotAfter: now.Add(time.Hour)},
+			pub:     p256.Public(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.req, tt.pub)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			wantErrIs := tt.wantErrIs
+			if wantErrIs == nil {
+				wantErrIs = ErrorPolicyViolation
+			}
+			if err != nil && !errors.Is(err, wantErrIs) {
+				t.Errorf("Check() error = %v, want %v", err, wantErrIs)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy([]byte(`{"max_validity": "2160h", "min_rsa_bit_size": 4096}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.MaxValidity != 2160*time.Hour || policy.MinRSABitSize != 4096 {
+		t.Errorf("LoadPolicy() = %+v", policy)
+	}
+
+	if _, err := LoadPolicy([]byte(`{"max_validity": "90 days"}`)); err == nil {
+		t.Errorf("LoadPolicy() expected an error for an invalid max_validity")
+	}
+	if _, err := LoadPolicy([]byte(`[]`)); err == nil {
+		t.Errorf("LoadPolicy() expected an error for invalid JSON")
+	}
+}
+
+func TestSignCSR_Policy(t *testing.T) {
+	caCrt, caKey, err := GenerateCA(&Request{
+		CommonName: "ca.example.com",
+		NotBefore:  time.Now(),
+		NotAfter:   time.Now().Add(time.Hour),
+		KeyType:    KeyTypeECDSA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csr, _, err := GenerateCSR(&Request{CommonName: "www.example.net", KeyType: KeyTypeECDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := ReadCSR(csr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Policy = &Policy{AllowedDomains: []string{"example.com"}}
+
+	if _, err := SignCSR(req, csr, caCrt, caKey); !errors.Is(err, ErrorPolicyViolation) {
+		t.Errorf("SignCSR() error = %v, want %v", err, ErrorPolicyViolation)
+	}
+
+	req.Policy.AllowedDomains = append(req.Policy.AllowedDomains, "example.net")
+	if _, err := SignCSR(req, csr, caCrt, caKey); err != nil {
+		t.Errorf("SignCSR() error = %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+)
+
+// Names of the built-in profiles
+const (
+	// ProfileDefault is used when a Request has no profile, the certificate can be used by TLS servers and clients
+	ProfileDefault = "default"
+	// ProfileServer is for TLS server certificates
+	ProfileServer = "server"
+	// ProfileClient is for TLS client certificates
+	ProfileClient = "client"
+	// ProfileCodeSigning is for code signing certificates
+	ProfileCodeSigning = "code-signing"
+	// ProfileSMIME is for S/MIME (email signing and encryption) certificates
+	ProfileSMIME = "smime"
+	// ProfileOCSPSigning is for delegated OCSP signing certificates (see GenerateOCSPSigner)
+	ProfileOCSPSigning = "ocsp-signing"
+)
+
+// Key usages that can be used in a Profile
+const (
+	KeyUsageDigitalSignature  = "digital-signature"
+	KeyUsageContentCommitment = "content-commitment"
+	KeyUsageKeyEncipherment   = "key-encipherment"
+	KeyUsageDataEncipherment  = "data-encipherment"
+	KeyUsageKeyAgreement      = "key-agreement"
+)
+
+// Extended key usages that can be used in a Profile
+const (
+	ExtKeyUsageServerAuth      = "server-auth"
+	ExtKeyUsageClientAuth      = "client-auth"
+	ExtKeyUsageCodeSigning     = "code-signing"
+	ExtKeyUsageEmailProtection = "email-protection"
+	ExtKeyUsageOCSPSigning     = "ocsp-signing"
+)
+
+var keyUsages = map[string]x509.KeyUsage{
+	KeyUsageDigitalSignature:  x509.KeyUsageDigitalSignature,
+	KeyUsageContentCommitment: x509.KeyUsageContentCommitment,
+	KeyUsageKeyEncipherment:   x509.KeyUsageKeyEncipherment,
+	KeyUsageDataEncipherment:  x509.KeyUsageDataEncipherment,
+	KeyUsageKeyAgreement:      x509.KeyUsageKeyAgreement,
+}
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	ExtKeyUsageServerAuth:      x509.ExtKeyUsageServerAuth,
+	ExtKeyUsageClientAuth:      x509.ExtKeyUsageClientAuth,
+	ExtKeyUsageCodeSigning:     x509.ExtKeyUsageCodeSigning,
+	ExtKeyUsageEmailProtection: x509.ExtKeyUsageEmailProtection,
+	ExtKeyUsageOCSPSigning:     x509.ExtKeyUsageOCSPSigning,
+}
+
+// oidExtensionOCSPNoCheck is the object identifier of the id-pkix-ocsp-nocheck extension (RFC 6960 section 4.2.2.2.1)
+var oidExtensionOCSPNoCheck = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+
+// Profile sets the key usages and basic constraints of an end entity certificate. The key-encipherment and
+// data-encipherment key usages are only set for RSA keys, other keys can not be used for encryption.
+type Profile struct {
+	KeyUsage    []string `json:"key_usage"`
+	ExtKeyUsage []string `json:"ext_key_usage"`
+	// BasicConstraints adds the basic constraints extension marking the certificate as not being a CA
+	BasicConstraints bool `json:"basic_constraints"`
+	// OCSPNoCheck adds the OCSP no check extension, see GenerateOCSPSigner
+	OCSPNoCheck bool `json:"ocsp_no_check"`
+}
+
+// DefaultProfiles are the built-in profiles, they are used when a Request has no Profiles
+var DefaultProfiles = map[string]*Profile{
+	ProfileDefault: {
+		KeyUsage:         []string{KeyUsageDigitalSignature, KeyUsageKeyEncipherment},
+		ExtKeyUsage:      []string{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth},
+		BasicConstraints: true,
+	},
+	ProfileServer: {
+		KeyUsage:         []string{KeyUsageDigitalSignature, KeyUsageKeyEncipherment},
+		ExtKeyUsage:      []string{ExtKeyUsageServerAuth},
+		BasicConstraints: true,
+	},
+	ProfileClient: {
+		KeyUsage:         []string{KeyUsageDigitalSignature},
+		ExtKeyUsage:      []string{ExtKeyUsageClientAuth},
+		BasicConstraints: true,
+	},
+	ProfileCodeSigning: {
+		KeyUsage:         []string{KeyUsageDigitalSignature},
+		ExtKeyUsage:      []string{ExtKeyUsageCodeSigning},
+		BasicConstraints: true,
+	},
+	ProfileSMIME: {
+		KeyUsage:         []string{KeyUsageDigitalSignature, KeyUsageContentCommitment, KeyUsageKeyEncipherment},
+		ExtKeyUsage:      []string{ExtKeyUsageEmailProtection},
+		BasicConstraints: true,
+	},
+	ProfileOCSPSigning: {
+		KeyUsage:         []string{KeyUsageDigitalSignature},
+		ExtKeyUsage:      []string{ExtKeyUsageOCSPSigning},
+		BasicConstraints: true,
+		OCSPNoCheck:      true,
+	},
+}
+
+// LoadProfiles parses a JSON object mapping profile names to profiles and returns them together with the
+// DefaultProfiles. Profiles in the JSON object replace built-in profiles with the same name.
+func LoadProfiles(data []byte) (map[string]*Profile, error) {
+	var loaded map[string]*Profile
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]*Profile, len(DefaultProfiles)+len(loaded))
+	for name, profile := range DefaultProfiles {
+		profiles[name] = profile
+	}
+	for name, profile := range loaded {
+		if profile == nil {
+			return nil, fmt.Errorf("%w: %q", ErrorInvalidProfile, name)
+		}
+		if err := profile.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %q", err, name)
+		}
+		profiles[name] = profile
+	}
+
+	return profiles, nil
+}
+
+// Validate checks that all key usages and extended key usages of the profile are known
+func (p *Profile) Validate() error {
+	for _, usage := range p.KeyUsage {
+		if _, ok := keyUsages[usage]; !ok {
+			return ErrorInvalidKeyUsage
+		}
+	}
+	for _, usage := range p.ExtKeyUsage {
+		if _, ok := extKeyUsages[usage]; !ok {
+			return ErrorInvalidKeyUsage
+		}
+	}
+
+	return nil
+}
+
+// apply sets the key usages, basic constraints and extensions of the profile on the certificate for the public key
+func (p *Profile) apply(cert *x509.Certificate, pub crypto.PublicKey) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	_, isRSA := pub.(*rsa.PublicKey)
+	for _, usage := range p.KeyUsage {
+		if !isRSA && (usage == KeyUsageKeyEncipherment || usage == KeyUsageDataEncipherment) {
+			continue
+		}
+		cert.KeyUsage |= keyUsages[usage]
+	}
+	for _, usage := range p.ExtKeyUsage {
+		cert.ExtKeyUsage = append(cert.ExtKeyUsage, extKeyUsages[usage])
+	}
+
+	cert.BasicConstraintsValid = p.BasicConstraints
+	cert.IsCA = false
+
+	if p.OCSPNoCheck {
+		cert.ExtraExtensions = append(cert.ExtraExtensions, pkix.Extension{Id: oidExtensionOCSPNoCheck, Value: asn1.NullBytes})
+	}
+
+	return nil
+}
+
+// profile returns the profile of the request, the default profile is used when the request has none
+func (req *Request) profile() (*Profile, error) {
+	profiles := req.Profiles
+	if profiles == nil {
+		profiles = DefaultProfiles
+	}
+
+	name := req.Profile
+	if name == "" {
+		name = ProfileDefault
+	}
+
+	profile, ok := profiles[name]
+	if !ok || profile == nil {
+		return nil, ErrorInvalidProfile
+	}
+
+	return profile, nil
+}
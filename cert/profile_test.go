@@ -0,0 +1,169 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGenerateCertificate_Profile(t *testing.T) {
+	caCrt, caKey, err := GenerateCA(&Request{
+		CommonName: "ca.example.com",
+		NotBefore:  time.Now(),
+		NotAfter:   time.Now().Add(time.Hour),
+		KeyType:    KeyTypeECDSA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	custom, err := LoadProfiles([]byte(`{"signing": {"key_usage": ["digital-signature", "content-commitment"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		profile         string
+		profiles        map[string]*Profile
+		keyType         string
+		wantKeyUsage    x509.KeyUsage
+		wantExtKeyUsage []x509.ExtKeyUsage
+		wantBasic       bool
+		wantErr         error
+	}{
+		{
+			name:            "default-rsa",
+			keyType:         KeyTypeRSA,
+			wantKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			wantBasic:       true,
+		},
+		{
+			name:            "default-ecdsa",
+			keyType:         KeyTypeECDSA,
+			wantKeyUsage:    x509.KeyUsageDigitalSignature,
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			wantBasic:       true,
+		},
+		{
+			name:            "server",
+			profile:         ProfileServer,
+			keyType:         KeyTypeRSA,
+			wantKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			wantBasic:       true,
+		},
+		{
+			name:            "client",
+			profile:         ProfileClient,
+			keyType:         KeyTypeEd25519,
+			wantKeyUsage:    x509.KeyUsageDigitalSignature,
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			wantBasic:       true,
+		},
+		{
+			name:            "code-signing",
+			profile:         ProfileCodeSigning,
+			keyType:         KeyTypeECDSA,
+			wantKeyUsage:    x509.KeyUsageDigitalSignature,
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			wantBasic:       true,
+		},
+		{
+			name:            "smime",
+			profile:         ProfileSMIME,
+			keyType:         KeyTypeRSA,
+			wantKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment | x509.KeyUsageKeyEncipherment,
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+			wantBasic:       true,
+		},
+		{
+			name:         "custom",
+			profile:      "signing",
+			profiles:     custom,
+			keyType:      KeyTypeECDSA,
+			wantKeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+		},
+		{
+			name:    "unknown",
+			profile: "signing",
+			keyType: KeyTypeECDSA,
+			wantErr: ErrorInvalidProfile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crtPEM, _, err := GenerateCertificate(&Request{
+				CommonName: "example.com",
+				NotBefore:  time.Now(),
+				NotAfter:   time.Now().Add(time.Hour),
+				KeyType:    tt.keyType,
+				BitSize:    2048,
+				Profile:    tt.profile,
+				Profiles:   tt.profiles,
+			}, caCrt, caKey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			block, _ := pem.Decode(crtPEM)
+			crt, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if crt.KeyUsage != tt.wantKeyUsage {
+				t.Errorf("GenerateCertificate() KeyUsage = %v, want %v", crt.KeyUsage, tt.wantKeyUsage)
+			}
+			if !reflect.DeepEqual(crt.ExtKeyUsage, tt.wantExtKeyUsage) {
+				t.Errorf("GenerateCertificate() ExtKeyUsage = %v, want %v", crt.ExtKeyUsage, tt.wantExtKeyUsage)
+			}
+			if crt.BasicConstraintsValid != tt.wantBasic || crt.IsCA {
+				t.Errorf("GenerateCertificate() BasicConstraintsValid = %v, IsCA = %v", crt.BasicConstraintsValid, crt.IsCA)
+			}
+		})
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{name: "empty", data: `{}`},
+		{name: "custom", data: `{"tls": {"key_usage": ["digital-signature"], "ext_key_usage": ["server-auth", "client-auth"], "basic_constraints": true}}`},
+		{name: "override", data: `{"server": {"key_usage": ["digital-signature"], "ext_key_usage": ["server-auth"]}}`},
+		{name: "invalid-key-usage", data: `{"ca": {"key_usage": ["cert-sign"]}}`, wantErr: ErrorInvalidKeyUsage},
+		{name: "invalid-ext-key-usage", data: `{"tls": {"ext_key_usage": ["any"]}}`, wantErr: ErrorInvalidKeyUsage},
+		{name: "invalid-profile", data: `{"tls": null}`, wantErr: ErrorInvalidProfile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles, err := LoadProfiles([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadProfiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			for name := range DefaultProfiles {
+				if profiles[name] == nil {
+					t.Errorf("LoadProfiles() is missing profile %q", name)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadProfiles([]byte("not json")); err == nil {
+		t.Errorf("LoadProfiles() expected an error for invalid JSON")
+	}
+}
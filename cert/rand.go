@@ -5,18 +5,31 @@ import (
 	"math/big"
 )
 
-// GenerateRandomBigInt generates a random big.int (decimal) needed for certificate serial numbers.
+// serialNumberBits is the size of random serial numbers. RFC 5280 limits serial numbers to 20 octets and requires
+// them to be positive, 159 bits is the most that fits without a leading zero octet. The CA/Browser Forum requires at
+// least 64 bits of entropy.
+const serialNumberBits = 159
+
+// GenerateRandomBigInt generates a random positive big.int of 159 bits needed for certificate serial numbers.
 func GenerateRandomBigInt() (*big.Int, error) {
-	var n *big.Int
-	var err error
+	return randomSerialNumber(nil, serialNumberBits)
+}
 
-	max := *big.NewInt(99999999999)
+// randomSerialNumber generates a random positive serial number of bits random bits, preceded by the prefix
+func randomSerialNumber(prefix *big.Int, bits uint) (*big.Int, error) {
+	max := new(big.Int).Lsh(big.NewInt(1), bits)
 
-	n, err = rand.Int(rand.Reader, &max)
+	for {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return nil, err
+		if prefix != nil {
+			n.Or(n, new(big.Int).Lsh(prefix, bits))
+		}
+		if n.Sign() > 0 {
+			return n, nil
+		}
 	}
-
-	return n, nil
 }
@@ -18,11 +18,15 @@ func TestGenerateRandomBigInt(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GenerateRandomBigInt()
+			got, err := GenerateRandomBigInt()
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GenerateRandomBigInt() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			// RFC 5280 serial numbers are positive and at most 20 octets
+			if got.Sign() <= 0 || got.BitLen() > 159 {
+				t.Errorf("GenerateRandomBigInt() = %s, want a positive number of at most 159 bits", got)
+			}
 		})
 	}
 }
@@ -0,0 +1,62 @@
+package cert
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Revocation reason codes as defined in RFC 5280 section 5.3.1
+const (
+	ReasonUnspecified          = 0
+	ReasonKeyCompromise        = 1
+	ReasonCACompromise         = 2
+	ReasonAffiliationChanged   = 3
+	ReasonSuperseded           = 4
+	ReasonCessationOfOperation = 5
+	ReasonCertificateHold      = 6
+	ReasonRemoveFromCRL        = 8
+	ReasonPrivilegeWithdrawn   = 9
+	ReasonAACompromise         = 10
+)
+
+var revocationReasons = map[int]string{
+	ReasonUnspecified:          "unspecified",
+	ReasonKeyCompromise:        "keyCompromise",
+	ReasonCACompromise:         "cACompromise",
+	ReasonAffiliationChanged:   "affiliationChanged",
+	ReasonSuperseded:           "superseded",
+	ReasonCessationOfOperation: "cessationOfOperation",
+	ReasonCertificateHold:      "certificateHold",
+	ReasonRemoveFromCRL:        "removeFromCRL",
+	ReasonPrivilegeWithdrawn:   "privilegeWithdrawn",
+	ReasonAACompromise:         "aACompromise",
+}
+
+// ParseRevocationReason parses a revocation reason given either as RFC 5280 name (case insensitive, for example
+// keyCompromise) or as numeric reason code. The removeFromCRL reason is only meant for delta CRLs and can not be
+// used to revoke a certificate.
+func ParseRevocationReason(reason string) (int, error) {
+	if code, err := strconv.Atoi(reason); err == nil {
+		if _, ok := revocationReasons[code]; !ok || code == ReasonRemoveFromCRL {
+			return 0, ErrorInvalidRevocationReason
+		}
+		return code, nil
+	}
+
+	for code, name := range revocationReasons {
+		if strings.EqualFold(name, reason) && code != ReasonRemoveFromCRL {
+			return code, nil
+		}
+	}
+
+	return 0, ErrorInvalidRevocationReason
+}
+
+// RevocationReasonName returns the RFC 5280 name of a revocation reason code
+func RevocationReasonName(reason int) string {
+	if name, ok := revocationReasons[reason]; ok {
+		return name
+	}
+
+	return strconv.Itoa(reason)
+}
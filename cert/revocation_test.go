@@ -0,0 +1,49 @@
+package cert
+
+import "testing"
+
+func TestParseRevocationReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  string
+		want    int
+		wantErr bool
+	}{
+		{name: "name", reason: "keyCompromise", want: ReasonKeyCompromise, wantErr: false},
+		{name: "name_case_insensitive", reason: "SUPERSEDED", want: ReasonSuperseded, wantErr: false},
+		{name: "code", reason: "5", want: ReasonCessationOfOperation, wantErr: false},
+		{name: "unused_code", reason: "7", want: 0, wantErr: true},
+		{name: "remove_from_crl", reason: "removeFromCRL", want: 0, wantErr: true},
+		{name: "unknown", reason: "lost", want: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRevocationReason(tt.reason)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRevocationReason() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseRevocationReason() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevocationReasonName(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason int
+		want   string
+	}{
+		{name: "known", reason: ReasonKeyCompromise, want: "keyCompromise"},
+		{name: "unknown", reason: 7, want: "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RevocationReasonName(tt.reason); got != tt.want {
+				t.Errorf("RevocationReasonName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package cert
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// Prefixes of the typed subject alt names. Names without a prefix are IP addresses when they parse as an IPv4 or
+// IPv6 address and DNS names otherwise.
+const (
+	SANPrefixDNS   = "dns:"
+	SANPrefixIP    = "ip:"
+	SANPrefixEmail = "email:"
+	SANPrefixURI   = "uri:"
+)
+
+// SubjectAltNames holds the subject alt names of a certificate by type
+type SubjectAltNames struct {
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	URIs           []*url.URL
+}
+
+// ParseSubjectAltNames parses and validates the subject alt names. Every name is either a DNS name (optionally with
+// a leading wildcard label), an IPv4 or IPv6 address, an email address prefixed with "email:" or a URI prefixed with
+// "uri:", for example uri:spiffe://example.com/service. The "dns:" and "ip:" prefixes can be used to be explicit.
+func ParseSubjectAltNames(names []string) (*SubjectAltNames, error) {
+	sans := &SubjectAltNames{}
+
+	for _, name := range names {
+		switch {
+		case strings.HasPrefix(name, SANPrefixEmail):
+			address := strings.TrimPrefix(name, SANPrefixEmail)
+			parsed, err := mail.ParseAddress(address)
+			if err != nil || parsed.Address != address {
+				return nil, fmt.Errorf("%w: %q", ErrorInvalidSubjectAltName, name)
+			}
+			sans.EmailAddresses = append(sans.EmailAddresses, address)
+		case strings.HasPrefix(name, SANPrefixURI):
+			uri, err := url.Parse(strings.TrimPrefix(name, SANPrefixURI))
+			if err != nil || uri.Scheme == "" || (uri.Host == "" && uri.Opaque == "" && uri.Path == "") {
+				return nil, fmt.Errorf("%w: %q", ErrorInvalidSubjectAltName, name)
+			}
+			sans.URIs = append(sans.URIs, uri)
+		case strings.HasPrefix(name, SANPrefixIP):
+			ip := net.ParseIP(strings.TrimPrefix(name, SANPrefixIP))
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrorInvalidSubjectAltName, name)
+			}
+			sans.IPAddresses = append(sans.IPAddresses, ip)
+		case strings.HasPrefix(name, SANPrefixDNS):
+			if !isValidDNSName(strings.TrimPrefix(name, SANPrefixDNS)) {
+				return nil, fmt.Errorf("%w: %q", ErrorInvalidSubjectAltName, name)
+			}
+			sans.DNSNames = append(sans.DNSNames, strings.TrimPrefix(name, SANPrefixDNS))
+		default:
+			if ip := net.ParseIP(name); ip != nil {
+				sans.IPAddresses = append(sans.IPAddresses, ip)
+				continue
+			}
+
+			if !isValidDNSName(name) {
+				return nil, fmt.Errorf("%w: %q", ErrorInvalidSubjectAltName, name)
+			}
+			sans.DNSNames = append(sans.DNSNames, name)
+		}
+	}
+
+	return sans, nil
+}
+
+// Strings formats the subject alt names in the notation accepted by ParseSubjectAltNames, so they can be parsed
+// again to the same names. The result is nil when there are no names.
+func (sans *SubjectAltNames) Strings() []string {
+	var names []string
+	names = append(names, sans.DNSNames...)
+	for _, ip := range sans.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, email := range sans.EmailAddresses {
+		names = append(names, SANPrefixEmail+email)
+	}
+	for _, uri := range sans.URIs {
+		names = append(names, SANPrefixURI+uri.String())
+	}
+	return names
+}
+
+// isValidDNSName checks if the name consists of valid DNS labels. Only the first label can be a wildcard.
+func isValidDNSName(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+
+	for i, label := range strings.Split(name, ".") {
+		if label == "*" && i == 0 {
+			continue
+		}
+
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,176 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSubjectAltNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		names     []string
+		wantDNS   []string
+		wantIPs   []string
+		wantEmail []string
+		wantURIs  []string
+		wantErr   bool
+	}{
+		{name: "empty", names: nil},
+		{name: "dns", names: []string{"example.com", "*.example.com", "_acme.example.com"}, wantDNS: []string{"example.com", "*.example.com", "_acme.example.com"}},
+		{name: "dns-prefix", names: []string{"dns:example.com"}, wantDNS: []string{"example.com"}},
+		{name: "ipv4", names: []string{"192.0.2.1"}, wantIPs: []string{"192.0.2.1"}},
+		{name: "ipv6", names: []string{"2001:db8::1"}, wantIPs: []string{"2001:db8::1"}},
+		{name: "ip-prefix", names: []string{"ip:10.0.0.1"}, wantIPs: []string{"10.0.0.1"}},
+		{name: "email", names: []string{"email:admin@example.com"}, wantEmail: []string{"admin@example.com"}},
+		{name: "uri", names: []string{"uri:spiffe://example.com/service"}, wantURIs: []string{"spiffe://example.com/service"}},
+		{name: "urn", names: []string{"uri:urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66"}, wantURIs: []string{"urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66"}},
+		{
+			name:      "mixed",
+			names:     []string{"example.com", "127.0.0.1", "email:admin@example.com", "uri:https://example.com"},
+			wantDNS:   []string{"example.com"},
+			wantIPs:   []string{"127.0.0.1"},
+			wantEmail: []string{"admin@example.com"},
+			wantURIs:  []string{"https://example.com"},
+		},
+		{name: "empty-name", names: []string{""}, wantErr: true},
+		{name: "invalid-dns", names: []string{"exa mple.com"}, wantErr: true},
+		{name: "invalid-wildcard", names: []string{"www.*.example.com"}, wantErr: true},
+		{name: "invalid-label", names: []string{"-example.com"}, wantErr: true},
+		{name: "invalid-ip", names: []string{"ip:256.0.0.1"}, wantErr: true},
+		{name: "invalid-email", names: []string{"email:admin"}, wantErr: true},
+		{name: "invalid-email-name", names: []string{"email:Admin <admin@example.com>"}, wantErr: true},
+		{name: "invalid-uri-scheme", names: []string{"uri://example.com"}, wantErr: true},
+		{name: "invalid-uri-empty", names: []string{"uri:https:"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSubjectAltNames(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSubjectAltNames() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrorInvalidSubjectAltName) {
+					t.Errorf("ParseSubjectAltNames() error = %v, want %v", err, ErrorInvalidSubjectAltName)
+				}
+				return
+			}
+
+			var ips, uris []string
+			for _, ip := range got.IPAddresses {
+				ips = append(ips, ip.String())
+			}
+			for _, uri := range got.URIs {
+				uris = append(uris, uri.String())
+			}
+
+			if !reflect.DeepEqual(got.DNSNames, tt.wantDNS) {
+				t.Errorf("ParseSubjectAltNames() DNSNames = %v, want %v", got.DNSNames, tt.wantDNS)
+			}
+			if !reflect.DeepEqual(ips, tt.wantIPs) {
+				t.Errorf("ParseSubjectAltNames() IPAddresses = %v, want %v", ips, tt.wantIPs)
+			}
+			if !reflect.DeepEqual(got.EmailAddresses, tt.wantEmail) {
+				t.Errorf("ParseSubjectAltNames() EmailAddresses = %v, want %v", got.EmailAddresses, tt.wantEmail)
+			}
+			if !reflect.DeepEqual(uris, tt.wantURIs) {
+				t.Errorf("ParseSubjectAltNames() URIs = %v, want %v", uris, tt.wantURIs)
+			}
+		})
+	}
+}
+
+func TestSubjectAltNames_Strings(t *testing.T) {
+	names := []string{"example.com", "192.0.2.1", "2001:db8::1", "email:admin@example.com", "uri:spiffe://example.com/service"}
+
+	sans, err := ParseSubjectAltNames(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sans.Strings(); !reflect.DeepEqual(got, names) {
+		t.Errorf("Strings() = %v, want %v", got, names)
+	}
+
+	if got := (&SubjectAltNames{}).Strings(); got != nil {
+		t.Errorf("Strings() = %v, want nil", got)
+	}
+}
+
+func TestReadCSR_SubjectAltNames(t *testing.T) {
+	names := []string{"example.com", "192.0.2.1", "email:admin@example.com", "uri:spiffe://example.com/service"}
+	sans, err := ParseSubjectAltNames(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	priv, err := generateKey(&Request{KeyType: KeyTypeECDSA, Curve: CurveP256})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: "example.com"},
+		DNSNames:       sans.DNSNames,
+		IPAddresses:    sans.IPAddresses,
+		EmailAddresses: sans.EmailAddresses,
+		URIs:           sans.URIs,
+	}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := ReadCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(request.SubjectAltNames, names) {
+		t.Errorf("ReadCSR() SubjectAltNames = %v, want %v", request.SubjectAltNames, names)
+	}
+}
+
+func TestGenerateCertificate_SubjectAltNames(t *testing.T) {
+	caCrt, caKey, err := GenerateCA(&Request{
+		CommonName: "ca.example.com",
+		NotBefore:  time.Now(),
+		NotAfter:   time.Now().Add(time.Hour),
+		KeyType:    KeyTypeECDSA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crtPEM, _, err := GenerateCertificate(&Request{
+		CommonName:      "example.com",
+		NotBefore:       time.Now(),
+		NotAfter:        time.Now().Add(time.Hour),
+		KeyType:         KeyTypeECDSA,
+		SubjectAltNames: []string{"example.com", "2001:db8::1", "email:admin@example.com", "uri:spiffe://example.com/service"},
+	}, caCrt, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(crtPEM)
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(crt.DNSNames) != 1 || crt.DNSNames[0] != "example.com" {
+		t.Errorf("DNSNames = %v", crt.DNSNames)
+	}
+	if len(crt.IPAddresses) != 1 || crt.IPAddresses[0].String() != "2001:db8::1" {
+		t.Errorf("IPAddresses = %v", crt.IPAddresses)
+	}
+	if len(crt.EmailAddresses) != 1 || crt.EmailAddresses[0] != "admin@example.com" {
+		t.Errorf("EmailAddresses = %v", crt.EmailAddresses)
+	}
+	if len(crt.URIs) != 1 || crt.URIs[0].String() != "spiffe://example.com/service" {
+		t.Errorf("URIs = %v", crt.URIs)
+	}
+}
@@ -0,0 +1,70 @@
+package cert
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// serialNumberOctets is the maximum length of a serial number in RFC 5280
+	serialNumberOctets = 20
+	// minSerialEntropy is the number of random bits a prefixed serial number has at least
+	minSerialEntropy = 64
+)
+
+// ParseSerialNumber parses a serial number given in decimal or in hexadecimal with a 0x prefix, for example 2748 or
+// 0xabc. Serial numbers are positive, ErrorInvalidSerialNumber is returned for anything else.
+func ParseSerialNumber(serial string) (*big.Int, error) {
+	digits, base := serial, 10
+	if strings.HasPrefix(serial, "0x") || strings.HasPrefix(serial, "0X") {
+		digits, base = serial[2:], 16
+	}
+
+	serialNumber, ok := new(big.Int).SetString(digits, base)
+	if !ok || strings.ContainsAny(digits, "+-") || serialNumber.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q, use a positive decimal or 0x prefixed hexadecimal number", ErrorInvalidSerialNumber, serial)
+	}
+	return serialNumber, nil
+}
+
+// SerialGenerator generates serial numbers for certificates. Generators do not know which serial numbers are taken,
+// a serial number that turns out to be in use is replaced by the next one.
+type SerialGenerator interface {
+	Next() (*big.Int, error)
+}
+
+// RandomSerialGenerator generates random 159 bit serial numbers (see GenerateRandomBigInt)
+type RandomSerialGenerator struct{}
+
+// Next returns a random serial number
+func (RandomSerialGenerator) Next() (*big.Int, error) {
+	return GenerateRandomBigInt()
+}
+
+// PrefixedSerialGenerator generates serial numbers that start with a fixed prefix followed by random bits, for
+// example to tell the CA or environment that issued a certificate from its serial number
+type PrefixedSerialGenerator struct {
+	prefix *big.Int
+	bits   uint
+}
+
+// NewPrefixedSerialGenerator creates a PrefixedSerialGenerator for the prefix. The serial numbers are 20 octets, the
+// first ones hold the prefix and at least 64 bits are left random. As the serial numbers have to be positive the
+// prefix has to start with an octet from 0x01 to 0x7f. ErrorInvalidSerialNumber is returned for an invalid prefix.
+func NewPrefixedSerialGenerator(prefix []byte) (*PrefixedSerialGenerator, error) {
+	bits := 8 * (serialNumberOctets - len(prefix))
+	if len(prefix) == 0 || prefix[0] == 0 || prefix[0] >= 0x80 || bits < minSerialEntropy {
+		return nil, ErrorInvalidSerialNumber
+	}
+
+	return &PrefixedSerialGenerator{
+		prefix: new(big.Int).SetBytes(prefix),
+		bits:   uint(bits),
+	}, nil
+}
+
+// Next returns the prefix followed by random bits
+func (g *PrefixedSerialGenerator) Next() (*big.Int, error) {
+	return randomSerialNumber(g.prefix, g.bits)
+}
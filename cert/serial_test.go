@@ -0,0 +1,87 @@
+package cert
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParseSerialNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		serial  string
+		want    int64
+		wantErr bool
+	}{
+		{name: "decimal", serial: "4242", want: 4242},
+		{name: "leading_zero", serial: "010", want: 10},
+		{name: "hex", serial: "0x0A1B", want: 0x0a1b},
+		{name: "hex_upper_prefix", serial: "0Xff", want: 0xff},
+		{name: "hex_without_prefix", serial: "0A1B", wantErr: true},
+		{name: "underscore", serial: "1_000", wantErr: true},
+		{name: "octal_prefix", serial: "0o10", wantErr: true},
+		{name: "zero", serial: "0", wantErr: true},
+		{name: "hex_zero", serial: "0x0", wantErr: true},
+		{name: "negative", serial: "-1", wantErr: true},
+		{name: "hex_negative", serial: "0x-1", wantErr: true},
+		{name: "plus", serial: "+1", wantErr: true},
+		{name: "prefix_only", serial: "0x", wantErr: true},
+		{name: "empty", serial: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSerialNumber(tt.serial)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSerialNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrorInvalidSerialNumber) {
+					t.Errorf("ParseSerialNumber() error = %v, want %v", err, ErrorInvalidSerialNumber)
+				}
+				return
+			}
+			if got.Cmp(big.NewInt(tt.want)) != 0 {
+				t.Errorf("ParseSerialNumber() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPrefixedSerialGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  []byte
+		wantErr error
+	}{
+		{name: "one_octet", prefix: []byte{0x2a}},
+		{name: "two_octets", prefix: []byte{0x7f, 0xff}},
+		{name: "max_length", prefix: bytes.Repeat([]byte{0x01}, 12)},
+		{name: "too_long", prefix: bytes.Repeat([]byte{0x01}, 13), wantErr: ErrorInvalidSerialNumber},
+		{name: "high_bit", prefix: []byte{0x80}, wantErr: ErrorInvalidSerialNumber},
+		{name: "leading_zero", prefix: []byte{0x00, 0x01}, wantErr: ErrorInvalidSerialNumber},
+		{name: "empty", prefix: nil, wantErr: ErrorInvalidSerialNumber},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewPrefixedSerialGenerator(tt.prefix)
+			if err != tt.wantErr {
+				t.Fatalf("NewPrefixedSerialGenerator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got, err := g.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Sign() <= 0 || len(got.Bytes()) != 20 {
+				t.Errorf("Next() = %x, want a positive number of 20 octets", got.Bytes())
+			}
+			if !bytes.HasPrefix(got.Bytes(), tt.prefix) {
+				t.Errorf("Next() = %x, want prefix %x", got.Bytes(), tt.prefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"time"
+)
+
+// The functions in this file sign with a crypto.Signer instead of a PEM encoded CA key, so the CA key can be kept in
+// an HSM, a cloud KMS or any other key store that does not hand out the private key. The CA certificate is still
+// given PEM encoded and its public key has to match the public key of the signer.
+
+// GenerateCertificateWithSigner works like GenerateCertificate, the certificate is signed by the caSigner for the
+// CA certificate caCrt
+func GenerateCertificateWithSigner(req *Request, caCrt []byte, caSigner crypto.Signer) ([]byte, []byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	ca, err := parseSigner(caCrt, caSigner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return generateCertificate(req, ca, caSigner)
+}
+
+// SignCSRWithSigner works like SignCSR, the certificate is signed by the caSigner for the CA certificate caCrt
+func SignCSRWithSigner(req *Request, csr []byte, caCrt []byte, caSigner crypto.Signer) ([]byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	ca, err := parseSigner(caCrt, caSigner)
+	if err != nil {
+		return nil, err
+	}
+
+	return signCSR(req, csr, ca, caSigner)
+}
+
+// SignPublicKeyWithSigner works like SignPublicKey, the certificate is signed by the caSigner for the CA certificate
+// caCrt
+func SignPublicKeyWithSigner(req *Request, pub crypto.PublicKey, caCrt []byte, caSigner crypto.Signer) ([]byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	ca, err := parseSigner(caCrt, caSigner)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueCertificate(req, pub, ca, caSigner)
+}
+
+// GenerateIntermediateCAWithSigner works like GenerateIntermediateCA, the intermediate CA certificate is signed by
+// the parentSigner for the parent CA certificate parentCrt
+func GenerateIntermediateCAWithSigner(req *Request, parentCrt []byte, parentSigner crypto.Signer) ([]byte, []byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	parent, err := parseSigner(parentCrt, parentSigner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return generateIntermediateCA(req, parent, parentSigner)
+}
+
+// GenerateOCSPSignerWithSigner works like GenerateOCSPSigner, the OCSP signing certificate is signed by the caSigner
+// for the CA certificate caCrt
+func GenerateOCSPSignerWithSigner(req *Request, caCrt []byte, caSigner crypto.Signer) ([]byte, []byte, error) {
+	req.Profile = ProfileOCSPSigning
+	req.Profiles = nil
+
+	return GenerateCertificateWithSigner(req, caCrt, caSigner)
+}
+
+// GenerateCRLWithSigner works like GenerateCRL, the CRL is signed by the caSigner for the CA certificate caCrt
+func GenerateCRLWithSigner(caCrt []byte, caSigner crypto.Signer, revoked []RevokedEntry, number *big.Int, nextUpdate time.Time) ([]byte, error) {
+	ca, err := parseSigner(caCrt, caSigner)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateCRL(ca, caSigner, revoked, number, nextUpdate)
+}
+
+// CheckSigner checks that the signer holds the private key of the first certificate of the PEM encoded crt, it
+// returns ErrorSignerMismatch when it does not
+func CheckSigner(crt []byte, signer crypto.Signer) error {
+	_, err := parseSigner(crt, signer)
+	return err
+}
+
+// parseSigner parses the first certificate of the PEM encoded crt and checks that the signer holds its private key
+func parseSigner(crt []byte, signer crypto.Signer) (*x509.Certificate, error) {
+	block, _ := pem.Decode(crt)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, ErrorInvalidCertificate
+	}
+
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if signer == nil {
+		return nil, ErrorSignerMismatch
+	}
+	if !publicKeysEqual(signer.Public(), parsed.PublicKey) {
+		return nil, ErrorSignerMismatch
+	}
+
+	return parsed, nil
+}
+
+// publicKeysEqual reports whether both public keys are the same key
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	pub, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(b)
+}
@@ -0,0 +1,147 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// opaqueSigner hides the private key behind the crypto.Signer interface, like an HSM backed signer does
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+func TestWithSigner(t *testing.T) {
+	for _, keyType := range []string{KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519} {
+		t.Run(keyType, func(t *testing.T) {
+			caCrt, caKey, err := GenerateCA(&Request{
+				CommonName: "ca.example.com",
+				NotBefore:  time.Now(),
+				NotAfter:   time.Now().Add(time.Hour),
+				KeyType:    keyType,
+				BitSize:    2048,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ca, caPriv, err := parseKeyPair(caCrt, caKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signer := opaqueSigner{caPriv}
+
+			crt, _, err := GenerateCertificateWithSigner(&Request{
+				CommonName: "www.example.com",
+				NotBefore:  time.Now(),
+				NotAfter:   time.Now().Add(time.Hour),
+				KeyType:    KeyTypeECDSA,
+			}, caCrt, signer)
+			if err != nil {
+				t.Fatalf("GenerateCertificateWithSigner() error = %v", err)
+			}
+			checkSignedBy(t, crt, ca)
+
+			csr, _, err := GenerateCSR(&Request{CommonName: "csr.example.com", KeyType: KeyTypeECDSA})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := ReadCSR(csr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.NotBefore, req.NotAfter = time.Now(), time.Now().Add(time.Hour)
+			crt, err = SignCSRWithSigner(req, csr, caCrt, signer)
+			if err != nil {
+				t.Fatalf("SignCSRWithSigner() error = %v", err)
+			}
+			checkSignedBy(t, crt, ca)
+
+			crt, err = SignPublicKeyWithSigner(req, caPriv.Public(), caCrt, signer)
+			if err != nil {
+				t.Fatalf("SignPublicKeyWithSigner() error = %v", err)
+			}
+			checkSignedBy(t, crt, ca)
+
+			crt, _, err = GenerateIntermediateCAWithSigner(&Request{
+				CommonName: "intermediate.example.com",
+				NotBefore:  time.Now(),
+				NotAfter:   time.Now().Add(time.Hour),
+				KeyType:    KeyTypeECDSA,
+			}, caCrt, signer)
+			if err != nil {
+				t.Fatalf("GenerateIntermediateCAWithSigner() error = %v", err)
+			}
+			checkSignedBy(t, crt, ca)
+
+			crt, _, err = GenerateOCSPSignerWithSigner(&Request{
+				CommonName: "ocsp.example.com",
+				NotBefore:  time.Now(),
+				NotAfter:   time.Now().Add(time.Hour),
+				KeyType:    KeyTypeECDSA,
+			}, caCrt, signer)
+			if err != nil {
+				t.Fatalf("GenerateOCSPSignerWithSigner() error = %v", err)
+			}
+			checkSignedBy(t, crt, ca)
+
+			crl, err := GenerateCRLWithSigner(caCrt, signer, nil, big.NewInt(1), time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatalf("GenerateCRLWithSigner() error = %v", err)
+			}
+			block, _ := pem.Decode(crl)
+			list, err := x509.ParseCRL(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ca.CheckCRLSignature(list); err != nil {
+				t.Errorf("GenerateCRLWithSigner() signature error = %v", err)
+			}
+		})
+	}
+}
+
+func TestWithSigner_Mismatch(t *testing.T) {
+	caCrt, _, err := GenerateCA(&Request{
+		CommonName: "ca.example.com",
+		NotBefore:  time.Now(),
+		NotAfter:   time.Now().Add(time.Hour),
+		KeyType:    KeyTypeECDSA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := generateKey(&Request{KeyType: KeyTypeECDSA, Curve: CurveP256})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &Request{CommonName: "www.example.com", NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour), KeyType: KeyTypeECDSA}
+	if _, _, err := GenerateCertificateWithSigner(req, caCrt, opaqueSigner{other}); err != ErrorSignerMismatch {
+		t.Errorf("GenerateCertificateWithSigner() error = %v, want %v", err, ErrorSignerMismatch)
+	}
+	if _, _, err := GenerateCertificateWithSigner(req, caCrt, nil); err != ErrorSignerMismatch {
+		t.Errorf("GenerateCertificateWithSigner() error = %v, want %v", err, ErrorSignerMismatch)
+	}
+	if _, _, err := GenerateCertificateWithSigner(req, []byte("not a certificate"), opaqueSigner{other}); err != ErrorInvalidCertificate {
+		t.Errorf("GenerateCertificateWithSigner() error = %v, want %v", err, ErrorInvalidCertificate)
+	}
+}
+
+func checkSignedBy(t *testing.T, crt []byte, ca *x509.Certificate) {
+	t.Helper()
+
+	block, _ := pem.Decode(crt)
+	if block == nil {
+		t.Fatalf("no certificate returned")
+	}
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("certificate is not signed by the ca: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// Names of the checks done by Verify
+const (
+	// CheckChain checks that the certificate chains up to one of the roots through the intermediates
+	CheckChain = "chain"
+	// CheckValidity checks that the current time is within the validity of the certificate and the rest of its chain
+	CheckValidity = "validity"
+	// CheckHost checks that the certificate is valid for the host
+	CheckHost = "host"
+	// CheckUsage checks that the certificate can be used for a key usage or extended key usage
+	CheckUsage = "usage"
+)
+
+// VerifyOptions are the options of Verify
+type VerifyOptions struct {
+	// Host is the DNS name or IP address the certificate has to be valid for, it is not checked when empty
+	Host string
+	// Usages are the names of the key usages (see Profile) and extended key usages the certificate must have, "any"
+	// can be used for the any extended key usage
+	Usages []string
+	// CurrentTime is the time to verify at, the current time is used when it is zero
+	CurrentTime time.Time
+}
+
+// VerifyReport is the result of Verify, it holds every check that was done
+type VerifyReport struct {
+	// Valid is true when all checks passed
+	Valid bool `json:"valid"`
+	// Chain holds the subjects of the verified chain, starting with the leaf and ending with the root
+	Chain  []string      `json:"chain,omitempty"`
+	Checks []VerifyCheck `json:"checks"`
+}
+
+// VerifyCheck is a check done by Verify
+type VerifyCheck struct {
+	Name string `json:"name"`
+	// Usage is the key usage or extended key usage of a CheckUsage
+	Usage  string `json:"usage,omitempty"`
+	Passed bool   `json:"passed"`
+	// Message tells why the check failed
+	Message string `json:"message,omitempty"`
+}
+
+// Verify verifies the first certificate of the PEM encoded leaf against the PEM encoded roots, using the
+// intermediates and any other certificates in leaf to build the chain. Unlike x509.Certificate.Verify it does not
+// stop at the first problem: the report holds the result of every check, so an expired certificate that is also
+// issued for another host fails both the validity and the host check.
+//
+// An error is only returned when the certificates can not be read or an unknown usage is given, a certificate that
+// fails verification gives a report that is not Valid.
+func Verify(leaf []byte, intermediates []byte, roots []byte, opts VerifyOptions) (*VerifyReport, error) {
+	leafCerts, err := parseCertificates(leaf)
+	if err != nil {
+		return nil, err
+	}
+	intermediateCerts, err := parseCertificates(intermediates)
+	if err != nil {
+		return nil, err
+	}
+	rootCerts, err := parseCertificates(roots)
+	if err != nil {
+		return nil, err
+	}
+	if len(leafCerts) == 0 || len(rootCerts) == 0 {
+		return nil, ErrorEmptyChain
+	}
+
+	usages, err := parseUsages(opts.Usages)
+	if err != nil {
+		return nil, err
+	}
+
+	crt := leafCerts[0]
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	verifyOpts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		Roots:         x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		CurrentTime:   now,
+	}
+	for _, intermediate := range append(leafCerts[1:], intermediateCerts...) {
+		verifyOpts.Intermediates.AddCert(intermediate)
+	}
+	for _, root := range rootCerts {
+		verifyOpts.Roots.AddCert(root)
+	}
+
+	// The chain is built at a time the leaf is valid, so an expired leaf fails the validity check and not the chain
+	if now.Before(crt.NotBefore) {
+		verifyOpts.CurrentTime = crt.NotBefore
+	} else if now.After(crt.NotAfter) {
+		verifyOpts.CurrentTime = crt.NotAfter
+	}
+
+	report := &VerifyReport{}
+	chain := []*x509.Certificate{crt}
+	if chains, err := crt.Verify(verifyOpts); err != nil {
+		report.add(CheckChain, err)
+	} else {
+		chain = chains[0]
+		report.add(CheckChain, nil)
+		for _, c := range chain {
+			report.Chain = append(report.Chain, c.Subject.String())
+		}
+	}
+
+	var validityErr error
+	for _, c := range chain {
+		if now.Before(c.NotBefore) {
+			validityErr = fmt.Errorf("certificate %s is not valid before %s", c.Subject, c.NotBefore.Format(time.RFC3339))
+		} else if now.After(c.NotAfter) {
+			validityErr = fmt.Errorf("certificate %s expired at %s", c.Subject, c.NotAfter.Format(time.RFC3339))
+		}
+		if validityErr != nil {
+			break
+		}
+	}
+	report.add(CheckValidity, validityErr)
+
+	if opts.Host != "" {
+		report.add(CheckHost, crt.VerifyHostname(opts.Host))
+	}
+
+	for _, usage := range usages {
+		var usageErr error
+		if usage.ext {
+			usageErr = checkExtKeyUsage(crt, usage, verifyOpts, len(report.Chain) > 0)
+		} else if crt.KeyUsage != 0 && crt.KeyUsage&usage.keyUsage == 0 {
+			usageErr = fmt.Errorf("certificate does not have the %s key usage", usage.name)
+		}
+		report.add(CheckUsage, usageErr)
+		report.Checks[len(report.Checks)-1].Usage = usage.name
+	}
+
+	report.Valid = true
+	for _, check := range report.Checks {
+		report.Valid = report.Valid && check.Passed
+	}
+
+	return report, nil
+}
+
+// add adds the result of a check to the report, the check passed when err is nil
+func (r *VerifyReport) add(name string, err error) {
+	check := VerifyCheck{Name: name, Passed: err == nil}
+	if err != nil {
+		check.Message = err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// checkExtKeyUsage checks that the certificate has the extended key usage, when the chain could be built it also
+// checks that the usage is allowed by the issuing CAs
+func checkExtKeyUsage(crt *x509.Certificate, usage verifyUsage, opts x509.VerifyOptions, checkChain bool) error {
+	found := len(crt.ExtKeyUsage) == 0 && len(crt.UnknownExtKeyUsage) == 0
+	for _, u := range crt.ExtKeyUsage {
+		found = found || u == usage.extKeyUsage || u == x509.ExtKeyUsageAny
+	}
+	if !found {
+		return fmt.Errorf("certificate does not have the %s extended key usage", usage.name)
+	}
+
+	if checkChain {
+		opts.KeyUsages = []x509.ExtKeyUsage{usage.extKeyUsage}
+		if _, err := crt.Verify(opts); err != nil {
+			return fmt.Errorf("the %s extended key usage is not allowed by the chain: %w", usage.name, err)
+		}
+	}
+
+	return nil
+}
+
+// verifyUsage is a key usage or extended key usage to check
+type verifyUsage struct {
+	name        string
+	ext         bool
+	keyUsage    x509.KeyUsage
+	extKeyUsage x509.ExtKeyUsage
+}
+
+// parseUsages looks up the key usages and extended key usages by their names
+func parseUsages(names []string) ([]verifyUsage, error) {
+	var usages []verifyUsage
+
+	for _, name := range names {
+		if usage, ok := extKeyUsages[name]; ok {
+			usages = append(usages, verifyUsage{name: name, ext: true, extKeyUsage: usage})
+			continue
+		}
+		if name == "any" {
+			usages = append(usages, verifyUsage{name: name, ext: true, extKeyUsage: x509.ExtKeyUsageAny})
+			continue
+		}
+
+		found := false
+		for _, usage := range keyUsageNames {
+			if usage.name == name {
+				usages = append(usages, verifyUsage{name: name, keyUsage: usage.usage})
+				found = true
+			}
+		}
+		if !found {
+			return nil, ErrorInvalidKeyUsage
+		}
+	}
+
+	return usages, nil
+}
+
+// parseCertificates parses all PEM encoded certificates in data
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, ErrorInvalidCertificate
+		}
+		certs = append(certs, crt)
+	}
+
+	return certs, nil
+}
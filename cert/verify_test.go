@@ -0,0 +1,154 @@
+package cert
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	caCrt, caKey, err := GenerateCA(&Request{CommonName: "ca.test.local", NotBefore: now.Add(-time.Hour), NotAfter: now.AddDate(1, 0, 0), KeyType: KeyTypeECDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediateCrt, intermediateKey, err := GenerateIntermediateCA(&Request{CommonName: "intermediate.test.local", NotBefore: now.Add(-time.Hour), NotAfter: now.AddDate(0, 6, 0), KeyType: KeyTypeECDSA}, caCrt, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCACrt, _, err := GenerateCA(&Request{CommonName: "other.test.local", NotBefore: now.Add(-time.Hour), NotAfter: now.AddDate(1, 0, 0), KeyType: KeyTypeECDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf := func(caCrt, caKey []byte, notAfter time.Time) []byte {
+		crt, _, err := GenerateCertificate(&Request{
+			CommonName:      "www.test.local",
+			SubjectAltNames: []string{"www.test.local"},
+			SerialNumber:    big.NewInt(now.UnixNano()),
+			NotBefore:       now.Add(-time.Hour),
+			NotAfter:        notAfter,
+			KeyType:         KeyTypeECDSA,
+		}, caCrt, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crt
+	}
+	validCrt := leaf(caCrt, caKey, now.AddDate(0, 1, 0))
+	expiredCrt := leaf(caCrt, caKey, now.Add(-time.Minute))
+	intermediateLeafCrt := leaf(intermediateCrt, intermediateKey, now.AddDate(0, 1, 0))
+
+	type args struct {
+		leaf          []byte
+		intermediates []byte
+		roots         []byte
+		opts          VerifyOptions
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantValid  bool
+		wantFailed []string
+		wantChain  int
+		wantErr    error
+	}{
+		{
+			name:      "valid",
+			args:      args{leaf: validCrt, roots: caCrt, opts: VerifyOptions{Host: "www.test.local", Usages: []string{ExtKeyUsageServerAuth, KeyUsageDigitalSignature}}},
+			wantValid: true,
+			wantChain: 2,
+		},
+		{
+			name:      "valid_intermediate",
+			args:      args{leaf: intermediateLeafCrt, intermediates: intermediateCrt, roots: caCrt},
+			wantValid: true,
+			wantChain: 3,
+		},
+		{
+			name:      "valid_fullchain",
+			args:      args{leaf: append(append([]byte{}, intermediateLeafCrt...), intermediateCrt...), roots: caCrt},
+			wantValid: true,
+			wantChain: 3,
+		},
+		{
+			name:       "missing_intermediate",
+			args:       args{leaf: intermediateLeafCrt, roots: caCrt},
+			wantFailed: []string{CheckChain},
+		},
+		{
+			name:       "untrusted",
+			args:       args{leaf: validCrt, roots: otherCACrt},
+			wantFailed: []string{CheckChain},
+		},
+		{
+			name:       "expired_and_wrong_host",
+			args:       args{leaf: expiredCrt, roots: caCrt, opts: VerifyOptions{Host: "mail.test.local"}},
+			wantFailed: []string{CheckValidity, CheckHost},
+			wantChain:  2,
+		},
+		{
+			name:       "future_time",
+			args:       args{leaf: validCrt, roots: caCrt, opts: VerifyOptions{CurrentTime: now.AddDate(0, 2, 0)}},
+			wantFailed: []string{CheckValidity},
+			wantChain:  2,
+		},
+		{
+			name:       "missing_usages",
+			args:       args{leaf: validCrt, roots: caCrt, opts: VerifyOptions{Usages: []string{ExtKeyUsageCodeSigning, ExtKeyUsageClientAuth, "cert-sign"}}},
+			wantFailed: []string{CheckUsage, CheckUsage},
+			wantChain:  2,
+		},
+		{
+			name:    "unknown_usage",
+			args:    args{leaf: validCrt, roots: caCrt, opts: VerifyOptions{Usages: []string{"everything"}}},
+			wantErr: ErrorInvalidKeyUsage,
+		},
+		{
+			name:    "missing_roots",
+			args:    args{leaf: validCrt},
+			wantErr: ErrorEmptyChain,
+		},
+		{
+			name:    "missing_leaf",
+			args:    args{leaf: []byte("invalid"), roots: caCrt},
+			wantErr: ErrorEmptyChain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.args.leaf, tt.args.intermediates, tt.args.roots, tt.args.opts)
+			if err != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.Valid != tt.wantValid {
+				t.Errorf("Verify() Valid = %v, want %v: %+v", got.Valid, tt.wantValid, got.Checks)
+			}
+			if len(got.Chain) != tt.wantChain {
+				t.Errorf("Verify() Chain = %v, want %d certificates", got.Chain, tt.wantChain)
+			}
+
+			var failed []string
+			for _, check := range got.Checks {
+				if !check.Passed {
+					failed = append(failed, check.Name)
+					if check.Message == "" {
+						t.Errorf("Verify() check %s failed without a message", check.Name)
+					}
+				}
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Fatalf("Verify() failed checks = %v, want %v: %+v", failed, tt.wantFailed, got.Checks)
+			}
+			for i := range failed {
+				if failed[i] != tt.wantFailed[i] {
+					t.Errorf("Verify() failed checks = %v, want %v", failed, tt.wantFailed)
+				}
+			}
+		})
+	}
+}
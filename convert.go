@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mvmaasakkers/certificates/keystore"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+)
+
+// Output formats of certificate pairs
+const (
+	formatPEM = "pem"
+	formatP12 = "p12"
+	formatJKS = "jks"
+)
+
+// keystoreFlags are the flags used to write a certificate pair to a password protected keystore
+var keystoreFlags = flags([]cli.Flag{
+	cli.StringFlag{
+		Name:  "keystore",
+		Value: "",
+		Usage: "Filename to write the keystore to (default certificate.<format>)",
+	},
+	cli.StringFlag{
+		Name:  "alias",
+		Value: "certificate",
+		Usage: "Alias of the key entry in a jks keystore",
+	},
+}, passwordFlags)
+
+// passwordFlags are the flags used to give the password of a keystore or truststore
+var passwordFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "password",
+		Value:  "",
+		Usage:  "Password of the keystore",
+		EnvVar: "CERTIFICATES_PASSWORD",
+	},
+	cli.StringFlag{
+		Name:  "password-file",
+		Value: "",
+		Usage: "File with the password of the keystore",
+	},
+}
+
+var convertCommand = cli.Command{
+	Name:  "convert",
+	Usage: "Convert a PEM encoded certificate pair into a password protected keystore",
+	Description: `The password is taken from --password, the CERTIFICATES_PASSWORD environment variable or
+   --password-file.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "crt",
+			Value: "certificate.crt",
+			Usage: "Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "key",
+			Value: "certificate.key",
+			Usage: "Key file",
+		},
+		cli.StringFlag{
+			Name:  "chain",
+			Value: "",
+			Usage: "File with the chain of intermediate certificates to add to the keystore",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: formatP12,
+			Usage: "Keystore format (p12 or jks)",
+		},
+	}, keyPassFlags, keystoreFlags),
+	Action: func(c *cli.Context) error {
+
+		if c.String("format") != formatP12 && c.String("format") != formatJKS {
+			fmt.Printf("Error parsing --format: %s\n", errorInvalidFormat.Error())
+			return errorInvalidFormat
+		}
+
+		password, err := readPassword(c)
+		if err != nil {
+			return err
+		}
+
+		crt, err := ioutil.ReadFile(c.String("crt"))
+		if err != nil {
+			fmt.Printf("Error reading certificate: %s\n", err.Error())
+			return err
+		}
+		key, err := readKey(c, c.String("key"), "key-pass")
+		if err != nil {
+			fmt.Printf("Error reading key: %s\n", err.Error())
+			return err
+		}
+
+		var chain []byte
+		if c.String("chain") != "" {
+			chain, err = ioutil.ReadFile(c.String("chain"))
+			if err != nil {
+				fmt.Printf("Error reading chain: %s\n", err.Error())
+				return err
+			}
+		}
+
+		return writeKeystore(c, c.String("format"), crt, key, chain, password)
+	},
+}
+
+// writeKeystore encodes the PEM encoded pair and chain into a password protected keystore of the format and writes
+// it to the file given with the keystoreFlags
+func writeKeystore(c *cli.Context, format string, crt []byte, key []byte, chain []byte, password string) error {
+	var data []byte
+	var err error
+	switch format {
+	case formatJKS:
+		data, err = keystore.EncodeJKS(crt, key, chain, c.String("alias"), password)
+	default:
+		data, err = keystore.EncodePKCS12(crt, key, chain, password)
+	}
+	if err != nil {
+		fmt.Printf("Error creating keystore: %s\n", err.Error())
+		return err
+	}
+
+	filename := c.String("keystore")
+	if filename == "" {
+		filename = "certificate." + format
+	}
+
+	fmt.Printf("Writing keystore to %s\n", filename)
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		fmt.Printf("Error writing keystore to file: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// writeTrustStore writes the PEM encoded certificates to a password protected jks truststore
+func writeTrustStore(filename string, certs []byte, password string) error {
+	data, err := keystore.EncodeJKSTrustStore(certs, password)
+	if err != nil {
+		fmt.Printf("Error creating truststore: %s\n", err.Error())
+		return err
+	}
+
+	fmt.Printf("Writing truststore to %s\n", filename)
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		fmt.Printf("Error writing truststore to file: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// readPassword returns the keystore password given with --password (or its environment variable) or read from
+// --password-file
+func readPassword(c *cli.Context) (string, error) {
+	password, err := readPassphrase(c, "password")
+	if err != nil {
+		fmt.Printf("Error reading password file: %s\n", err.Error())
+		return "", err
+	}
+
+	if password == nil {
+		fmt.Printf("Error: %s\n", errorMissingPassword.Error())
+		return "", errorMissingPassword
+	}
+
+	return string(password), nil
+}
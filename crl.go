@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+)
+
+var errorInvalidFormat = errors.New("invalid format")
+
+var crlCommand = cli.Command{
+	Name:  "crl",
+	Usage: "Generate a CRL of the revoked certificates in the CA DB",
+	Description: `The CRL is signed by the CA pair and lists the revoked certificates issued by that CA. Every CA in the CA DB
+   has its own CRL number counter, the CRL gets the next number of the CA.`,
+	Flags: flags([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "stdout",
+			Usage: "Send the crl to stdout instead of to file",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "CA Key file",
+		},
+		cli.StringFlag{
+			Name:  "crl",
+			Value: "ca.crl",
+			Usage: "Filename to write the crl to",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "pem",
+			Usage: "Output format of the crl (pem or der)",
+		},
+		cli.DurationFlag{
+			Name:  "next-update",
+			Value: 7 * 24 * time.Hour,
+			Usage: "Time until the next crl update. The default is 7 days.",
+		},
+	}, caKeyPassFlags, caSignerFlags, dbFlags),
+	Action: func(c *cli.Context) error {
+
+		if c.String("format") != "pem" && c.String("format") != "der" {
+			fmt.Printf("Error parsing --format: %s\n", errorInvalidFormat.Error())
+			return errorInvalidFormat
+		}
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		caSigner, err := openCASigner(c, caCrt)
+		if err != nil {
+			fmt.Printf("Error reading CA key: %s\n", err.Error())
+			return err
+		}
+		defer caSigner.Close()
+
+		block, _ := pem.Decode(caCrt)
+		if block == nil {
+			fmt.Printf("Error reading CA certificate: %s\n", cert.ErrorInvalidCertificate.Error())
+			return cert.ErrorInvalidCertificate
+		}
+		ca, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		issuer := ca.Subject.String()
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		revoked, err := DB.GetCertificateRepository().GetRevoked()
+		if err != nil {
+			fmt.Printf("Error reading revoked certificates: %s\n", err.Error())
+			return err
+		}
+
+		// CAs can share the CA DB, only the certificates issued by this CA are listed
+		entries := make([]cert.RevokedEntry, 0, len(revoked))
+		for _, crt := range revoked {
+			if crt.Issuer == issuer {
+				entries = append(entries, revokedEntry(crt))
+			}
+		}
+
+		number, err := DB.GetCounterRepository().Next(database.CRLNumberCounter(issuer))
+		if err != nil {
+			fmt.Printf("Error getting CRL number: %s\n", err.Error())
+			return err
+		}
+
+		crl, err := cert.GenerateCRLWithSigner(caCrt, caSigner, entries, big.NewInt(number), time.Now().Add(c.Duration("next-update")))
+		if err != nil {
+			fmt.Printf("Error generating CRL: %s\n", err.Error())
+			return err
+		}
+
+		if c.String("format") == "der" {
+			block, _ := pem.Decode(crl)
+			crl = block.Bytes
+		}
+
+		if c.Bool("stdout") {
+			os.Stdout.Write(crl)
+			return nil
+		}
+
+		fmt.Printf("Generated CRL number %d with %d revoked certificates\n", number, len(entries))
+		fmt.Printf("Writing CRL to %s\n", c.String("crl"))
+		if err := ioutil.WriteFile(c.String("crl"), crl, 0644); err != nil {
+			fmt.Printf("Error writing CRL to file: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
+
+// revokedEntry converts a revoked certificate from the CA DB into a CRL entry
+func revokedEntry(crt *database.Certificate) cert.RevokedEntry {
+	entry := cert.RevokedEntry{
+		SerialNumber: crt.SerialNumber,
+		Reason:       crt.RevocationReason,
+	}
+	if crt.RevocationDate != nil {
+		entry.RevocationTime = *crt.RevocationDate
+	}
+	return entry
+}
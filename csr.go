@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+)
+
+var generateCSRCommand = cli.Command{
+	Name:    "generate-csr",
+	Aliases: []string{"gen-csr"},
+	Usage:   "Generate a certificate signing request and its key",
+	Description: `To generate a CSR you need to supply at least a valid name. The key never leaves this host, the CSR can
+   be signed by the CA with gen --csr.`,
+	Flags: flags([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "stdout",
+			Usage: "Send pem to stdout instead of to file",
+		},
+		cli.StringFlag{
+			Name:  "csr",
+			Value: "certificate.csr",
+			Usage: "Filename to write the CSR to",
+		},
+		cli.StringFlag{
+			Name:  "key",
+			Value: "certificate.key",
+			Usage: "Filename to write key to",
+		},
+		cli.StringFlag{
+			Name:  "name-serialnumber",
+			Value: "",
+			Usage: "Name SerialNumber",
+		},
+		cli.StringSliceFlag{
+			Name:  "subject-alt-name",
+			Usage: "Subject Alt Name: DNS name, IP address, email:<address> or uri:<uri>",
+		},
+	}, subjectFlags, keyFlags, keyPassFlags),
+	Action: func(c *cli.Context) error {
+
+		cr := cert.NewRequest()
+		setSubject(c, cr)
+		setKey(c, cr)
+		cr.NameSerialNumber = c.String("name-serialnumber")
+		cr.SubjectAltNames = c.StringSlice("subject-alt-name")
+
+		csr, key, err := cert.GenerateCSR(cr)
+		if err != nil {
+			fmt.Printf("Error generating CSR: %s\n", err.Error())
+			return err
+		}
+
+		key, err = encryptKey(c, key, "key-pass")
+		if err != nil {
+			fmt.Printf("Error encrypting key: %s\n", err.Error())
+			return err
+		}
+
+		if c.Bool("stdout") {
+			fmt.Println(string(key))
+			fmt.Println(string(csr))
+			return nil
+		}
+
+		fmt.Printf("Writing CSR to %s\n", c.String("csr"))
+		if err := ioutil.WriteFile(c.String("csr"), csr, 0644); err != nil {
+			fmt.Printf("Error writing CSR to file: %s\n", err.Error())
+			return err
+		}
+		fmt.Printf("Writing key to %s\n", c.String("key"))
+		if err := ioutil.WriteFile(c.String("key"), key, 0600); err != nil {
+			fmt.Printf("Error writing key to file: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Account is the struct for the database ACME account object
+//
+// The KeyID is the RFC 7638 thumbprint of the account key, the key itself is stored as JWK.
+type Account struct {
+	Meta
+
+	KeyID   string `gorm:"unique"`
+	JWK     string `gorm:"type:text"`
+	Contact string `gorm:"type:text"`
+	Status  string
+}
+
+// NewAccount creates a new Account object with a generated ID and settings the CreatedAt and UpdatedAt to now
+func NewAccount() *Account {
+	uid, _ := uuid.NewRandom()
+
+	a := &Account{}
+	a.UUID = uid.String()
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = time.Now()
+
+	return a
+}
+
+// Order is the struct for the database ACME order object
+//
+// The Identifiers are the comma separated DNS names of the order. The Authorizations (including their challenges)
+// are owned by the ACME server and stored as JSON. Once the order is finalized the CertificateSerialNumber refers
+// to the issued certificate, in decimal notation.
+type Order struct {
+	Meta
+
+	AccountUUID             string `gorm:"index"`
+	Status                  string
+	Identifiers             string `gorm:"type:text"`
+	Expires                 time.Time
+	Authorizations          string `gorm:"type:text"`
+	CertificateSerialNumber string
+	Error                   string `gorm:"type:text"`
+}
+
+// NewOrder creates a new Order object with a generated ID and settings the CreatedAt and UpdatedAt to now
+func NewOrder() *Order {
+	uid, _ := uuid.NewRandom()
+
+	o := &Order{}
+	o.UUID = uid.String()
+	o.CreatedAt = time.Now()
+	o.UpdatedAt = time.Now()
+
+	return o
+}
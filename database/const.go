@@ -9,4 +9,24 @@ var (
 	ErrorObjectNotFound = errors.New("object not found")
 	// ErrorDuplicateObject is used when a Serial Number (which is the primary id) is already in the db
 	ErrorDuplicateObject = errors.New("object is a duplicate")
+	// ErrorMissingSerialNumber is used when a certificate without a Serial Number is stored
+	ErrorMissingSerialNumber = errors.New("object has no serial number")
+	// ErrorInvalidCertificate is used when a certificate to store can not be decoded
+	ErrorInvalidCertificate = errors.New("invalid certificate")
+	// ErrorAlreadyRevoked is used when a certificate that is already revoked is revoked again
+	ErrorAlreadyRevoked = errors.New("object is already revoked")
+)
+
+const (
+	// CounterCRLNumber is the prefix of the counters used for CRL numbers, see CRLNumberCounter
+	CounterCRLNumber = "crl_number"
+	// CounterSerialNumber is the name of the counter used for sequential serial numbers
+	CounterSerialNumber = "serial_number"
+)
+
+const (
+	// StatusValid is the status of a certificate that is issued and not revoked
+	StatusValid = "valid"
+	// StatusRevoked is the status of a certificate that is revoked
+	StatusRevoked = "revoked"
 )
@@ -1,8 +1,18 @@
 package database
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/mvmaasakkers/certificates/cert"
 	"math/big"
+	"strings"
 	"time"
 )
 
@@ -13,25 +23,97 @@ type DB interface {
 	Provision() error
 
 	GetCertificateRepository() CertificateRepository
+	GetCounterRepository() CounterRepository
+	GetAccountRepository() AccountRepository
+	GetOrderRepository() OrderRepository
 }
 
 // CertificateRepository is the interface for certificate repository implementations
 type CertificateRepository interface {
 	GetByNameSerialNumber(nameSerialNumber string) (*Certificate, error)
+	GetBySerialNumber(serialNumber *big.Int) (*Certificate, error)
+	GetRevoked() ([]*Certificate, error)
+	List(filter CertificateFilter) ([]*Certificate, error)
 	Create(certificate *Certificate) error
+	Revoke(serialNumber *big.Int, reason int, revocationDate time.Time) error
+	CreateRenewal(serialNumber *big.Int, renewal *Certificate, revoke bool) error
 	DeleteByNameSerialNumber(nameSerialNumber string) error
 }
 
+// CertificateFilter is used to list certificates. Zero values are ignored, so an empty filter lists all
+// certificates. The results are ordered by the time they were created.
+type CertificateFilter struct {
+	// Status only lists certificates with the given status
+	Status string
+	// CommonName only lists certificates with a common name containing the given value
+	CommonName string
+	// ExpiresBefore only lists certificates that expire before the given time
+	ExpiresBefore *time.Time
+	// IssuedAfter only lists certificates that were created after the given time
+	IssuedAfter *time.Time
+
+	// Offset is the number of matching certificates to skip
+	Offset int
+	// Limit is the maximum number of certificates to list, 0 means no limit
+	Limit int
+}
+
+// CRLNumberCounter returns the name of the CRL number counter of the CA with the given subject, so CAs sharing a
+// database each number their own CRLs
+func CRLNumberCounter(issuer string) string {
+	h := sha256.Sum256([]byte(issuer))
+	return CounterCRLNumber + "_" + hex.EncodeToString(h[:])
+}
+
+// CounterRepository is the interface for persistent counter implementations, used for example for CRL numbers
+type CounterRepository interface {
+	// Next increments the counter with the given name and returns the new value. The first value of a counter is 1.
+	Next(name string) (int64, error)
+}
+
+// AccountRepository is the interface for ACME account repository implementations
+type AccountRepository interface {
+	GetByUUID(uuid string) (*Account, error)
+	GetByKeyID(keyID string) (*Account, error)
+	Create(account *Account) error
+	Update(account *Account) error
+}
+
+// OrderRepository is the interface for ACME order repository implementations
+type OrderRepository interface {
+	GetByUUID(uuid string) (*Order, error)
+	Create(order *Order) error
+	Update(order *Order) error
+}
+
 // Certificate is the struct for the database certificate object
+//
+// The SerialNumber is not mapped by gorm directly, implementations have to take care of storing it themselves.
+// The RevocationReason holds the RFC 5280 reason code and is only relevant when the Status is StatusRevoked.
+// The issued certificate itself and the details derived from it are set with SetCertificate. Certificates stored
+// before these details were kept have them empty. A certificate and its renewal (see CreateRenewal) are linked by
+// Renews and RenewedBy, which hold the serial number of the other certificate in decimal notation.
 type Certificate struct {
 	Meta
 
 	Status           string
 	ExpirationDate   time.Time
 	RevocationDate   *time.Time
+	RevocationReason int
 	NameSerialNumber string   `gorm:"unique"`
-	SerialNumber     *big.Int `gorm:"unique"`
+	SerialNumber     *big.Int `gorm:"-"`
 	CommonName       string
+
+	CertificatePEM  string `gorm:"type:text"`
+	SubjectAltNames string `gorm:"type:text"`
+	Issuer          string
+	KeyAlgorithm    string
+	Fingerprint     string `gorm:"index"`
+	SubjectKeyID    string
+
+	Profile   string
+	Renews    string
+	RenewedBy string
 }
 
 // NewCertificate creates a new Certificate object with a generated ID and settings the CreatedAt and UpdatedAt to now
@@ -46,6 +128,54 @@ func NewCertificate() *Certificate {
 	return c
 }
 
+// SetCertificate sets the PEM encoded certificate and the details derived from it: the serial number, common name,
+// expiration date, subject alt names (comma separated, in the notation of cert.ParseSubjectAltNames), issuer DN, key
+// algorithm, SHA-256 fingerprint and Subject Key Identifier (both in hexadecimal notation).
+func (c *Certificate) SetCertificate(crtPEM []byte) error {
+	block, _ := pem.Decode(crtPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return ErrorInvalidCertificate
+	}
+
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	fingerprint := sha256.Sum256(crt.Raw)
+	sans := &cert.SubjectAltNames{
+		DNSNames:       crt.DNSNames,
+		IPAddresses:    crt.IPAddresses,
+		EmailAddresses: crt.EmailAddresses,
+		URIs:           crt.URIs,
+	}
+
+	c.CertificatePEM = string(pem.EncodeToMemory(block))
+	c.SerialNumber = crt.SerialNumber
+	c.CommonName = crt.Subject.CommonName
+	c.ExpirationDate = crt.NotAfter
+	c.SubjectAltNames = strings.Join(sans.Strings(), ",")
+	c.Issuer = crt.Issuer.String()
+	c.KeyAlgorithm = keyAlgorithm(crt.PublicKey)
+	c.Fingerprint = hex.EncodeToString(fingerprint[:])
+	c.SubjectKeyID = hex.EncodeToString(crt.SubjectKeyId)
+
+	return nil
+}
+
+// keyAlgorithm describes the public key, for example RSA-4096 or ECDSA-P-256
+func keyAlgorithm(pub interface{}) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA-%s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return "unknown"
+}
+
 // Meta is an extra set of recurring data used in database objects
 type Meta struct {
 	UUID      string `gorm:"index"`
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"github.com/mvmaasakkers/certificates/cert"
+	"math/big"
 	"testing"
 )
 
@@ -22,3 +24,64 @@ func TestNewCertificate(t *testing.T) {
 		})
 	}
 }
+
+func TestCertificate_SetCertificate(t *testing.T) {
+	caCrt, caKey, err := cert.GenerateCA(&cert.Request{CommonName: "ca.test.local", KeyType: cert.KeyTypeECDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaCrt, _, err := cert.GenerateCertificate(&cert.Request{CommonName: "ecdsa.test.local", KeyType: cert.KeyTypeECDSA, Curve: cert.CurveP384, SerialNumber: big.NewInt(1001), SubjectAltNames: []string{"ecdsa.test.local", "www.test.local"}}, caCrt, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Crt, _, err := cert.GenerateCertificate(&cert.Request{CommonName: "ed25519.test.local", KeyType: cert.KeyTypeEd25519, SerialNumber: big.NewInt(1002)}, caCrt, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		crt              []byte
+		wantSerial       int64
+		wantSANs         string
+		wantKeyAlgorithm string
+		wantErr          bool
+	}{
+		{name: "ecdsa", crt: ecdsaCrt, wantSerial: 1001, wantSANs: "ecdsa.test.local,www.test.local", wantKeyAlgorithm: "ECDSA-P-384"},
+		{name: "ed25519", crt: ed25519Crt, wantSerial: 1002, wantSANs: "", wantKeyAlgorithm: "Ed25519"},
+		{name: "invalid", crt: []byte("invalid"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCertificate()
+			if err := c.SetCertificate(tt.crt); (err != nil) != tt.wantErr {
+				t.Errorf("SetCertificate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if c.CertificatePEM != string(tt.crt) {
+				t.Errorf("SetCertificate() CertificatePEM = %v, want %v", c.CertificatePEM, string(tt.crt))
+			}
+			if c.SerialNumber.Int64() != tt.wantSerial {
+				t.Errorf("SetCertificate() SerialNumber = %v, want %v", c.SerialNumber, tt.wantSerial)
+			}
+			if c.SubjectAltNames != tt.wantSANs {
+				t.Errorf("SetCertificate() SubjectAltNames = %v, want %v", c.SubjectAltNames, tt.wantSANs)
+			}
+			if c.KeyAlgorithm != tt.wantKeyAlgorithm {
+				t.Errorf("SetCertificate() KeyAlgorithm = %v, want %v", c.KeyAlgorithm, tt.wantKeyAlgorithm)
+			}
+			if c.Issuer != "CN=ca.test.local" {
+				t.Errorf("SetCertificate() Issuer = %v, want %v", c.Issuer, "CN=ca.test.local")
+			}
+			if len(c.Fingerprint) != 64 || len(c.SubjectKeyID) != 40 {
+				t.Errorf("SetCertificate() Fingerprint = %v, SubjectKeyID = %v", c.Fingerprint, c.SubjectKeyID)
+			}
+		})
+	}
+}
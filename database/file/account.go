@@ -0,0 +1,90 @@
+package file
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+	"time"
+)
+
+// GetAccountRepository returns a bootstrapped account repository
+func (db *db) GetAccountRepository() database.AccountRepository {
+	return &AccountRepository{db}
+}
+
+// AccountRepository implements the AccountRepository interface from database package
+type AccountRepository struct {
+	db *db
+}
+
+// GetByUUID gets an account by UUID
+func (repo *AccountRepository) GetByUUID(uuid string) (*database.Account, error) {
+	var account *database.Account
+	err := repo.db.view(func(s *state) error {
+		a, ok := s.Accounts[uuid]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+
+		account = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// GetByKeyID gets an account by the thumbprint of its key
+func (repo *AccountRepository) GetByKeyID(keyID string) (*database.Account, error) {
+	var account *database.Account
+	err := repo.db.view(func(s *state) error {
+		key, ok := s.accountKeyIDs[keyID]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+
+		account = s.Accounts[key]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// Create creates an account. Both the UUID and the KeyID have to be unique.
+func (repo *AccountRepository) Create(account *database.Account) error {
+	return repo.db.update(func(s *state) error {
+		if _, ok := s.Accounts[account.UUID]; ok {
+			return database.ErrorDuplicateObject
+		}
+		if _, ok := s.accountKeyIDs[account.KeyID]; ok {
+			return database.ErrorDuplicateObject
+		}
+
+		s.Accounts[account.UUID] = account
+		s.accountKeyIDs[account.KeyID] = account.UUID
+		return nil
+	})
+}
+
+// Update updates the account with the UUID of the given account
+func (repo *AccountRepository) Update(account *database.Account) error {
+	return repo.db.update(func(s *state) error {
+		existing, ok := s.Accounts[account.UUID]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+		if key, ok := s.accountKeyIDs[account.KeyID]; ok && key != account.UUID {
+			return database.ErrorDuplicateObject
+		}
+
+		delete(s.accountKeyIDs, existing.KeyID)
+
+		account.UpdatedAt = time.Now()
+		s.Accounts[account.UUID] = account
+		s.accountKeyIDs[account.KeyID] = account.UUID
+		return nil
+	})
+}
@@ -1,7 +1,12 @@
 package file
 
 import (
+	"github.com/mvmaasakkers/certificates/cert"
 	"github.com/mvmaasakkers/certificates/database"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
 )
 
 // GetCertificateRepository returns a bootstrapped certificate repository
@@ -16,38 +21,218 @@ type CertificateRepository struct {
 
 // GetByNameSerialNumber gets a certificate by NameSerialNumber
 func (repo *CertificateRepository) GetByNameSerialNumber(nameSerialNumber string) (*database.Certificate, error) {
-	repo.db.stateLock.Lock()
-	defer repo.db.stateLock.Unlock()
+	var crt *database.Certificate
+	err := repo.db.view(func(s *state) error {
+		key, ok := s.nameSerialNumbers[nameSerialNumber]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+
+		crt = s.Certificates[key]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return crt, nil
+}
+
+// GetBySerialNumber gets a certificate by SerialNumber
+func (repo *CertificateRepository) GetBySerialNumber(serialNumber *big.Int) (*database.Certificate, error) {
+	if serialNumber == nil {
+		return nil, database.ErrorObjectNotFound
+	}
 
-	for _, c := range repo.db.state.Certificates {
-		if c.NameSerialNumber == nameSerialNumber {
-			return c, nil
+	var crt *database.Certificate
+	err := repo.db.view(func(s *state) error {
+		c, ok := s.Certificates[serialNumber.String()]
+		if !ok {
+			return database.ErrorObjectNotFound
 		}
+
+		crt = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, database.ErrorObjectNotFound
+	return crt, nil
+}
+
+// GetRevoked gets all revoked certificates
+func (repo *CertificateRepository) GetRevoked() ([]*database.Certificate, error) {
+	revoked := []*database.Certificate{}
+	err := repo.db.view(func(s *state) error {
+		for _, c := range s.Certificates {
+			if c.Status == database.StatusRevoked {
+				revoked = append(revoked, c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revoked, nil
+}
+
+// List lists the certificates matching the given filter
+func (repo *CertificateRepository) List(filter database.CertificateFilter) ([]*database.Certificate, error) {
+	list := []*database.Certificate{}
+	err := repo.db.view(func(s *state) error {
+		for _, c := range s.Certificates {
+			if matchesFilter(c, filter) {
+				list = append(list, c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].NameSerialNumber < list[j].NameSerialNumber
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	if filter.Offset > 0 {
+		if filter.Offset >= len(list) {
+			return []*database.Certificate{}, nil
+		}
+		list = list[filter.Offset:]
+	}
+
+	if filter.Limit > 0 && filter.Limit < len(list) {
+		list = list[:filter.Limit]
+	}
+
+	return list, nil
+}
+
+// matchesFilter checks if the certificate matches all the set fields of the filter
+func matchesFilter(c *database.Certificate, filter database.CertificateFilter) bool {
+	if filter.Status != "" && c.Status != filter.Status {
+		return false
+	}
+	if filter.CommonName != "" && !strings.Contains(c.CommonName, filter.CommonName) {
+		return false
+	}
+	if filter.ExpiresBefore != nil && !c.ExpirationDate.Before(*filter.ExpiresBefore) {
+		return false
+	}
+	if filter.IssuedAfter != nil && !c.CreatedAt.After(*filter.IssuedAfter) {
+		return false
+	}
+	return true
 }
 
-// Create creates a certificate
+// Create creates a certificate. Both the SerialNumber and the NameSerialNumber have to be unique.
 func (repo *CertificateRepository) Create(certificate *database.Certificate) error {
-	repo.db.stateLock.Lock()
-	defer repo.db.stateLock.Unlock()
+	if certificate.SerialNumber == nil {
+		return database.ErrorMissingSerialNumber
+	}
+
+	return repo.db.update(func(s *state) error {
+		key := certificateKey(certificate)
+		if _, ok := s.Certificates[key]; ok {
+			return database.ErrorDuplicateObject
+		}
+		if _, ok := s.nameSerialNumbers[certificate.NameSerialNumber]; ok {
+			return database.ErrorDuplicateObject
+		}
+
+		s.Certificates[key] = certificate
+		s.nameSerialNumbers[certificate.NameSerialNumber] = key
+		return nil
+	})
+}
 
-	if repo.db.state.Certificates == nil {
-		repo.db.state.Certificates = make(map[string]*database.Certificate, 0)
+// Revoke marks the certificate with the given SerialNumber as revoked with the given RFC 5280 reason code
+func (repo *CertificateRepository) Revoke(serialNumber *big.Int, reason int, revocationDate time.Time) error {
+	if serialNumber == nil {
+		return database.ErrorObjectNotFound
 	}
 
-	if _, ok := repo.db.state.Certificates[certificate.CommonName]; ok {
-		return database.ErrorDuplicateObject
+	return repo.db.update(func(s *state) error {
+		c, ok := s.Certificates[serialNumber.String()]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+
+		if c.Status == database.StatusRevoked {
+			return database.ErrorAlreadyRevoked
+		}
+
+		c.Status = database.StatusRevoked
+		c.RevocationDate = &revocationDate
+		c.RevocationReason = reason
+		c.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// CreateRenewal creates the renewal of the certificate with the given SerialNumber and links both certificates. The
+// renewal has to be unique like in Create. With revoke the renewed certificate is revoked as superseded in the same
+// update, ErrorAlreadyRevoked is returned when it already is revoked.
+func (repo *CertificateRepository) CreateRenewal(serialNumber *big.Int, renewal *database.Certificate, revoke bool) error {
+	if serialNumber == nil {
+		return database.ErrorObjectNotFound
+	}
+	if renewal.SerialNumber == nil {
+		return database.ErrorMissingSerialNumber
 	}
 
-	repo.db.state.Certificates[certificate.CommonName] = certificate
-	return repo.db.writeState()
+	return repo.db.update(func(s *state) error {
+		c, ok := s.Certificates[serialNumber.String()]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+		if revoke && c.Status == database.StatusRevoked {
+			return database.ErrorAlreadyRevoked
+		}
+
+		key := certificateKey(renewal)
+		if _, ok := s.Certificates[key]; ok {
+			return database.ErrorDuplicateObject
+		}
+		if _, ok := s.nameSerialNumbers[renewal.NameSerialNumber]; ok {
+			return database.ErrorDuplicateObject
+		}
+
+		renewal.Renews = serialNumber.String()
+		s.Certificates[key] = renewal
+		s.nameSerialNumbers[renewal.NameSerialNumber] = key
+
+		c.RenewedBy = renewal.SerialNumber.String()
+		c.UpdatedAt = time.Now()
+		if revoke {
+			revocationDate := c.UpdatedAt
+			c.Status = database.StatusRevoked
+			c.RevocationDate = &revocationDate
+			c.RevocationReason = cert.ReasonSuperseded
+		}
+		return nil
+	})
 }
 
 // DeleteByNameSerialNumber deletes a certificate by NameSerialNumber
 func (repo *CertificateRepository) DeleteByNameSerialNumber(nameSerialNumber string) error {
-	return nil
+	return repo.db.update(func(s *state) error {
+		key, ok := s.nameSerialNumbers[nameSerialNumber]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+
+		delete(s.Certificates, key)
+		delete(s.nameSerialNumbers, nameSerialNumber)
+		return nil
+	})
 }
 
 // Certificate is the implementation for the Certificate struct in the database package
@@ -9,6 +9,38 @@ func TestCertificateCertificate(t *testing.T) {
 	test.TestCertificateCertificate(t, testDB.GetCertificateRepository())
 }
 
+func TestCertificateBySerialNumber(t *testing.T) {
+	test.TestCertificateBySerialNumber(t, testDB.GetCertificateRepository())
+}
+
 func TestCertificateCreateCertificate(t *testing.T) {
 	test.TestCertificateCreateCertificate(t, testDB.GetCertificateRepository())
 }
+
+func TestCertificateRevoke(t *testing.T) {
+	test.TestCertificateRevoke(t, testDB.GetCertificateRepository())
+}
+
+func TestCertificateDelete(t *testing.T) {
+	test.TestCertificateDelete(t, testDB.GetCertificateRepository())
+}
+
+func TestCertificateList(t *testing.T) {
+	test.TestCertificateList(t, testDB.GetCertificateRepository())
+}
+
+func TestCertificateDetails(t *testing.T) {
+	test.TestCertificateDetails(t, testDB.GetCertificateRepository())
+}
+
+func TestCertificateCreateDuplicate(t *testing.T) {
+	test.TestCertificateCreateDuplicate(t, testDB.GetCertificateRepository())
+}
+
+func TestCertificateRenew(t *testing.T) {
+	test.TestCertificateRenew(t, testDB.GetCertificateRepository())
+}
+
+func TestSerialNumberUnique(t *testing.T) {
+	test.TestSerialNumberUnique(t, testDB.GetCertificateRepository())
+}
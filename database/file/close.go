@@ -1,5 +1,12 @@
 package file
 
 func (db *db) Close() error {
-	return nil
+	if db.lockFile == nil {
+		return nil
+	}
+
+	err := db.lockFile.Close()
+	db.lockFile = nil
+	db.lock = nil
+	return err
 }
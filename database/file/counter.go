@@ -0,0 +1,30 @@
+package file
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+)
+
+// GetCounterRepository returns a bootstrapped counter repository
+func (db *db) GetCounterRepository() database.CounterRepository {
+	return &CounterRepository{db}
+}
+
+// CounterRepository implements the CounterRepository interface from database package
+type CounterRepository struct {
+	db *db
+}
+
+// Next increments the counter with the given name and returns the new value
+func (repo *CounterRepository) Next(name string) (int64, error) {
+	var value int64
+	err := repo.db.update(func(s *state) error {
+		s.Counters[name]++
+		value = s.Counters[name]
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,14 @@
+package file
+
+import (
+	"github.com/mvmaasakkers/certificates/database/test"
+	"testing"
+)
+
+func TestCounterNext(t *testing.T) {
+	test.TestCounterNext(t, testDB.GetCounterRepository())
+}
+
+func TestSerialNumberSequential(t *testing.T) {
+	test.TestSerialNumberSequential(t, testDB.GetCounterRepository())
+}
@@ -4,52 +4,205 @@ import (
 	"encoding/json"
 	"github.com/mvmaasakkers/certificates/database"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
+// stateVersion is the version of the state format. Version 0 had the certificates keyed by CommonName, since
+// version 1 they are keyed by SerialNumber.
+const stateVersion = 1
+
+// db is a file based DB, storing the state as JSON. Every operation reads the latest state from the file while
+// holding an advisory lock on a separate lock file, so multiple processes can safely share the same file. The state is
+// written atomically by writing to a temporary file which is renamed over the original.
 type db struct {
-	filename  string
-	stateLock *sync.Mutex
-	fileLock  *sync.Mutex
-	state     *state
+	filename string
+	lock     *sync.Mutex
+	lockFile *os.File
 }
 
 type state struct {
+	Version      int
 	LastSync     time.Time
 	Certificates map[string]*database.Certificate
+	Counters     map[string]int64
+	Accounts     map[string]*database.Account
+	Orders       map[string]*database.Order
+
+	// nameSerialNumbers is the index of the certificate keys by NameSerialNumber and accountKeyIDs is the index of
+	// the account keys by KeyID, they are rebuilt on every read
+	nameSerialNumbers map[string]string
+	accountKeyIDs     map[string]string
 }
 
 // NewDB bootstraps a new File DB instance
 func NewDB(filename string) database.DB {
 	return &db{
 		filename: filename,
-		state:    &state{},
 	}
 }
 
-func (db *db) readState() error {
-	db.fileLock.Lock()
-	defer db.fileLock.Unlock()
+// view runs fn on the latest state while holding a shared lock
+func (db *db) view(fn func(s *state) error) error {
+	if db.lock == nil {
+		return database.ErrorNilConnection
+	}
 
-	d, err := ioutil.ReadFile(db.filename)
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := lockFile(db.lockFile, false); err != nil {
+		return err
+	}
+	defer unlockFile(db.lockFile)
+
+	s, err := db.readState()
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(d, db.state)
+	return fn(s)
 }
 
-func (db *db) writeState() error {
-	db.fileLock.Lock()
-	defer db.fileLock.Unlock()
+// update runs fn on the latest state while holding an exclusive lock and writes the state when fn succeeds
+func (db *db) update(fn func(s *state) error) error {
+	if db.lock == nil {
+		return database.ErrorNilConnection
+	}
 
-	db.state.LastSync = time.Now()
+	db.lock.Lock()
+	defer db.lock.Unlock()
 
-	d, err := json.Marshal(db.state)
+	if err := lockFile(db.lockFile, true); err != nil {
+		return err
+	}
+	defer unlockFile(db.lockFile)
+
+	s, err := db.readState()
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(db.filename, d, 0644)
+	if err := fn(s); err != nil {
+		return err
+	}
+
+	return db.writeState(s)
+}
+
+// readState reads the state from file. A missing file results in an empty state.
+func (db *db) readState() (*state, error) {
+	s := &state{}
+
+	d, err := ioutil.ReadFile(db.filename)
+	switch {
+	case os.IsNotExist(err):
+		s.Version = stateVersion
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(d, s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Certificates == nil {
+		s.Certificates = make(map[string]*database.Certificate)
+	}
+	if s.Counters == nil {
+		s.Counters = make(map[string]int64)
+	}
+	if s.Accounts == nil {
+		s.Accounts = make(map[string]*database.Account)
+	}
+	if s.Orders == nil {
+		s.Orders = make(map[string]*database.Order)
+	}
+
+	if s.Version < stateVersion {
+		s.migrate()
+	}
+
+	s.buildIndexes()
+	return s, nil
+}
+
+// writeState writes the state to a temporary file, syncs it to disk and renames it over the original file
+func (db *db) writeState(s *state) error {
+	s.LastSync = time.Now()
+
+	d, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	dir, base := filepath.Split(db.filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := ioutil.TempFile(dir, base+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(d); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), db.filename); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// migrate upgrades the state to the current version
+func (s *state) migrate() {
+	if s.Version < 1 {
+		certificates := make(map[string]*database.Certificate, len(s.Certificates))
+		for _, c := range s.Certificates {
+			certificates[certificateKey(c)] = c
+		}
+		s.Certificates = certificates
+	}
+
+	s.Version = stateVersion
+}
+
+// buildIndexes builds the secondary indexes of the state
+func (s *state) buildIndexes() {
+	s.nameSerialNumbers = make(map[string]string, len(s.Certificates))
+	for key, c := range s.Certificates {
+		s.nameSerialNumbers[c.NameSerialNumber] = key
+	}
+
+	s.accountKeyIDs = make(map[string]string, len(s.Accounts))
+	for key, a := range s.Accounts {
+		s.accountKeyIDs[a.KeyID] = key
+	}
+}
+
+// certificateKey returns the key of the certificate in the state, which is the SerialNumber in decimal notation.
+// Certificates without a SerialNumber, which could be stored in version 0, are keyed by their UUID.
+func certificateKey(c *database.Certificate) string {
+	if c.SerialNumber == nil {
+		return "uuid:" + c.UUID
+	}
+	return c.SerialNumber.String()
 }
@@ -0,0 +1,137 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/database"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestDB_migrate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file.db")
+
+	v0 := `{"LastSync":"2019-01-01T00:00:00Z","Certificates":{"test.id":{"UUID":"1","NameSerialNumber":"testserial","SerialNumber":1001,"CommonName":"test.id","Status":"valid"}}}`
+	if err := ioutil.WriteFile(filename, []byte(v0), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewDB(filename)
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Provision(); err != nil {
+		t.Fatal(err)
+	}
+
+	crt, err := db.GetCertificateRepository().GetBySerialNumber(big.NewInt(1001))
+	if err != nil {
+		t.Fatalf("GetBySerialNumber() error = %v", err)
+	}
+	if crt.NameSerialNumber != "testserial" {
+		t.Errorf("GetBySerialNumber() = %v, want %v", crt.NameSerialNumber, "testserial")
+	}
+
+	d, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &state{}
+	if err := json.Unmarshal(d, s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != stateVersion {
+		t.Errorf("Provision() version = %v, want %v", s.Version, stateVersion)
+	}
+	if _, ok := s.Certificates["1001"]; !ok {
+		t.Errorf("Provision() certificates = %v, want them keyed by serial number", s.Certificates)
+	}
+}
+
+func TestDB_concurrent(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file.db")
+
+	const instances = 4
+	const certificates = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, instances*certificates*2)
+	for i := 0; i < instances; i++ {
+		// Every instance uses its own DB, like separate processes sharing the same file
+		db := NewDB(filename)
+		if err := db.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		if err := db.Provision(); err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func(i int, db database.DB) {
+			defer wg.Done()
+			for j := 0; j < certificates; j++ {
+				c := database.NewCertificate()
+				c.CommonName = "concurrent.test.id"
+				c.NameSerialNumber = fmt.Sprintf("concurrent-%d-%d", i, j)
+				c.SerialNumber = big.NewInt(int64(i*certificates + j + 1))
+				errs <- db.GetCertificateRepository().Create(c)
+
+				_, err := db.GetCounterRepository().Next(database.CounterCRLNumber)
+				errs <- err
+			}
+		}(i, db)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent write error = %v", err)
+		}
+	}
+
+	db := NewDB(filename)
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	list, err := db.GetCertificateRepository().List(database.CertificateFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != instances*certificates {
+		t.Errorf("List() = %d certificates, want %d", len(list), instances*certificates)
+	}
+
+	next, err := db.GetCounterRepository().Next(database.CounterCRLNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != instances*certificates+1 {
+		t.Errorf("Next() = %d, want %d", next, instances*certificates+1)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "file.db.tmp*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("temporary files left behind: %v", files)
+	}
+}
+
+func TestDB_closed(t *testing.T) {
+	db := NewDB(filepath.Join(t.TempDir(), "file.db"))
+	if _, err := db.GetCertificateRepository().GetByNameSerialNumber("testserial"); err != database.ErrorNilConnection {
+		t.Errorf("GetByNameSerialNumber() error = %v, want %v", err, database.ErrorNilConnection)
+	}
+}
@@ -18,11 +18,14 @@ func TestMain(m *testing.M) {
 
 func runTests(m *testing.M) int {
 	testDB = NewDB("file.db")
+	defer os.Remove("file.db")
+	defer os.Remove("file.db.lock")
 
 	if err := testDB.Open(); err != nil {
 		fmt.Println(err)
 		return 1
 	}
+	defer testDB.Close()
 	if err := testDB.Provision(); err != nil {
 		return 1
 	}
@@ -30,7 +33,6 @@ func runTests(m *testing.M) int {
 	test.InsertFixtures(testDB)
 
 	defer test.ClearFixtures(testDB)
-	defer os.Remove("file.db")
 
 	return m.Run()
 }
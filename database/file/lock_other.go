@@ -0,0 +1,21 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package file
+
+import "os"
+
+// lockFile is a no-op on platforms without file locking support, only the locking within the process applies
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without file locking support
+func unlockFile(f *os.File) error {
+	return nil
+}
+
+// syncDir is a no-op on platforms without file locking support
+func syncDir(dir string) error {
+	return nil
+}
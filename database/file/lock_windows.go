@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package file
+
+import (
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// lockFile places a lock on the file, exclusive for writing or shared for reading. It blocks until the lock is
+// acquired.
+func lockFile(f *os.File, exclusive bool) error {
+	var flags uint32
+	if exclusive {
+		flags = windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+
+	return windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, 1, 0, &windows.Overlapped{})
+}
+
+// unlockFile releases the lock on the file
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
+
+// syncDir is a no-op, as directories can not be synced on windows
+func syncDir(dir string) error {
+	return nil
+}
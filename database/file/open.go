@@ -6,14 +6,15 @@ import (
 )
 
 func (db *db) Open() error {
-	db.fileLock = &sync.Mutex{}
-	db.stateLock = &sync.Mutex{}
-
-	if _, err := os.Stat(db.filename); os.IsNotExist(err) {
-		if err := db.writeState(); err != nil {
-			return err
-		}
+	lockFile, err := os.OpenFile(db.filename+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
 	}
 
-	return db.readState()
+	db.lockFile = lockFile
+	db.lock = &sync.Mutex{}
+
+	return db.view(func(s *state) error {
+		return nil
+	})
 }
@@ -0,0 +1,60 @@
+package file
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+	"time"
+)
+
+// GetOrderRepository returns a bootstrapped order repository
+func (db *db) GetOrderRepository() database.OrderRepository {
+	return &OrderRepository{db}
+}
+
+// OrderRepository implements the OrderRepository interface from database package
+type OrderRepository struct {
+	db *db
+}
+
+// GetByUUID gets an order by UUID
+func (repo *OrderRepository) GetByUUID(uuid string) (*database.Order, error) {
+	var order *database.Order
+	err := repo.db.view(func(s *state) error {
+		o, ok := s.Orders[uuid]
+		if !ok {
+			return database.ErrorObjectNotFound
+		}
+
+		order = o
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Create creates an order
+func (repo *OrderRepository) Create(order *database.Order) error {
+	return repo.db.update(func(s *state) error {
+		if _, ok := s.Orders[order.UUID]; ok {
+			return database.ErrorDuplicateObject
+		}
+
+		s.Orders[order.UUID] = order
+		return nil
+	})
+}
+
+// Update updates the order with the UUID of the given order
+func (repo *OrderRepository) Update(order *database.Order) error {
+	return repo.db.update(func(s *state) error {
+		if _, ok := s.Orders[order.UUID]; !ok {
+			return database.ErrorObjectNotFound
+		}
+
+		order.UpdatedAt = time.Now()
+		s.Orders[order.UUID] = order
+		return nil
+	})
+}
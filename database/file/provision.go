@@ -1,5 +1,8 @@
 package file
 
+// Provision creates the file, or migrates it to the current state version
 func (db *db) Provision() error {
-	return db.writeState()
+	return db.update(func(s *state) error {
+		return nil
+	})
 }
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"github.com/mvmaasakkers/certificates/cert"
+	"math/big"
+)
+
+// maxSerialNumberAttempts is the number of serial numbers WithUniqueSerialNumber tries
+const maxSerialNumberAttempts = 10
+
+// SequentialSerialGenerator generates serial numbers from the CounterSerialNumber counter, so the serial numbers of
+// a CA are 1, 2, 3 and so on. Sequential serial numbers have no entropy, they are meant for private CAs only.
+type SequentialSerialGenerator struct {
+	repo CounterRepository
+}
+
+// NewSequentialSerialGenerator creates a SequentialSerialGenerator persisted in the counter repository
+func NewSequentialSerialGenerator(repo CounterRepository) *SequentialSerialGenerator {
+	return &SequentialSerialGenerator{repo: repo}
+}
+
+// Next increments the counter and returns it as serial number
+func (g *SequentialSerialGenerator) Next() (*big.Int, error) {
+	n, err := g.repo.Next(CounterSerialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return big.NewInt(n), nil
+}
+
+// WithUniqueSerialNumber calls issue with a serial number of the generator, issue is expected to issue the
+// certificate and store it in the repository. When issue fails with ErrorDuplicateObject because the serial number
+// is already taken it is called again with the next serial number. Serial numbers that are known to be taken are
+// skipped before issue is called.
+//
+// ErrorDuplicateObject is returned when no free serial number is found after a few attempts, or when something else
+// than the serial number (such as the NameSerialNumber) is a duplicate.
+func WithUniqueSerialNumber(repo CertificateRepository, serials cert.SerialGenerator, issue func(serialNumber *big.Int) error) error {
+	for i := 0; i < maxSerialNumberAttempts; i++ {
+		serialNumber, err := serials.Next()
+		if err != nil {
+			return err
+		}
+
+		taken, err := serialNumberTaken(repo, serialNumber)
+		if err != nil {
+			return err
+		}
+		if taken {
+			continue
+		}
+
+		err = issue(serialNumber)
+		if err != ErrorDuplicateObject {
+			return err
+		}
+
+		// Only a duplicate serial number is solved by another attempt
+		taken, err = serialNumberTaken(repo, serialNumber)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			return ErrorDuplicateObject
+		}
+	}
+
+	return ErrorDuplicateObject
+}
+
+// serialNumberTaken checks if a certificate with the serial number is stored in the repository
+func serialNumberTaken(repo CertificateRepository, serialNumber *big.Int) (bool, error) {
+	_, err := repo.GetBySerialNumber(serialNumber)
+	switch err {
+	case nil:
+		return true, nil
+	case ErrorObjectNotFound:
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,64 @@
+package sql
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+	"time"
+)
+
+// GetAccountRepository returns a bootstrapped account repository
+func (sqldb *sqlDB) GetAccountRepository() database.AccountRepository {
+	return &AccountRepository{sqldb}
+}
+
+// AccountRepository implements the AccountRepository interface from database package
+type AccountRepository struct {
+	sqldb *sqlDB
+}
+
+// GetByUUID gets an account by UUID
+func (repo *AccountRepository) GetByUUID(uuid string) (*database.Account, error) {
+	account := &Account{}
+	if err := repo.sqldb.conn.Where("uuid = ?", uuid).First(account).Error; err != nil {
+		return nil, GetError(err)
+	}
+	return &account.Account, nil
+}
+
+// GetByKeyID gets an account by the thumbprint of its key
+func (repo *AccountRepository) GetByKeyID(keyID string) (*database.Account, error) {
+	account := &Account{}
+	if err := repo.sqldb.conn.Where("key_id = ?", keyID).First(account).Error; err != nil {
+		return nil, GetError(err)
+	}
+	return &account.Account, nil
+}
+
+// Create creates an account
+func (repo *AccountRepository) Create(account *database.Account) error {
+	if err := repo.sqldb.conn.Create(&Account{Account: *account}).Error; err != nil {
+		return GetError(err)
+	}
+	return nil
+}
+
+// Update updates the account with the UUID of the given account
+func (repo *AccountRepository) Update(account *database.Account) error {
+	existing := &Account{}
+	if err := repo.sqldb.conn.Where("uuid = ?", account.UUID).First(existing).Error; err != nil {
+		return GetError(err)
+	}
+
+	account.UpdatedAt = time.Now()
+	existing.Account = *account
+
+	if err := repo.sqldb.conn.Save(existing).Error; err != nil {
+		return GetError(err)
+	}
+	return nil
+}
+
+// Account is the implementation for the Account struct in the database package
+type Account struct {
+	GormModel
+	database.Account
+}
@@ -0,0 +1,14 @@
+package sql
+
+import (
+	"github.com/mvmaasakkers/certificates/database/test"
+	"testing"
+)
+
+func TestAccount(t *testing.T) {
+	test.TestAccount(t, testDB.GetAccountRepository())
+}
+
+func TestOrder(t *testing.T) {
+	test.TestOrder(t, testDB.GetOrderRepository())
+}
@@ -1,7 +1,11 @@
 package sql
 
 import (
+	"github.com/mvmaasakkers/certificates/cert"
 	"github.com/mvmaasakkers/certificates/database"
+	"math"
+	"math/big"
+	"time"
 )
 
 // GetCertificateRepository returns a bootstrapped certificate repository
@@ -16,17 +20,164 @@ type CertificateRepository struct {
 
 // GetByNameSerialNumber gets a certificate by NameSerialNumber
 func (repo *CertificateRepository) GetByNameSerialNumber(nameSerialNumber string) (*database.Certificate, error) {
-	crt := &database.Certificate{}
+	crt := &Certificate{}
 	if err := repo.sqldb.conn.Where("name_serial_number = ?", nameSerialNumber).First(crt).Error; err != nil {
 		return nil, GetError(err)
 	}
-	return crt, nil
+	return crt.toDatabase(), nil
 }
 
-// Create creates a certificate
+// GetBySerialNumber gets a certificate by SerialNumber
+func (repo *CertificateRepository) GetBySerialNumber(serialNumber *big.Int) (*database.Certificate, error) {
+	if serialNumber == nil {
+		return nil, database.ErrorObjectNotFound
+	}
+
+	crt := &Certificate{}
+	if err := repo.sqldb.conn.Where("serial_number = ?", serialNumber.String()).First(crt).Error; err != nil {
+		return nil, GetError(err)
+	}
+	return crt.toDatabase(), nil
+}
+
+// GetRevoked gets all revoked certificates
+func (repo *CertificateRepository) GetRevoked() ([]*database.Certificate, error) {
+	crts := []*Certificate{}
+	if err := repo.sqldb.conn.Where("status = ?", database.StatusRevoked).Find(&crts).Error; err != nil {
+		return nil, GetError(err)
+	}
+
+	revoked := make([]*database.Certificate, 0, len(crts))
+	for _, crt := range crts {
+		revoked = append(revoked, crt.toDatabase())
+	}
+	return revoked, nil
+}
+
+// List lists the certificates matching the given filter
+func (repo *CertificateRepository) List(filter database.CertificateFilter) ([]*database.Certificate, error) {
+	query := repo.sqldb.conn.Order("created_at asc, id asc")
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.CommonName != "" {
+		query = query.Where("common_name LIKE ?", "%"+filter.CommonName+"%")
+	}
+	if filter.ExpiresBefore != nil {
+		query = query.Where("expiration_date < ?", *filter.ExpiresBefore)
+	}
+	if filter.IssuedAfter != nil {
+		query = query.Where("created_at > ?", *filter.IssuedAfter)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		// An OFFSET is only allowed in combination with a LIMIT
+		if filter.Limit <= 0 {
+			query = query.Limit(math.MaxInt64)
+		}
+		query = query.Offset(filter.Offset)
+	}
+
+	crts := []*Certificate{}
+	if err := query.Find(&crts).Error; err != nil {
+		return nil, GetError(err)
+	}
+
+	list := make([]*database.Certificate, 0, len(crts))
+	for _, crt := range crts {
+		list = append(list, crt.toDatabase())
+	}
+	return list, nil
+}
+
+// Create creates a certificate. Both the SerialNumber and the NameSerialNumber have to be unique.
 func (repo *CertificateRepository) Create(certificate *database.Certificate) error {
+	if certificate.SerialNumber == nil {
+		return database.ErrorMissingSerialNumber
+	}
 
-	if err := repo.sqldb.conn.Create(certificate).Error; err != nil {
+	crt := newCertificate(certificate)
+	if err := repo.sqldb.conn.Create(crt).Error; err != nil {
+		return GetError(err)
+	}
+	return nil
+}
+
+// Revoke marks the certificate with the given SerialNumber as revoked with the given RFC 5280 reason code
+func (repo *CertificateRepository) Revoke(serialNumber *big.Int, reason int, revocationDate time.Time) error {
+	if serialNumber == nil {
+		return database.ErrorObjectNotFound
+	}
+
+	crt := &Certificate{}
+	if err := repo.sqldb.conn.Where("serial_number = ?", serialNumber.String()).First(crt).Error; err != nil {
+		return GetError(err)
+	}
+
+	if crt.Status == database.StatusRevoked {
+		return database.ErrorAlreadyRevoked
+	}
+
+	crt.Status = database.StatusRevoked
+	crt.RevocationDate = &revocationDate
+	crt.RevocationReason = reason
+	crt.UpdatedAt = time.Now()
+
+	if err := repo.sqldb.conn.Save(crt).Error; err != nil {
+		return GetError(err)
+	}
+	return nil
+}
+
+// CreateRenewal creates the renewal of the certificate with the given SerialNumber and links both certificates. The
+// renewal has to be unique like in Create. With revoke the renewed certificate is revoked as superseded in the same
+// transaction, ErrorAlreadyRevoked is returned when it already is revoked.
+func (repo *CertificateRepository) CreateRenewal(serialNumber *big.Int, renewal *database.Certificate, revoke bool) error {
+	if serialNumber == nil {
+		return database.ErrorObjectNotFound
+	}
+	if renewal.SerialNumber == nil {
+		return database.ErrorMissingSerialNumber
+	}
+
+	tx := repo.sqldb.conn.Begin()
+	if tx.Error != nil {
+		return GetError(tx.Error)
+	}
+
+	crt := &Certificate{}
+	if err := tx.Where("serial_number = ?", serialNumber.String()).First(crt).Error; err != nil {
+		tx.Rollback()
+		return GetError(err)
+	}
+	if revoke && crt.Status == database.StatusRevoked {
+		tx.Rollback()
+		return database.ErrorAlreadyRevoked
+	}
+
+	renewal.Renews = serialNumber.String()
+	if err := tx.Create(newCertificate(renewal)).Error; err != nil {
+		tx.Rollback()
+		return GetError(err)
+	}
+
+	crt.RenewedBy = renewal.SerialNumber.String()
+	crt.UpdatedAt = time.Now()
+	if revoke {
+		revocationDate := crt.UpdatedAt
+		crt.Status = database.StatusRevoked
+		crt.RevocationDate = &revocationDate
+		crt.RevocationReason = cert.ReasonSuperseded
+	}
+	if err := tx.Save(crt).Error; err != nil {
+		tx.Rollback()
+		return GetError(err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return GetError(err)
 	}
 	return nil
@@ -34,11 +185,41 @@ func (repo *CertificateRepository) Create(certificate *database.Certificate) err
 
 // DeleteByNameSerialNumber deletes a certificate by NameSerialNumber
 func (repo *CertificateRepository) DeleteByNameSerialNumber(nameSerialNumber string) error {
+	result := repo.sqldb.conn.Where("name_serial_number = ?", nameSerialNumber).Delete(&Certificate{})
+	if result.Error != nil {
+		return GetError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return database.ErrorObjectNotFound
+	}
 	return nil
 }
 
 // Certificate is the implementation for the Certificate struct in the database package
+//
+// The SerialNumber of the database.Certificate is stored in decimal notation as Serial, as gorm is unable to map
+// a big.Int. A nil SerialNumber is stored as NULL.
 type Certificate struct {
 	GormModel
 	database.Certificate
+	Serial *string `gorm:"column:serial_number;unique"`
+}
+
+// newCertificate wraps a database.Certificate for storage
+func newCertificate(certificate *database.Certificate) *Certificate {
+	crt := &Certificate{Certificate: *certificate}
+	if certificate.SerialNumber != nil {
+		serial := certificate.SerialNumber.String()
+		crt.Serial = &serial
+	}
+	return crt
+}
+
+// toDatabase unwraps the stored certificate into a database.Certificate
+func (crt *Certificate) toDatabase() *database.Certificate {
+	certificate := crt.Certificate
+	if crt.Serial != nil {
+		certificate.SerialNumber, _ = new(big.Int).SetString(*crt.Serial, 10)
+	}
+	return &certificate
 }
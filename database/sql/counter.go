@@ -0,0 +1,68 @@
+package sql
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/mvmaasakkers/certificates/database"
+)
+
+// GetCounterRepository returns a bootstrapped counter repository
+func (sqldb *sqlDB) GetCounterRepository() database.CounterRepository {
+	return &CounterRepository{sqldb}
+}
+
+// CounterRepository implements the CounterRepository interface from database package
+type CounterRepository struct {
+	sqldb *sqlDB
+}
+
+// Next increments the counter with the given name and returns the new value. The counter is incremented in the
+// database so concurrent calls get different values. A counter is created on first use, when a concurrent call
+// creates it first the increment is retried once.
+func (repo *CounterRepository) Next(name string) (int64, error) {
+	value, err := repo.next(name)
+	if err == database.ErrorDuplicateObject {
+		value, err = repo.next(name)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// next increments or creates the counter in a transaction and returns the new value
+func (repo *CounterRepository) next(name string) (int64, error) {
+	var value int64
+
+	err := repo.sqldb.conn.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Counter{}).Where("name = ?", name).UpdateColumn("value", gorm.Expr("value + ?", 1))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			value = 1
+			return tx.Create(&Counter{Name: name, Value: value}).Error
+		}
+
+		counter := &Counter{}
+		if err := tx.Where("name = ?", name).First(counter).Error; err != nil {
+			return err
+		}
+
+		value = counter.Value
+		return nil
+	})
+	if err != nil {
+		return 0, GetError(err)
+	}
+
+	return value, nil
+}
+
+// Counter is the model for persistent counters
+type Counter struct {
+	GormModel
+	Name  string `gorm:"unique"`
+	Value int64
+}
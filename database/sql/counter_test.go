@@ -0,0 +1,14 @@
+package sql
+
+import (
+	"github.com/mvmaasakkers/certificates/database/test"
+	"testing"
+)
+
+func TestCounterNext(t *testing.T) {
+	test.TestCounterNext(t, testDB.GetCounterRepository())
+}
+
+func TestSerialNumberSequential(t *testing.T) {
+	test.TestSerialNumberSequential(t, testDB.GetCounterRepository())
+}
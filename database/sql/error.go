@@ -1,10 +1,19 @@
 package sql
 
 import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	"github.com/mvmaasakkers/certificates/database"
 )
 
+// Error codes of unique constraint violations
+const (
+	mysqlDuplicateEntry     = 1062
+	postgresUniqueViolation = "23505"
+)
+
 // GetError translates gorm errors into database package errors
 func GetError(err error) error {
 	switch err {
@@ -12,6 +21,16 @@ func GetError(err error) error {
 		return database.ErrorObjectNotFound
 	}
 
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return database.ErrorDuplicateObject
+	}
+
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) && postgresErr.Code == postgresUniqueViolation {
+		return database.ErrorDuplicateObject
+	}
+
 	chUnique := "UNIQUE constraint failed"
 
 	if len(err.Error()) >= len(chUnique) && err.Error()[:len(chUnique)] == chUnique {
@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/mvmaasakkers/certificates/database/test"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetError(t *testing.T) {
+	other := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "not_found", err: gorm.ErrRecordNotFound, want: database.ErrorObjectNotFound},
+		{name: "sqlite_unique", err: errors.New("UNIQUE constraint failed: certificates.serial_number"), want: database.ErrorDuplicateObject},
+		{name: "mysql_duplicate_entry", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '42' for key 'serial_number'"}, want: database.ErrorDuplicateObject},
+		{name: "mysql_other", err: &mysql.MySQLError{Number: 1045, Message: "Access denied"}},
+		{name: "postgres_unique_violation", err: &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}, want: database.ErrorDuplicateObject},
+		{name: "postgres_other", err: &pq.Error{Code: "23502", Message: "null value violates not-null constraint"}},
+		{name: "other", err: other, want: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == nil {
+				want = tt.err
+			}
+			if got := GetError(tt.err); got != want {
+				t.Errorf("GetError() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestSerialNumberUniqueDriverErrors runs the serial number retry through the repository with the unique constraint
+// violations reported the way MySQL and PostgreSQL do
+func TestSerialNumberUniqueDriverErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "mysql", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}},
+		{name: "postgres", err: &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewDB("sqlite3", filepath.Join(t.TempDir(), "driver.db"))
+			if err := db.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if err := db.Provision(); err != nil {
+				t.Fatal(err)
+			}
+
+			db.(*sqlDB).conn.Callback().Create().After("gorm:create").Register("test:driver_error", func(scope *gorm.Scope) {
+				if scope.HasError() {
+					scope.DB().Error = tt.err
+				}
+			})
+
+			test.TestSerialNumberUnique(t, db.GetCertificateRepository())
+		})
+	}
+}
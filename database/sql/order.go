@@ -0,0 +1,55 @@
+package sql
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+	"time"
+)
+
+// GetOrderRepository returns a bootstrapped order repository
+func (sqldb *sqlDB) GetOrderRepository() database.OrderRepository {
+	return &OrderRepository{sqldb}
+}
+
+// OrderRepository implements the OrderRepository interface from database package
+type OrderRepository struct {
+	sqldb *sqlDB
+}
+
+// GetByUUID gets an order by UUID
+func (repo *OrderRepository) GetByUUID(uuid string) (*database.Order, error) {
+	order := &Order{}
+	if err := repo.sqldb.conn.Where("uuid = ?", uuid).First(order).Error; err != nil {
+		return nil, GetError(err)
+	}
+	return &order.Order, nil
+}
+
+// Create creates an order
+func (repo *OrderRepository) Create(order *database.Order) error {
+	if err := repo.sqldb.conn.Create(&Order{Order: *order}).Error; err != nil {
+		return GetError(err)
+	}
+	return nil
+}
+
+// Update updates the order with the UUID of the given order
+func (repo *OrderRepository) Update(order *database.Order) error {
+	existing := &Order{}
+	if err := repo.sqldb.conn.Where("uuid = ?", order.UUID).First(existing).Error; err != nil {
+		return GetError(err)
+	}
+
+	order.UpdatedAt = time.Now()
+	existing.Order = *order
+
+	if err := repo.sqldb.conn.Save(existing).Error; err != nil {
+		return GetError(err)
+	}
+	return nil
+}
+
+// Order is the implementation for the Order struct in the database package
+type Order struct {
+	GormModel
+	database.Order
+}
@@ -10,5 +10,5 @@ import (
 
 func (sqldb *sqlDB) Provision() error {
 
-	return sqldb.conn.AutoMigrate(&Certificate{}).Error
+	return sqldb.conn.AutoMigrate(&Certificate{}, &Counter{}, &Account{}, &Order{}).Error
 }
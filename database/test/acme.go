@@ -0,0 +1,90 @@
+package test
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+	"testing"
+	"time"
+)
+
+// TestAccount tests
+func TestAccount(t *testing.T, accountRepository database.AccountRepository) {
+	account := database.NewAccount()
+	account.KeyID = "account-key-id"
+	account.JWK = `{"kty":"EC","crv":"P-256","x":"x","y":"y"}`
+	account.Contact = "mailto:admin@test.id"
+	account.Status = "valid"
+
+	if err := accountRepository.Create(account); err != nil {
+		t.Errorf("create: expected error %+v, got error %+v", nil, err)
+		return
+	}
+
+	duplicate := database.NewAccount()
+	duplicate.KeyID = account.KeyID
+	if err := accountRepository.Create(duplicate); err != database.ErrorDuplicateObject {
+		t.Errorf("duplicate: expected error %+v, got error %+v", database.ErrorDuplicateObject, err)
+	}
+
+	got, err := accountRepository.GetByKeyID(account.KeyID)
+	if err != nil || got.UUID != account.UUID || got.JWK != account.JWK || got.Contact != account.Contact {
+		t.Errorf("get by key id: expected %+v, got %+v (error %+v)", account, got, err)
+	}
+
+	account.Status = "deactivated"
+	if err := accountRepository.Update(account); err != nil {
+		t.Errorf("update: expected error %+v, got error %+v", nil, err)
+	}
+
+	got, err = accountRepository.GetByUUID(account.UUID)
+	if err != nil || got.Status != "deactivated" {
+		t.Errorf("get by uuid: expected status %s, got %+v (error %+v)", "deactivated", got, err)
+	}
+
+	if _, err := accountRepository.GetByUUID("notfound"); err != database.ErrorObjectNotFound {
+		t.Errorf("notfound: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+	if _, err := accountRepository.GetByKeyID("notfound"); err != database.ErrorObjectNotFound {
+		t.Errorf("notfound: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+	if err := accountRepository.Update(database.NewAccount()); err != database.ErrorObjectNotFound {
+		t.Errorf("update notfound: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+}
+
+// TestOrder tests
+func TestOrder(t *testing.T, orderRepository database.OrderRepository) {
+	order := database.NewOrder()
+	order.AccountUUID = "account-uuid"
+	order.Status = "pending"
+	order.Identifiers = "test.id,www.test.id"
+	order.Expires = time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	order.Authorizations = `[{"identifier":"test.id"}]`
+
+	if err := orderRepository.Create(order); err != nil {
+		t.Errorf("create: expected error %+v, got error %+v", nil, err)
+		return
+	}
+
+	got, err := orderRepository.GetByUUID(order.UUID)
+	if err != nil || got.Identifiers != order.Identifiers || got.Authorizations != order.Authorizations || !got.Expires.Equal(order.Expires) {
+		t.Errorf("get: expected %+v, got %+v (error %+v)", order, got, err)
+	}
+
+	order.Status = "valid"
+	order.CertificateSerialNumber = "1001"
+	if err := orderRepository.Update(order); err != nil {
+		t.Errorf("update: expected error %+v, got error %+v", nil, err)
+	}
+
+	got, err = orderRepository.GetByUUID(order.UUID)
+	if err != nil || got.Status != "valid" || got.CertificateSerialNumber != "1001" {
+		t.Errorf("get updated: expected %+v, got %+v (error %+v)", order, got, err)
+	}
+
+	if _, err := orderRepository.GetByUUID("notfound"); err != database.ErrorObjectNotFound {
+		t.Errorf("notfound: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+	if err := orderRepository.Update(database.NewOrder()); err != database.ErrorObjectNotFound {
+		t.Errorf("update notfound: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+}
@@ -1,8 +1,12 @@
 package test
 
 import (
+	"github.com/mvmaasakkers/certificates/cert"
 	"github.com/mvmaasakkers/certificates/database"
+	"math/big"
+	"strings"
 	"testing"
+	"time"
 )
 
 var certificateTests = []struct {
@@ -33,17 +37,53 @@ func TestCertificateCertificate(t *testing.T, certificateRepository database.Cer
 	}
 }
 
+var serialNumberCertificateTests = []struct {
+	ID           string
+	Error        error
+	SerialNumber *big.Int
+}{
+	{
+		ID:           "test.id",
+		Error:        nil,
+		SerialNumber: big.NewInt(1001),
+	},
+	{
+		ID:           "testnotfound",
+		Error:        database.ErrorObjectNotFound,
+		SerialNumber: big.NewInt(9999),
+	},
+	{
+		ID:           "testnil",
+		Error:        database.ErrorObjectNotFound,
+		SerialNumber: nil,
+	},
+}
+
+// TestCertificateBySerialNumber tests
+func TestCertificateBySerialNumber(t *testing.T, certificateRepository database.CertificateRepository) {
+	for _, test := range serialNumberCertificateTests {
+		crt, err := certificateRepository.GetBySerialNumber(test.SerialNumber)
+		if err != test.Error {
+			t.Errorf("%s: expected error %+v, got error %+v", test.ID, test.Error, err)
+			continue
+		}
+		if err == nil && crt.CommonName != test.ID {
+			t.Errorf("%s: expected common name %s, got %s", test.ID, test.ID, crt.CommonName)
+		}
+	}
+}
+
 var createCertificateTests = []struct {
 	Error       error
 	DeleteError error
 	Certificate *database.Certificate
 }{
 	{
-		Certificate: &database.Certificate{CommonName: "test.id", NameSerialNumber: "testserial"},
+		Certificate: &database.Certificate{CommonName: "test.id", NameSerialNumber: "testserial", SerialNumber: big.NewInt(1001)},
 		Error:       database.ErrorDuplicateObject,
 	},
 	{
-		Certificate: &database.Certificate{CommonName: "testid_2", NameSerialNumber: "two"},
+		Certificate: &database.Certificate{CommonName: "testid_2", NameSerialNumber: "two", SerialNumber: big.NewInt(1002)},
 		Error:       nil,
 	},
 }
@@ -63,3 +103,362 @@ func TestCertificateCreateCertificate(t *testing.T, certificateRepository databa
 		}
 	}
 }
+
+var revokeCertificateTests = []struct {
+	ID           string
+	Error        error
+	SerialNumber *big.Int
+	Reason       int
+}{
+	{
+		ID:           "revoke",
+		Error:        nil,
+		SerialNumber: big.NewInt(1003),
+		Reason:       1,
+	},
+	{
+		ID:           "already_revoked",
+		Error:        database.ErrorAlreadyRevoked,
+		SerialNumber: big.NewInt(1003),
+		Reason:       1,
+	},
+	{
+		ID:           "notfound",
+		Error:        database.ErrorObjectNotFound,
+		SerialNumber: big.NewInt(9999),
+		Reason:       1,
+	},
+}
+
+// TestCertificateRevoke tests
+func TestCertificateRevoke(t *testing.T, certificateRepository database.CertificateRepository) {
+	revocationDate := time.Now().UTC().Truncate(time.Second)
+	for _, test := range revokeCertificateTests {
+		err := certificateRepository.Revoke(test.SerialNumber, test.Reason, revocationDate)
+		if err != test.Error {
+			t.Errorf("%s: expected error %+v, got error %+v", test.ID, test.Error, err)
+		}
+	}
+
+	crt, err := certificateRepository.GetByNameSerialNumber("revokeserial")
+	if err != nil {
+		t.Errorf("revoked: expected error %+v, got error %+v", nil, err)
+		return
+	}
+
+	if crt.Status != database.StatusRevoked {
+		t.Errorf("revoked: expected status %s, got %s", database.StatusRevoked, crt.Status)
+	}
+	if crt.RevocationReason != 1 {
+		t.Errorf("revoked: expected reason %d, got %d", 1, crt.RevocationReason)
+	}
+	if crt.RevocationDate == nil || !crt.RevocationDate.Equal(revocationDate) {
+		t.Errorf("revoked: expected revocation date %s, got %v", revocationDate, crt.RevocationDate)
+	}
+	if crt.SerialNumber == nil || crt.SerialNumber.Cmp(big.NewInt(1003)) != 0 {
+		t.Errorf("revoked: expected serial number %d, got %s", 1003, crt.SerialNumber)
+	}
+
+	revoked, err := certificateRepository.GetRevoked()
+	if err != nil {
+		t.Errorf("get revoked: expected error %+v, got error %+v", nil, err)
+		return
+	}
+	if len(revoked) != 1 || revoked[0].NameSerialNumber != "revokeserial" {
+		t.Errorf("get revoked: expected only %s, got %+v", "revokeserial", revoked)
+	}
+}
+
+var deleteCertificateTests = []struct {
+	ID               string
+	Error            error
+	NameSerialNumber string
+}{
+	{
+		ID:               "delete",
+		Error:            nil,
+		NameSerialNumber: "deleteserial",
+	},
+	{
+		ID:               "already_deleted",
+		Error:            database.ErrorObjectNotFound,
+		NameSerialNumber: "deleteserial",
+	},
+}
+
+// TestCertificateDelete tests
+func TestCertificateDelete(t *testing.T, certificateRepository database.CertificateRepository) {
+	for _, test := range deleteCertificateTests {
+		if err := certificateRepository.DeleteByNameSerialNumber(test.NameSerialNumber); err != test.Error {
+			t.Errorf("%s: expected error %+v, got error %+v", test.ID, test.Error, err)
+		}
+	}
+
+	if _, err := certificateRepository.GetByNameSerialNumber("deleteserial"); err != database.ErrorObjectNotFound {
+		t.Errorf("deleted: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+}
+
+var listCertificateTests = []struct {
+	ID     string
+	Filter database.CertificateFilter
+	Want   []string
+}{
+	{
+		ID:     "all",
+		Filter: database.CertificateFilter{CommonName: "list.test.id"},
+		Want:   []string{"listserial1", "listserial2", "listserial3"},
+	},
+	{
+		ID:     "status",
+		Filter: database.CertificateFilter{CommonName: "list.test.id", Status: database.StatusRevoked},
+		Want:   []string{"listserial2"},
+	},
+	{
+		ID:     "common_name",
+		Filter: database.CertificateFilter{CommonName: "b.list.test"},
+		Want:   []string{"listserial2"},
+	},
+	{
+		ID:     "expires_before",
+		Filter: database.CertificateFilter{CommonName: "list.test.id", ExpiresBefore: timeRef(listBase.AddDate(0, 0, 15))},
+		Want:   []string{"listserial1", "listserial2"},
+	},
+	{
+		ID:     "issued_after",
+		Filter: database.CertificateFilter{CommonName: "list.test.id", IssuedAfter: timeRef(listBase.Add(90 * time.Minute))},
+		Want:   []string{"listserial2", "listserial3"},
+	},
+	{
+		ID:     "limit",
+		Filter: database.CertificateFilter{CommonName: "list.test.id", Limit: 2},
+		Want:   []string{"listserial1", "listserial2"},
+	},
+	{
+		ID:     "offset",
+		Filter: database.CertificateFilter{CommonName: "list.test.id", Offset: 1},
+		Want:   []string{"listserial2", "listserial3"},
+	},
+	{
+		ID:     "offset_limit",
+		Filter: database.CertificateFilter{CommonName: "list.test.id", Offset: 1, Limit: 1},
+		Want:   []string{"listserial2"},
+	},
+	{
+		ID:     "offset_out_of_range",
+		Filter: database.CertificateFilter{CommonName: "list.test.id", Offset: 5},
+		Want:   []string{},
+	},
+	{
+		ID:     "no_match",
+		Filter: database.CertificateFilter{CommonName: "notfound.list.test.id"},
+		Want:   []string{},
+	},
+}
+
+var listBase = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func timeRef(t time.Time) *time.Time {
+	return &t
+}
+
+// TestCertificateList tests
+func TestCertificateList(t *testing.T, certificateRepository database.CertificateRepository) {
+	list := []*database.Certificate{
+		{Meta: database.Meta{CreatedAt: listBase.Add(time.Hour)}, CommonName: "a.list.test.id", NameSerialNumber: "listserial1", SerialNumber: big.NewInt(1101), Status: database.StatusValid, ExpirationDate: listBase.AddDate(0, 0, 10)},
+		{Meta: database.Meta{CreatedAt: listBase.Add(2 * time.Hour)}, CommonName: "b.list.test.id", NameSerialNumber: "listserial2", SerialNumber: big.NewInt(1102), Status: database.StatusRevoked, ExpirationDate: listBase.AddDate(0, 0, 10)},
+		{Meta: database.Meta{CreatedAt: listBase.Add(3 * time.Hour)}, CommonName: "c.list.test.id", NameSerialNumber: "listserial3", SerialNumber: big.NewInt(1103), Status: database.StatusValid, ExpirationDate: listBase.AddDate(0, 0, 20)},
+	}
+
+	// Created in reverse order to verify the ordering of the results
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := certificateRepository.Create(list[i]); err != nil {
+			t.Errorf("%s: expected error %+v, got error %+v", list[i].NameSerialNumber, nil, err)
+		}
+	}
+	defer func() {
+		for _, crt := range list {
+			certificateRepository.DeleteByNameSerialNumber(crt.NameSerialNumber)
+		}
+	}()
+
+	for _, test := range listCertificateTests {
+		crts, err := certificateRepository.List(test.Filter)
+		if err != nil {
+			t.Errorf("%s: expected error %+v, got error %+v", test.ID, nil, err)
+			continue
+		}
+
+		got := []string{}
+		for _, crt := range crts {
+			got = append(got, crt.NameSerialNumber)
+		}
+
+		if strings.Join(got, ",") != strings.Join(test.Want, ",") {
+			t.Errorf("%s: expected %v, got %v", test.ID, test.Want, got)
+		}
+	}
+}
+
+// TestCertificateDetails tests
+func TestCertificateDetails(t *testing.T, certificateRepository database.CertificateRepository) {
+	crt := database.NewCertificate()
+	crt.CommonName = "details.test.id"
+	crt.NameSerialNumber = "detailsserial"
+	crt.SerialNumber = big.NewInt(1201)
+	crt.Status = database.StatusValid
+	crt.CertificatePEM = "-----BEGIN CERTIFICATE-----\nZGV0YWlscw==\n-----END CERTIFICATE-----\n"
+	crt.SubjectAltNames = "details.test.id,www.details.test.id"
+	crt.Issuer = "CN=ca.test.id"
+	crt.KeyAlgorithm = "ECDSA-P-256"
+	crt.Fingerprint = "a4f0e1b4"
+	crt.SubjectKeyID = "0b1c"
+
+	if err := certificateRepository.Create(crt); err != nil {
+		t.Errorf("details: expected error %+v, got error %+v", nil, err)
+		return
+	}
+	defer certificateRepository.DeleteByNameSerialNumber(crt.NameSerialNumber)
+
+	got, err := certificateRepository.GetBySerialNumber(crt.SerialNumber)
+	if err != nil {
+		t.Errorf("details: expected error %+v, got error %+v", nil, err)
+		return
+	}
+
+	if got.CertificatePEM != crt.CertificatePEM || got.SubjectAltNames != crt.SubjectAltNames || got.Issuer != crt.Issuer ||
+		got.KeyAlgorithm != crt.KeyAlgorithm || got.Fingerprint != crt.Fingerprint || got.SubjectKeyID != crt.SubjectKeyID {
+		t.Errorf("details: expected %+v, got %+v", crt, got)
+	}
+}
+
+var duplicateCertificateTests = []struct {
+	ID          string
+	Error       error
+	Certificate *database.Certificate
+}{
+	{
+		ID:          "original",
+		Error:       nil,
+		Certificate: &database.Certificate{CommonName: "duplicate.test.id", NameSerialNumber: "duplicateserial", SerialNumber: big.NewInt(1301)},
+	},
+	{
+		ID:          "same_common_name",
+		Error:       nil,
+		Certificate: &database.Certificate{CommonName: "duplicate.test.id", NameSerialNumber: "duplicateserial2", SerialNumber: big.NewInt(1302)},
+	},
+	{
+		ID:          "duplicate_serial_number",
+		Error:       database.ErrorDuplicateObject,
+		Certificate: &database.Certificate{CommonName: "other.duplicate.test.id", NameSerialNumber: "duplicateserial3", SerialNumber: big.NewInt(1301)},
+	},
+	{
+		ID:          "duplicate_name_serial_number",
+		Error:       database.ErrorDuplicateObject,
+		Certificate: &database.Certificate{CommonName: "other.duplicate.test.id", NameSerialNumber: "duplicateserial", SerialNumber: big.NewInt(1303)},
+	},
+	{
+		ID:          "missing_serial_number",
+		Error:       database.ErrorMissingSerialNumber,
+		Certificate: &database.Certificate{CommonName: "other.duplicate.test.id", NameSerialNumber: "duplicateserial4"},
+	},
+}
+
+// TestCertificateCreateDuplicate tests
+func TestCertificateCreateDuplicate(t *testing.T, certificateRepository database.CertificateRepository) {
+	defer func() {
+		certificateRepository.DeleteByNameSerialNumber("duplicateserial")
+		certificateRepository.DeleteByNameSerialNumber("duplicateserial2")
+	}()
+
+	for _, test := range duplicateCertificateTests {
+		if err := certificateRepository.Create(test.Certificate); err != test.Error {
+			t.Errorf("%s: expected error %+v, got error %+v", test.ID, test.Error, err)
+		}
+	}
+
+	crt, err := certificateRepository.GetBySerialNumber(big.NewInt(1301))
+	if err != nil || crt.NameSerialNumber != "duplicateserial" {
+		t.Errorf("original: expected %s, got %+v (error %+v)", "duplicateserial", crt, err)
+	}
+}
+
+// TestCertificateRenew tests
+func TestCertificateRenew(t *testing.T, certificateRepository database.CertificateRepository) {
+	original := &database.Certificate{CommonName: "renew.test.id", NameSerialNumber: "renewserial", SerialNumber: big.NewInt(1401), Status: database.StatusValid, Profile: "server"}
+	if err := certificateRepository.Create(original); err != nil {
+		t.Errorf("create: expected error %+v, got error %+v", nil, err)
+		return
+	}
+	defer func() {
+		certificateRepository.DeleteByNameSerialNumber("renewserial")
+		certificateRepository.DeleteByNameSerialNumber("renewserial2")
+	}()
+
+	tests := []struct {
+		ID           string
+		Error        error
+		SerialNumber *big.Int
+		Renewal      *database.Certificate
+		Revoke       bool
+	}{
+		{
+			ID:           "renew",
+			Error:        nil,
+			SerialNumber: big.NewInt(1401),
+			Renewal:      &database.Certificate{CommonName: "renew.test.id", NameSerialNumber: "renewserial2", SerialNumber: big.NewInt(1402), Status: database.StatusValid},
+			Revoke:       true,
+		},
+		{
+			ID:           "already_revoked",
+			Error:        database.ErrorAlreadyRevoked,
+			SerialNumber: big.NewInt(1401),
+			Renewal:      &database.Certificate{CommonName: "renew.test.id", NameSerialNumber: "renewserial6", SerialNumber: big.NewInt(1403)},
+			Revoke:       true,
+		},
+		{
+			ID:           "duplicate_renewal",
+			Error:        database.ErrorDuplicateObject,
+			SerialNumber: big.NewInt(1401),
+			Renewal:      &database.Certificate{CommonName: "renew.test.id", NameSerialNumber: "renewserial3", SerialNumber: big.NewInt(1402)},
+		},
+		{
+			ID:           "not_found",
+			Error:        database.ErrorObjectNotFound,
+			SerialNumber: big.NewInt(1499),
+			Renewal:      &database.Certificate{CommonName: "renew.test.id", NameSerialNumber: "renewserial4", SerialNumber: big.NewInt(1404)},
+		},
+		{
+			ID:           "missing_serial_number",
+			Error:        database.ErrorMissingSerialNumber,
+			SerialNumber: big.NewInt(1401),
+			Renewal:      &database.Certificate{CommonName: "renew.test.id", NameSerialNumber: "renewserial5"},
+		},
+	}
+	for _, test := range tests {
+		if err := certificateRepository.CreateRenewal(test.SerialNumber, test.Renewal, test.Revoke); err != test.Error {
+			t.Errorf("%s: expected error %+v, got error %+v", test.ID, test.Error, err)
+		}
+	}
+
+	crt, err := certificateRepository.GetBySerialNumber(big.NewInt(1401))
+	if err != nil || crt.RenewedBy != "1402" || crt.Renews != "" || crt.Profile != "server" {
+		t.Errorf("renewed: expected renewed by %s, got %+v (error %+v)", "1402", crt, err)
+	}
+	if err == nil && (crt.Status != database.StatusRevoked || crt.RevocationReason != cert.ReasonSuperseded || crt.RevocationDate == nil) {
+		t.Errorf("renewed: expected revoked as superseded, got %+v", crt)
+	}
+
+	crt, err = certificateRepository.GetBySerialNumber(big.NewInt(1402))
+	if err != nil || crt.Renews != "1401" || crt.RenewedBy != "" {
+		t.Errorf("renewal: expected renewal of %s, got %+v (error %+v)", "1401", crt, err)
+	}
+
+	if _, err := certificateRepository.GetByNameSerialNumber("renewserial3"); err != database.ErrorObjectNotFound {
+		t.Errorf("duplicate_renewal: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+
+	if _, err := certificateRepository.GetBySerialNumber(big.NewInt(1403)); err != database.ErrorObjectNotFound {
+		t.Errorf("already_revoked: expected error %+v, got error %+v", database.ErrorObjectNotFound, err)
+	}
+}
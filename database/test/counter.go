@@ -0,0 +1,29 @@
+package test
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+	"testing"
+)
+
+// TestCounterNext tests
+func TestCounterNext(t *testing.T, counterRepository database.CounterRepository) {
+	for i := int64(1); i <= 3; i++ {
+		value, err := counterRepository.Next("test_counter")
+		if err != nil {
+			t.Errorf("test_counter: expected error %+v, got error %+v", nil, err)
+			return
+		}
+		if value != i {
+			t.Errorf("test_counter: expected value %d, got %d", i, value)
+		}
+	}
+
+	value, err := counterRepository.Next("other_counter")
+	if err != nil {
+		t.Errorf("other_counter: expected error %+v, got error %+v", nil, err)
+		return
+	}
+	if value != 1 {
+		t.Errorf("other_counter: expected value %d, got %d", 1, value)
+	}
+}
@@ -3,6 +3,7 @@ package test
 import (
 	"github.com/mvmaasakkers/certificates/database"
 	"log"
+	"math/big"
 )
 
 // InsertFixtures inserts data needed for tests
@@ -28,6 +29,20 @@ func ClearFixtures(db database.DB) {
 var fixtureCertificates = []*database.Certificate{
 	{
 		NameSerialNumber: "testserial",
+		SerialNumber:     big.NewInt(1001),
 		CommonName:       "test.id",
+		Status:           database.StatusValid,
+	},
+	{
+		NameSerialNumber: "revokeserial",
+		SerialNumber:     big.NewInt(1003),
+		CommonName:       "revoke.test.id",
+		Status:           database.StatusValid,
+	},
+	{
+		NameSerialNumber: "deleteserial",
+		SerialNumber:     big.NewInt(1004),
+		CommonName:       "delete.test.id",
+		Status:           database.StatusValid,
 	},
 }
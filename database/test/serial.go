@@ -0,0 +1,105 @@
+package test
+
+import (
+	"github.com/mvmaasakkers/certificates/database"
+	"math/big"
+	"testing"
+)
+
+// serialSequence is a cert.SerialGenerator returning the serial numbers in order, the last one is repeated
+type serialSequence []int64
+
+func (s *serialSequence) Next() (*big.Int, error) {
+	n := (*s)[0]
+	if len(*s) > 1 {
+		*s = (*s)[1:]
+	}
+	return big.NewInt(n), nil
+}
+
+// TestSerialNumberSequential tests
+func TestSerialNumberSequential(t *testing.T, counterRepository database.CounterRepository) {
+	serials := database.NewSequentialSerialGenerator(counterRepository)
+	for i := int64(1); i <= 3; i++ {
+		serialNumber, err := serials.Next()
+		if err != nil || serialNumber.Int64() != i {
+			t.Errorf("sequential: expected serial number %d, got %s (error %+v)", i, serialNumber, err)
+		}
+	}
+}
+
+// TestSerialNumberUnique tests
+func TestSerialNumberUnique(t *testing.T, certificateRepository database.CertificateRepository) {
+	if err := certificateRepository.Create(&database.Certificate{NameSerialNumber: "uniqueserial", SerialNumber: big.NewInt(1501), Status: database.StatusValid}); err != nil {
+		t.Errorf("create: expected error %+v, got error %+v", nil, err)
+		return
+	}
+	defer func() {
+		for _, name := range []string{"uniqueserial", "uniqueserial2", "uniqueserial3", "uniqueserial4"} {
+			certificateRepository.DeleteByNameSerialNumber(name)
+		}
+	}()
+
+	tests := []struct {
+		ID      string
+		Error   error
+		Serials serialSequence
+		// Competitor is stored with the first serial number before the certificate, as if another process took it
+		Competitor       string
+		NameSerialNumber string
+		Calls            int
+		SerialNumber     int64
+	}{
+		{
+			ID:               "skip_taken",
+			Serials:          serialSequence{1501, 1502},
+			NameSerialNumber: "uniqueserial2",
+			Calls:            1,
+			SerialNumber:     1502,
+		},
+		{
+			ID:               "retry_duplicate",
+			Serials:          serialSequence{1503, 1504},
+			Competitor:       "uniqueserial3",
+			NameSerialNumber: "uniqueserial4",
+			Calls:            2,
+			SerialNumber:     1504,
+		},
+		{
+			ID:               "duplicate_name_serial_number",
+			Error:            database.ErrorDuplicateObject,
+			Serials:          serialSequence{1505, 1506},
+			NameSerialNumber: "uniqueserial",
+			Calls:            1,
+		},
+		{
+			ID:               "exhausted",
+			Error:            database.ErrorDuplicateObject,
+			Serials:          serialSequence{1501},
+			NameSerialNumber: "uniqueserial5",
+		},
+	}
+	for _, test := range tests {
+		var calls int
+		var serialNumber *big.Int
+		err := database.WithUniqueSerialNumber(certificateRepository, &test.Serials, func(n *big.Int) error {
+			calls++
+			if test.Competitor != "" && calls == 1 {
+				if err := certificateRepository.Create(&database.Certificate{NameSerialNumber: test.Competitor, SerialNumber: n}); err != nil {
+					return err
+				}
+			}
+			serialNumber = n
+			return certificateRepository.Create(&database.Certificate{NameSerialNumber: test.NameSerialNumber, SerialNumber: n})
+		})
+		if err != test.Error {
+			t.Errorf("%s: expected error %+v, got error %+v", test.ID, test.Error, err)
+		}
+		if calls != test.Calls {
+			t.Errorf("%s: expected %d calls, got %d", test.ID, test.Calls, calls)
+		}
+		if err == nil && serialNumber.Int64() != test.SerialNumber {
+			t.Errorf("%s: expected serial number %d, got %s", test.ID, test.SerialNumber, serialNumber)
+		}
+	}
+}
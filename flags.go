@@ -0,0 +1,634 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/mvmaasakkers/certificates/database/file"
+	"github.com/mvmaasakkers/certificates/database/sql"
+	"github.com/mvmaasakkers/certificates/signer"
+	"github.com/tkuchiki/parsetime"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errorMissingSerial = errors.New("either --serial or --name-serial is required")
+	errorMissingPEM    = errors.New("certificate is stored without pem")
+	errorMissingKey    = errors.New("a keystore needs the private key, which is not available when signing a csr")
+	errorMissingFile   = errors.New("a file to inspect is required")
+
+	errorMissingVerifyFile = errors.New("a certificate file to verify is required")
+
+	errorMissingPassword   = errors.New("either --password, CERTIFICATES_PASSWORD or --password-file is required")
+	errorMissingPassphrase = errors.New("the key is encrypted, a passphrase is required")
+
+	errorInvalidSerialType = errors.New("invalid serial type, use random, sequential or prefixed")
+
+	errorInvalidDuration     = errors.New("invalid duration, use a number followed by s, m, h, d, w or y")
+	errorConflictingNotAfter = errors.New("--notafter and --validity can not be combined")
+)
+
+// subjectFlags are the flags used to fill the subject of a certificate request
+var subjectFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "cn",
+		Value: "",
+		Usage: "Common name attached to the cert",
+	},
+	cli.StringFlag{
+		Name:  "org",
+		Value: "",
+		Usage: "Organisation",
+	},
+	cli.StringFlag{
+		Name:  "ou",
+		Value: "",
+		Usage: "Organisational unit, used as role of client certificates for the API (see serve)",
+	},
+	cli.StringFlag{
+		Name:  "country",
+		Value: "",
+		Usage: "Country",
+	},
+	cli.StringFlag{
+		Name:  "province",
+		Value: "",
+		Usage: "Province",
+	},
+	cli.StringFlag{
+		Name:  "locality",
+		Value: "",
+		Usage: "Locality",
+	},
+	cli.StringFlag{
+		Name:  "postalcode",
+		Value: "",
+		Usage: "PostalCode",
+	},
+	cli.StringFlag{
+		Name:  "streetaddress",
+		Value: "",
+		Usage: "StreetAddress",
+	},
+}
+
+// validityFlags are the flags used to set the validity period of a certificate request
+var validityFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "notbefore",
+		Value: "",
+		Usage: "NotBefore sets the NotBefore timestamp of the certificate request. The default is now.",
+	},
+	cli.StringFlag{
+		Name:  "notafter",
+		Value: "",
+		Usage: "NotAfter sets the NotAfter timestamp of the certificate request. The default is --validity after NotBefore.",
+	},
+	cli.StringFlag{
+		Name:  "validity",
+		Value: "30d",
+		Usage: "Validity period of the certificate request counted from NotBefore, e.g. 90d or 1y (units are s, m, h, d, w and y)",
+	},
+	cli.StringFlag{
+		Name:  "backdate",
+		Value: "",
+		Usage: "Moves NotBefore back by this duration to tolerate clock skew, e.g. 5m",
+	},
+	cli.StringFlag{
+		Name:  "timezone",
+		Value: "UTC",
+		Usage: "Timezone to use. Default is set to UTC.",
+	},
+}
+
+// keyFlags are the flags used to configure the key generation of a certificate request
+var keyFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "key-type",
+		Value: "rsa",
+		Usage: "Key type (rsa, ecdsa or ed25519)",
+	},
+	cli.IntFlag{
+		Name:  "bitsize",
+		Value: 4096,
+		Usage: "Encryption key bitsize (rsa only)",
+	},
+	cli.StringFlag{
+		Name:  "curve",
+		Value: "P-256",
+		Usage: "Elliptic curve (ecdsa only: P-256, P-384 or P-521)",
+	},
+}
+
+// profileFlags are the flags used to select the profile of a certificate request
+var profileFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "profile",
+		Value: cert.ProfileDefault,
+		Usage: "Certificate profile (default, server, client, code-signing, smime, ocsp-signing or one from --profiles)",
+	},
+	cli.StringFlag{
+		Name:  "profiles",
+		Value: "",
+		Usage: "JSON file with additional certificate profiles",
+	},
+}
+
+// policyFlags are the flags used to load the policy of the CA
+var policyFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "policy",
+		Value: "",
+		Usage: "JSON file with the policy the issued certificates have to comply with",
+	},
+}
+
+// caKeyPassFlags are the flags used to give the passphrase of an encrypted CA key
+var caKeyPassFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "ca-key-pass",
+		Value:  "",
+		Usage:  "Passphrase of the encrypted CA key",
+		EnvVar: "CERTIFICATES_CA_KEY_PASS",
+	},
+	cli.StringFlag{
+		Name:  "ca-key-pass-file",
+		Value: "",
+		Usage: "File with the passphrase of the encrypted CA key",
+	},
+}
+
+// caSignerFlags are the flags used to sign with a CA key kept in an HSM, KMS or external program
+var caSignerFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "ca-signer",
+		Value: "",
+		Usage: "URI of the signer holding the CA key, used instead of --ca-key (pkcs11:<RFC 7512 URI> or exec:<command>)",
+	},
+}
+
+// keyPassFlags are the flags used to give the passphrase of an encrypted key
+var keyPassFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "key-pass",
+		Value:  "",
+		Usage:  "Passphrase of the encrypted key",
+		EnvVar: "CERTIFICATES_KEY_PASS",
+	},
+	cli.StringFlag{
+		Name:  "key-pass-file",
+		Value: "",
+		Usage: "File with the passphrase of the encrypted key",
+	},
+}
+
+// nameConstraintFlags are the flags used to restrict the names a CA can issue certificates for
+var nameConstraintFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "permit-dns",
+		Usage: "Permitted DNS domain, *.domain or .domain only permits subdomains",
+	},
+	cli.StringSliceFlag{
+		Name:  "exclude-dns",
+		Usage: "Excluded DNS domain, *.domain or .domain only excludes subdomains",
+	},
+	cli.StringSliceFlag{
+		Name:  "permit-ip",
+		Usage: "Permitted IP range in CIDR notation",
+	},
+	cli.StringSliceFlag{
+		Name:  "exclude-ip",
+		Usage: "Excluded IP range in CIDR notation",
+	},
+	cli.StringSliceFlag{
+		Name:  "permit-email",
+		Usage: "Permitted email address, host or .domain",
+	},
+	cli.StringSliceFlag{
+		Name:  "exclude-email",
+		Usage: "Excluded email address, host or .domain",
+	},
+	cli.StringSliceFlag{
+		Name:  "permit-uri",
+		Usage: "Permitted URI host, .domain only permits subdomains",
+	},
+	cli.StringSliceFlag{
+		Name:  "exclude-uri",
+		Usage: "Excluded URI host, .domain only excludes subdomains",
+	},
+	cli.BoolFlag{
+		Name:  "name-constraints-critical",
+		Usage: "Mark the name constraints as critical",
+	},
+}
+
+// dbFlags are the flags used to connect to the CA database
+var dbFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "ca-DB-type",
+		Value: "file",
+		Usage: "CA DB type (file or sql)",
+	},
+	cli.StringFlag{
+		Name:  "ca-DB-file",
+		Value: "file.DB",
+		Usage: "File DB filename",
+	},
+	cli.StringFlag{
+		Name:  "ca-DB-sql-dialect",
+		Value: "mysql",
+		Usage: "SQL Dialect",
+	},
+	cli.StringFlag{
+		Name:  "ca-DB-sql-cs",
+		Value: "user:pass@tcp(localhost:3306)/test?charset=utf8&parseTime=True",
+		Usage: "SQL Connection String",
+	},
+}
+
+// Serial number generators of the serialFlags
+const (
+	serialTypeRandom     = "random"
+	serialTypeSequential = "sequential"
+	serialTypePrefixed   = "prefixed"
+)
+
+// serialFlags are the flags used to choose how serial numbers of certificates stored in the CA database are generated
+var serialFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "serial-type",
+		Value: serialTypeRandom,
+		Usage: "Serial number generation (random, sequential or prefixed)",
+	},
+	cli.StringFlag{
+		Name:  "serial-prefix",
+		Value: "",
+		Usage: "Hexadecimal prefix of prefixed serial numbers, at most 12 octets starting with 01 to 7f",
+	},
+}
+
+// flags combines sets of flags into one list
+func flags(sets ...[]cli.Flag) []cli.Flag {
+	out := []cli.Flag{}
+	for _, set := range sets {
+		out = append(out, set...)
+	}
+	return out
+}
+
+// setSubject fills the subject of the request using the subjectFlags
+func setSubject(c *cli.Context, req *cert.Request) {
+	req.CommonName = c.String("cn")
+	req.Organization = c.String("org")
+	req.OrganizationalUnit = c.String("ou")
+	req.Country = c.String("country")
+	req.Province = c.String("province")
+	req.Locality = c.String("locality")
+	req.PostalCode = c.String("postalcode")
+	req.StreetAddress = c.String("streetaddress")
+}
+
+// setKey configures the key generation of the request using the keyFlags
+func setKey(c *cli.Context, req *cert.Request) {
+	req.KeyType = c.String("key-type")
+	req.BitSize = c.Int("bitsize")
+	req.Curve = c.String("curve")
+}
+
+// setNameConstraints sets the name constraints of a CA request using the nameConstraintFlags, the request has no
+// name constraints when none are given
+func setNameConstraints(c *cli.Context, req *cert.Request) {
+	nc := &cert.NameConstraints{
+		Critical:                c.Bool("name-constraints-critical"),
+		PermittedDNSDomains:     c.StringSlice("permit-dns"),
+		ExcludedDNSDomains:      c.StringSlice("exclude-dns"),
+		PermittedIPRanges:       c.StringSlice("permit-ip"),
+		ExcludedIPRanges:        c.StringSlice("exclude-ip"),
+		PermittedEmailAddresses: c.StringSlice("permit-email"),
+		ExcludedEmailAddresses:  c.StringSlice("exclude-email"),
+		PermittedURIDomains:     c.StringSlice("permit-uri"),
+		ExcludedURIDomains:      c.StringSlice("exclude-uri"),
+	}
+
+	for _, constraints := range [][]string{nc.PermittedDNSDomains, nc.ExcludedDNSDomains, nc.PermittedIPRanges, nc.ExcludedIPRanges,
+		nc.PermittedEmailAddresses, nc.ExcludedEmailAddresses, nc.PermittedURIDomains, nc.ExcludedURIDomains} {
+		if len(constraints) > 0 {
+			req.NameConstraints = nc
+			return
+		}
+	}
+}
+
+// setProfile sets the profile of the request and loads the profiles file using the profileFlags
+func setProfile(c *cli.Context, req *cert.Request) error {
+	req.Profile = c.String("profile")
+
+	if c.String("profiles") != "" {
+		d, err := ioutil.ReadFile(c.String("profiles"))
+		if err != nil {
+			fmt.Printf("Error reading profiles: %s\n", err.Error())
+			return err
+		}
+		req.Profiles, err = cert.LoadProfiles(d)
+		if err != nil {
+			fmt.Printf("Error parsing profiles: %s\n", err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readPolicy loads the policy file given with the policyFlags, the policy is nil when no file is given
+func readPolicy(c *cli.Context) (*cert.Policy, error) {
+	if c.String("policy") == "" {
+		return nil, nil
+	}
+
+	d, err := ioutil.ReadFile(c.String("policy"))
+	if err != nil {
+		fmt.Printf("Error reading policy: %s\n", err.Error())
+		return nil, err
+	}
+	policy, err := cert.LoadPolicy(d)
+	if err != nil {
+		fmt.Printf("Error parsing policy: %s\n", err.Error())
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// readPassphrase reads the passphrase given with the --<name> flag, or the file given with --<name>-file. The
+// passphrase is nil when neither is given.
+func readPassphrase(c *cli.Context, name string) ([]byte, error) {
+	if c.String(name) != "" {
+		return []byte(c.String(name)), nil
+	}
+
+	if c.String(name+"-file") == "" {
+		return nil, nil
+	}
+
+	d, err := ioutil.ReadFile(c.String(name + "-file"))
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(d, "\r\n"), nil
+}
+
+// readKey reads a PEM encoded private key file, an encrypted key is decrypted with the passphrase given with the
+// --<passFlag> flags (see readPassphrase)
+func readKey(c *cli.Context, filename string, passFlag string) ([]byte, error) {
+	key, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cert.IsEncryptedKey(key) {
+		return key, nil
+	}
+
+	passphrase, err := readPassphrase(c, passFlag)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == nil {
+		return nil, fmt.Errorf("%w, use --%s or --%s-file", errorMissingPassphrase, passFlag, passFlag)
+	}
+
+	return cert.DecryptKey(key, passphrase)
+}
+
+// keySigner is a signer for a CA key read from a file, there is nothing to close
+type keySigner struct {
+	crypto.Signer
+}
+
+// Close does nothing
+func (s keySigner) Close() error {
+	return nil
+}
+
+// openCASigner opens the signer of the CA certificate, which is the signer given with --ca-signer or the key read
+// from --ca-key
+func openCASigner(c *cli.Context, caCrt []byte) (signer.Signer, error) {
+	if c.String("ca-signer") != "" {
+		return signer.Open(c.String("ca-signer"))
+	}
+
+	return readSigner(c, caCrt, c.String("ca-key"), "ca-key-pass")
+}
+
+// readSigner reads the key file of the PEM encoded certificate as a signer, an encrypted key is decrypted with the
+// passphrase given with the --<passFlag> flags (see readKey)
+func readSigner(c *cli.Context, crt []byte, filename string, passFlag string) (signer.Signer, error) {
+	key, err := readKey(c, filename, passFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := tls.X509KeyPair(crt, key)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, cert.ErrorInvalidKeyType
+	}
+
+	return keySigner{priv}, nil
+}
+
+// encryptKey encrypts a PEM encoded private key when a passphrase is given with the --<passFlag> flags (see
+// readPassphrase), otherwise the key is returned as is
+func encryptKey(c *cli.Context, key []byte, passFlag string) ([]byte, error) {
+	passphrase, err := readPassphrase(c, passFlag)
+	if err != nil || passphrase == nil {
+		return key, err
+	}
+
+	return cert.EncryptKey(key, passphrase)
+}
+
+// setValidity parses the validityFlags and sets the NotBefore and NotAfter of the request
+func setValidity(c *cli.Context, req *cert.Request) error {
+	return setValidityPeriod(c, req, 0)
+}
+
+// setValidityPeriod parses the validityFlags and sets the NotBefore and NotAfter of the request. The validity starts
+// at --notbefore (now by default) and ends at --notafter, or after --validity. When --validity is not given and
+// period is not zero the request is valid for period instead of the default of --validity.
+func setValidityPeriod(c *cli.Context, req *cert.Request, period time.Duration) error {
+	p, err := parsetime.NewParseTime(c.String("timezone"))
+	if err != nil {
+		fmt.Printf("Error parsing --timezone: %s\n", err.Error())
+		return err
+	}
+
+	if c.IsSet("notafter") && c.IsSet("validity") {
+		fmt.Printf("Error: %s\n", errorConflictingNotAfter.Error())
+		return errorConflictingNotAfter
+	}
+
+	notBefore := time.Now()
+	if c.String("notbefore") != "" {
+		notBefore, err = p.Parse(c.String("notbefore"))
+		if err != nil {
+			fmt.Printf("Error parsing --notbefore: %s\n", err.Error())
+			return err
+		}
+	}
+
+	if c.String("notafter") != "" {
+		req.NotAfter, err = p.Parse(c.String("notafter"))
+		if err != nil {
+			fmt.Printf("Error parsing --notafter: %s\n", err.Error())
+			return err
+		}
+	} else {
+		if period == 0 || c.IsSet("validity") {
+			period, err = parseDuration(c.String("validity"))
+			if err == nil && period == 0 {
+				err = errorInvalidDuration
+			}
+			if err != nil {
+				fmt.Printf("Error parsing --validity: %s\n", err.Error())
+				return err
+			}
+		}
+		req.NotAfter = notBefore.Add(period)
+	}
+
+	if c.String("backdate") != "" {
+		backdate, err := parseDuration(c.String("backdate"))
+		if err != nil {
+			fmt.Printf("Error parsing --backdate: %s\n", err.Error())
+			return err
+		}
+		notBefore = notBefore.Add(-backdate)
+	}
+	req.NotBefore = notBefore
+
+	return nil
+}
+
+// durationUnits are the units parseDuration accepts on top of the units of time.ParseDuration
+var durationUnits = map[byte]time.Duration{
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+	'y': 365 * 24 * time.Hour,
+}
+
+// parseDuration parses a positive duration like time.ParseDuration, a whole number of days (d), weeks (w) or years
+// (y) is accepted too. A year is 365 days.
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errorInvalidDuration
+	}
+
+	if unit, ok := durationUnits[s[len(s)-1]]; ok {
+		n, err := strconv.ParseUint(s[:len(s)-1], 10, 16)
+		if err != nil {
+			return 0, errorInvalidDuration
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errorInvalidDuration
+	}
+	return d, nil
+}
+
+// openSerialGenerator creates the serial number generator configured with the serialFlags, sequential serial numbers
+// are counted in the CA database
+func openSerialGenerator(c *cli.Context, DB database.DB) (cert.SerialGenerator, error) {
+	switch c.String("serial-type") {
+	case serialTypeRandom:
+		return cert.RandomSerialGenerator{}, nil
+	case serialTypeSequential:
+		return database.NewSequentialSerialGenerator(DB.GetCounterRepository()), nil
+	case serialTypePrefixed:
+		prefix, err := hex.DecodeString(strings.TrimPrefix(c.String("serial-prefix"), "0x"))
+		if err != nil {
+			fmt.Printf("Error parsing --serial-prefix: %s\n", err.Error())
+			return nil, err
+		}
+		serials, err := cert.NewPrefixedSerialGenerator(prefix)
+		if err != nil {
+			fmt.Printf("Error parsing --serial-prefix: %s\n", err.Error())
+			return nil, err
+		}
+		return serials, nil
+	}
+
+	fmt.Printf("Error parsing --serial-type: %s\n", errorInvalidSerialType.Error())
+	return nil, errorInvalidSerialType
+}
+
+// createCertificate stores the certificate issued for the request in the CA database, as renewal of the certificate
+// with serial number renews when it is not nil. With revoke the renewed certificate is revoked as superseded along
+// with storing the renewal. Duplicates are not reported here as they are retried when the serial number is generated
+// (see database.WithUniqueSerialNumber).
+func createCertificate(repo database.CertificateRepository, cr *cert.Request, crt []byte, renews *big.Int, revoke bool) error {
+	DBCert := database.NewCertificate()
+	DBCert.Status = database.StatusValid
+	DBCert.NameSerialNumber = cr.NameSerialNumber
+	DBCert.Profile = cr.Profile
+	if err := DBCert.SetCertificate(crt); err != nil {
+		fmt.Printf("Error saving certificate to DB: %s\n", err.Error())
+		return err
+	}
+
+	var err error
+	if renews != nil {
+		err = repo.CreateRenewal(renews, DBCert, revoke)
+	} else {
+		err = repo.Create(DBCert)
+	}
+	if err != nil && err != database.ErrorDuplicateObject {
+		fmt.Printf("Error saving certificate to DB: %s\n", err.Error())
+	}
+	return err
+}
+
+// openDB opens and provisions the CA database configured with the dbFlags
+func openDB(c *cli.Context) (database.DB, error) {
+	var DB database.DB
+	switch c.String("ca-DB-type") {
+	case "sql":
+		DB = sql.NewDB(c.String("ca-DB-sql-dialect"), c.String("ca-DB-sql-cs"))
+	case "file":
+		DB = file.NewDB(c.String("ca-DB-file"))
+	}
+
+	if DB == nil {
+		return nil, database.ErrorNilConnection
+	}
+
+	if err := DB.Open(); err != nil {
+		fmt.Printf("Error opening DB: %s\n", err.Error())
+		return nil, err
+	}
+
+	if err := DB.Provision(); err != nil {
+		DB.Close()
+		fmt.Printf("Error provisioning DB: %s\n", err.Error())
+		return nil, err
+	}
+
+	return DB, nil
+}
@@ -3,20 +3,27 @@ module github.com/mvmaasakkers/certificates
 go 1.18
 
 require (
+	github.com/ThalesIgnite/crypto11 v1.2.5
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/google/uuid v1.3.0
 	github.com/jinzhu/gorm v1.9.16
+	github.com/lib/pq v1.10.5
+	github.com/pavlo-v-chernykh/keystore-go/v4 v4.4.1
 	github.com/tkuchiki/parsetime v0.3.0
+	golang.org/x/crypto v0.0.0-20220427172511-eb4f295cb31f
+	golang.org/x/sys v0.0.0-20220412211240-33da011f77ad
 	gopkg.in/urfave/cli.v1 v1.20.0
+	software.sslmate.com/src/go-pkcs12 v0.2.0
 )
 
 require (
 	github.com/denisenkom/go-mssqldb v0.12.0 // indirect
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
 	github.com/golang-sql/civil v0.0.0-20220223132316-b832511892a9 // indirect
 	github.com/golang-sql/sqlexp v0.0.0-20170517235910-f1bb20e5a188 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
-	github.com/lib/pq v1.10.5 // indirect
 	github.com/mattn/go-sqlite3 v2.0.3+incompatible // indirect
+	github.com/miekg/pkcs11 v1.0.3-0.20190429190417-a667d056470f // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	github.com/thales-e-security/pool v0.0.2 // indirect
 	github.com/tkuchiki/go-timezone v0.2.2 // indirect
-	golang.org/x/crypto v0.0.0-20220427172511-eb4f295cb31f // indirect
 )
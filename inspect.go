@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var inspectCommand = cli.Command{
+	Name:      "inspect",
+	Usage:     "Inspect the certificates, CSRs, CRLs and keys in a PEM or DER file",
+	ArgsUsage: "<file>",
+	Description: `Prints the subject, issuer, subject alt names, validity, key, extensions and fingerprints of every
+   object in the file, use - to read from stdin. With --crt the public key of every object is compared to the
+   public key of the certificate, for instance to check that a key belongs to a certificate.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "crt",
+			Value: "",
+			Usage: "Certificate file to match the keys against",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "Output format (text or json)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+
+		switch c.String("format") {
+		case "text", "json":
+		default:
+			fmt.Printf("Error parsing --format: %s\n", errorInvalidFormat.Error())
+			return errorInvalidFormat
+		}
+
+		if c.NArg() != 1 {
+			fmt.Printf("Error: %s\n", errorMissingFile.Error())
+			return errorMissingFile
+		}
+
+		var data []byte
+		var err error
+		if c.Args().First() == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(c.Args().First())
+		}
+		if err != nil {
+			fmt.Printf("Error reading file: %s\n", err.Error())
+			return err
+		}
+
+		var crt []byte
+		if c.String("crt") != "" {
+			crt, err = ioutil.ReadFile(c.String("crt"))
+			if err != nil {
+				fmt.Printf("Error reading crt file: %s\n", err.Error())
+				return err
+			}
+		}
+
+		inspections, err := cert.Inspect(data, crt)
+		if err != nil {
+			fmt.Printf("Error inspecting file: %s\n", err.Error())
+			return err
+		}
+
+		if c.String("format") == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(inspections); err != nil {
+				fmt.Printf("Error writing inspection: %s\n", err.Error())
+				return err
+			}
+			return nil
+		}
+
+		for i, inspection := range inspections {
+			if i > 0 {
+				fmt.Println()
+			}
+			if err := writeInspectText(inspection); err != nil {
+				fmt.Printf("Error writing inspection: %s\n", err.Error())
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func writeInspectText(inspection *cert.Inspection) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", label, value)
+		}
+	}
+	timeField := func(label string, value *time.Time) {
+		if value != nil {
+			field(label, value.Format(time.RFC3339))
+		}
+	}
+	boolField := func(label string, value *bool) {
+		if value != nil {
+			field(label, map[bool]string{true: "yes", false: "no"}[*value])
+		}
+	}
+
+	field("Type", inspection.Type)
+	field("Subject", inspection.Subject)
+	field("Issuer", inspection.Issuer)
+	field("Serial Number", inspection.SerialNumber)
+	field("Subject Alt Names", strings.Join(inspection.SubjectAltNames, ", "))
+	timeField("Not Before", inspection.NotBefore)
+	timeField("Not After", inspection.NotAfter)
+	timeField("This Update", inspection.ThisUpdate)
+	timeField("Next Update", inspection.NextUpdate)
+	field("CRL Number", inspection.CRLNumber)
+	if inspection.Type == cert.ObjectCRL {
+		field("Revoked", fmt.Sprintf("%d certificates", len(inspection.RevokedSerials)))
+		field("Revoked Serial Numbers", strings.Join(inspection.RevokedSerials, ", "))
+	}
+	if inspection.KeyType != "" {
+		key := fmt.Sprintf("%s %d bits", inspection.KeyType, inspection.KeySize)
+		if inspection.Curve != "" {
+			key += " (" + inspection.Curve + ")"
+		}
+		field("Key", key)
+	}
+	field("Signature Algorithm", inspection.SignatureAlgorithm)
+	boolField("Signature Valid", inspection.SignatureValid)
+	if inspection.Type == cert.ObjectCertificate {
+		boolField("CA", &inspection.IsCA)
+	}
+	field("Key Usage", strings.Join(inspection.KeyUsage, ", "))
+	field("Ext Key Usage", strings.Join(inspection.ExtKeyUsage, ", "))
+
+	extensions := make([]string, 0, len(inspection.Extensions))
+	for _, extension := range inspection.Extensions {
+		name := extension.Name
+		if name == "" {
+			name = extension.OID
+		}
+		if extension.Critical {
+			name += " (critical)"
+		}
+		extensions = append(extensions, name)
+	}
+	field("Extensions", strings.Join(extensions, ", "))
+
+	field("Subject Key ID", inspection.SubjectKeyID)
+	field("Authority Key ID", inspection.AuthorityKeyID)
+	field("SHA-1 Fingerprint", inspection.FingerprintSHA1)
+	field("SHA-256 Fingerprint", inspection.FingerprintSHA256)
+	field("Public Key SHA-256", inspection.PublicKeySHA256)
+	boolField("Key Matches", inspection.KeyMatches)
+
+	return w.Flush()
+}
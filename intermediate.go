@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+)
+
+var generateIntermediateCommand = cli.Command{
+	Name:        "generate-intermediate",
+	Aliases:     []string{"gen-intermediate"},
+	Usage:       "Generate an intermediate CA pair signed by a (root) CA",
+	Description: `To generate an intermediate CA pair you need to supply at least a valid name and the parent CA pair.`,
+	Flags: flags([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "stdout",
+			Usage: "Send pem to stdout instead of to file",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "Parent CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "Parent CA Key file",
+		},
+		cli.StringFlag{
+			Name:  "ca-chain",
+			Value: "",
+			Usage: "File with the intermediate certificates above the parent CA, their name constraints are checked as well",
+		},
+		cli.StringFlag{
+			Name:  "crt",
+			Value: "intermediate.crt",
+			Usage: "Filename to write the intermediate ca cert to",
+		},
+		cli.StringFlag{
+			Name:  "key",
+			Value: "intermediate.key",
+			Usage: "Filename to write the intermediate ca key to",
+		},
+		cli.IntFlag{
+			Name:  "max-path-len",
+			Value: 0,
+			Usage: "Maximum number of intermediate CAs allowed below this intermediate CA. Use -1 for unlimited.",
+		},
+	}, caKeyPassFlags, caSignerFlags, keyPassFlags, subjectFlags, validityFlags, keyFlags, nameConstraintFlags),
+	Action: func(c *cli.Context) error {
+
+		ca := cert.NewRequest()
+		setSubject(c, ca)
+		setKey(c, ca)
+		setMaxPathLen(c, ca)
+		setNameConstraints(c, ca)
+
+		if err := setValidity(c, ca); err != nil {
+			return err
+		}
+
+		parentCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		parentSigner, err := openCASigner(c, parentCrt)
+		if err != nil {
+			fmt.Printf("Error reading CA key: %s\n", err.Error())
+			return err
+		}
+		defer parentSigner.Close()
+
+		if c.String("ca-chain") != "" {
+			ca.CAChain, err = ioutil.ReadFile(c.String("ca-chain"))
+			if err != nil {
+				fmt.Printf("Error reading CA chain: %s\n", err.Error())
+				return err
+			}
+		}
+
+		crt, key, err := cert.GenerateIntermediateCAWithSigner(ca, parentCrt, parentSigner)
+		if err != nil {
+			fmt.Printf("Error generating intermediate CA: %s\n", err.Error())
+			return err
+		}
+
+		key, err = encryptKey(c, key, "key-pass")
+		if err != nil {
+			fmt.Printf("Error encrypting intermediate CA key: %s\n", err.Error())
+			return err
+		}
+
+		if c.Bool("stdout") {
+			fmt.Println(string(key))
+			fmt.Println(string(crt))
+			return nil
+		}
+
+		fmt.Printf("Writing intermediate CA to %s\n", c.String("crt"))
+		if err := ioutil.WriteFile(c.String("crt"), crt, 0600); err != nil {
+			fmt.Printf("Error writing intermediate CA: %s\n", err.Error())
+			return err
+		}
+
+		fmt.Printf("Writing intermediate CA key to %s\n", c.String("key"))
+		if err := ioutil.WriteFile(c.String("key"), key, 0600); err != nil {
+			fmt.Printf("Error writing intermediate CA key: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
+
+// setMaxPathLen sets the path length constraint of a CA request using the max-path-len flag, where a negative
+// value means unlimited
+func setMaxPathLen(c *cli.Context, req *cert.Request) {
+	req.MaxPathLen = c.Int("max-path-len")
+	req.MaxPathLenZero = req.MaxPathLen == 0
+}
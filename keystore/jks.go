@@ -0,0 +1,104 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	jks "github.com/pavlo-v-chernykh/keystore-go/v4"
+	"strings"
+	"time"
+)
+
+// EncodeJKS creates a Java KeyStore (JKS) holding the key with the certificate and the certificates of the chain,
+// all PEM encoded, as a private key entry under the alias. The keystore and the key entry are protected with the
+// password, which can not be empty. The chain can be nil.
+func EncodeJKS(crt []byte, key []byte, chain []byte, alias string, password string) ([]byte, error) {
+	if password == "" {
+		return nil, ErrorEmptyPassword
+	}
+
+	leaf, priv, caCerts, err := parsePair(crt, key, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateChain := []jks.Certificate{{Type: "X509", Content: leaf.Raw}}
+	for _, caCert := range caCerts {
+		certificateChain = append(certificateChain, jks.Certificate{Type: "X509", Content: caCert.Raw})
+	}
+
+	ks := jks.New()
+	if err := ks.SetPrivateKeyEntry(alias, jks.PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       pkcs8,
+		CertificateChain: certificateChain,
+	}, []byte(password)); err != nil {
+		return nil, err
+	}
+
+	return storeJKS(ks, password)
+}
+
+// EncodeJKSTrustStore creates a Java KeyStore (JKS) truststore holding the PEM encoded certificates as trusted
+// certificate entries. The alias of an entry is the lower case common name of its certificate, followed by a
+// number when the common name is used before. The truststore is protected with the password, which can not be
+// empty.
+func EncodeJKSTrustStore(certs []byte, password string) ([]byte, error) {
+	if password == "" {
+		return nil, ErrorEmptyPassword
+	}
+
+	ks := jks.New()
+	for {
+		var block *pem.Block
+		block, certs = pem.Decode(certs)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, ErrorInvalidChain
+		}
+
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		alias := strings.ToLower(crt.Subject.CommonName)
+		if alias == "" {
+			alias = "certificate"
+		}
+		for i := 1; ks.IsTrustedCertificateEntry(alias); i++ {
+			alias = fmt.Sprintf("%s-%d", strings.ToLower(crt.Subject.CommonName), i)
+		}
+
+		if err := ks.SetTrustedCertificateEntry(alias, jks.TrustedCertificateEntry{
+			CreationTime: time.Now(),
+			Certificate:  jks.Certificate{Type: "X509", Content: crt.Raw},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ks.Aliases()) == 0 {
+		return nil, ErrorInvalidChain
+	}
+
+	return storeJKS(ks, password)
+}
+
+// storeJKS encodes the keystore protected with the password
+func storeJKS(ks jks.KeyStore, password string) ([]byte, error) {
+	out := bytes.NewBuffer([]byte{})
+	if err := ks.Store(out, []byte(password)); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/pem"
+	"github.com/mvmaasakkers/certificates/cert"
+	jks "github.com/pavlo-v-chernykh/keystore-go/v4"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeJKS(t *testing.T) {
+	for _, keyType := range []string{cert.KeyTypeRSA, cert.KeyTypeECDSA} {
+		t.Run(keyType, func(t *testing.T) {
+			ca, intermediate, leaf := generateTestPairs(t, keyType)
+
+			data, err := EncodeJKS(leaf.Crt, leaf.Key, intermediate.Crt, "server", "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ks := jks.New()
+			if err := ks.Load(bytes.NewReader(data), []byte("secret")); err != nil {
+				t.Fatal(err)
+			}
+			entry, err := ks.GetPrivateKeyEntry("server", []byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entry.CertificateChain) != 2 {
+				t.Fatalf("EncodeJKS() chain length = %d, want 2", len(entry.CertificateChain))
+			}
+			leafBlock, _ := pem.Decode(leaf.Crt)
+			intermediateBlock, _ := pem.Decode(intermediate.Crt)
+			if !bytes.Equal(entry.CertificateChain[0].Content, leafBlock.Bytes) || !bytes.Equal(entry.CertificateChain[1].Content, intermediateBlock.Bytes) {
+				t.Errorf("EncodeJKS() chain does not match")
+			}
+
+			keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: entry.PrivateKey})
+			if _, err := tls.X509KeyPair(leaf.Crt, keyPEM); err != nil {
+				t.Errorf("EncodeJKS() key does not match the certificate: %s", err)
+			}
+
+			if err := jks.New().Load(bytes.NewReader(data), []byte("wrong")); err == nil {
+				t.Errorf("Load() expected an error for a wrong password")
+			}
+
+			if _, err := EncodeJKS(leaf.Crt, leaf.Key, nil, "server", ""); err != ErrorEmptyPassword {
+				t.Errorf("EncodeJKS() error = %v, wantErr %v", err, ErrorEmptyPassword)
+			}
+			if _, err := EncodeJKS(leaf.Crt, ca.Key, nil, "server", "secret"); err == nil {
+				t.Errorf("EncodeJKS() expected an error for a key that does not match the certificate")
+			}
+		})
+	}
+}
+
+func TestEncodeJKSTrustStore(t *testing.T) {
+	ca, intermediate, _ := generateTestPairs(t, cert.KeyTypeECDSA)
+
+	tests := []struct {
+		name        string
+		certs       []byte
+		password    string
+		wantAliases []string
+		wantErr     bool
+	}{
+		{name: "ca", certs: ca.Crt, password: "secret", wantAliases: []string{"ca.test.local"}},
+		{name: "bundle", certs: append(append(ca.Crt, intermediate.Crt...), ca.Crt...), password: "secret", wantAliases: []string{"ca.test.local", "ca.test.local-1", "intermediate.test.local"}},
+		{name: "empty-password", certs: ca.Crt, wantErr: true},
+		{name: "no-certificates", certs: []byte("invalid"), password: "secret", wantErr: true},
+		{name: "key", certs: ca.Key, password: "secret", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeJKSTrustStore(tt.certs, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncodeJKSTrustStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			ks := jks.New(jks.WithOrderedAliases())
+			if err := ks.Load(bytes.NewReader(data), []byte(tt.password)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ks.Aliases(), tt.wantAliases) {
+				t.Errorf("EncodeJKSTrustStore() aliases = %v, want %v", ks.Aliases(), tt.wantAliases)
+			}
+			for _, alias := range tt.wantAliases {
+				if !ks.IsTrustedCertificateEntry(alias) {
+					t.Errorf("EncodeJKSTrustStore() %s is not a trusted certificate entry", alias)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Package keystore converts PEM encoded certificate pairs, as returned by the cert package, into password protected
+// keystores for consumers that can not read PEM files, such as Windows and Java.
+package keystore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+var (
+	// ErrorEmptyPassword is given if a keystore is created without password
+	ErrorEmptyPassword = errors.New("empty keystore password")
+	// ErrorInvalidChain is given if the chain contains data that is not a PEM encoded certificate
+	ErrorInvalidChain = errors.New("invalid certificate chain")
+)
+
+// parsePair parses the PEM encoded certificate and key and the PEM encoded certificates of the chain
+func parsePair(crt []byte, key []byte, chain []byte) (*x509.Certificate, interface{}, []*x509.Certificate, error) {
+	keyPair, err := tls.X509KeyPair(crt, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var caCerts []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, chain = pem.Decode(chain)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, nil, nil, ErrorInvalidChain
+		}
+
+		caCert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		caCerts = append(caCerts, caCert)
+	}
+
+	return leaf, keyPair.PrivateKey, caCerts, nil
+}
@@ -0,0 +1,25 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// EncodePKCS12 creates a PKCS#12 (.p12 or .pfx) keystore holding the key, the certificate and the certificates of
+// the chain, all PEM encoded. The keystore is protected with the password, which can not be empty. The chain can
+// be nil.
+//
+// The key is encrypted with 3DES and the certificates with RC2, as done by OpenSSL, so the keystore can be imported
+// on older Windows and Java versions as well.
+func EncodePKCS12(crt []byte, key []byte, chain []byte, password string) ([]byte, error) {
+	if password == "" {
+		return nil, ErrorEmptyPassword
+	}
+
+	leaf, priv, caCerts, err := parsePair(crt, key, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	return pkcs12.Encode(rand.Reader, priv, leaf, caCerts, password)
+}
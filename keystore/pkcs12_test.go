@@ -0,0 +1,105 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/mvmaasakkers/certificates/cert"
+	"software.sslmate.com/src/go-pkcs12"
+	"testing"
+	"time"
+)
+
+type testPair struct {
+	Crt []byte
+	Key []byte
+}
+
+// generateTestPairs generates a CA, an intermediate CA signed by it and a certificate signed by the intermediate
+func generateTestPairs(t *testing.T, keyType string) (ca *testPair, intermediate *testPair, leaf *testPair) {
+	ca, intermediate, leaf = &testPair{}, &testPair{}, &testPair{}
+	notBefore, notAfter := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+	var err error
+	ca.Crt, ca.Key, err = cert.GenerateCA(&cert.Request{CommonName: "ca.test.local", KeyType: cert.KeyTypeECDSA, NotBefore: notBefore, NotAfter: notAfter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediate.Crt, intermediate.Key, err = cert.GenerateIntermediateCA(&cert.Request{CommonName: "intermediate.test.local", KeyType: cert.KeyTypeECDSA, NotBefore: notBefore, NotAfter: notAfter}, ca.Crt, ca.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf.Crt, leaf.Key, err = cert.GenerateCertificate(&cert.Request{CommonName: "leaf.test.local", KeyType: keyType, BitSize: 2048, NotBefore: notBefore, NotAfter: notAfter}, intermediate.Crt, intermediate.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ca, intermediate, leaf
+}
+
+func TestEncodePKCS12(t *testing.T) {
+	for _, keyType := range []string{cert.KeyTypeRSA, cert.KeyTypeECDSA, cert.KeyTypeEd25519} {
+		t.Run(keyType, func(t *testing.T) {
+			ca, intermediate, leaf := generateTestPairs(t, keyType)
+
+			pfx, err := EncodePKCS12(leaf.Crt, leaf.Key, append(intermediate.Crt, ca.Crt...), "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			priv, crt, caCerts, err := pkcs12.DecodeChain(pfx, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			block, _ := pem.Decode(leaf.Crt)
+			if !bytes.Equal(crt.Raw, block.Bytes) {
+				t.Errorf("EncodePKCS12() certificate does not match")
+			}
+			if len(caCerts) != 2 || caCerts[0].Subject.CommonName != "intermediate.test.local" || caCerts[1].Subject.CommonName != "ca.test.local" {
+				t.Errorf("EncodePKCS12() chain = %v", caCerts)
+			}
+
+			der, err := x509.MarshalPKCS8PrivateKey(priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tls.X509KeyPair(leaf.Crt, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+				t.Errorf("EncodePKCS12() key does not match the certificate: %s", err)
+			}
+
+			if _, _, _, err := pkcs12.DecodeChain(pfx, "wrong"); err == nil {
+				t.Errorf("DecodeChain() expected an error for a wrong password")
+			}
+		})
+	}
+}
+
+func TestEncodePKCS12_Errors(t *testing.T) {
+	ca, intermediate, leaf := generateTestPairs(t, cert.KeyTypeECDSA)
+
+	tests := []struct {
+		name     string
+		crt      []byte
+		key      []byte
+		chain    []byte
+		password string
+		wantErr  error
+	}{
+		{name: "no-chain", crt: leaf.Crt, key: leaf.Key, password: "secret"},
+		{name: "empty-password", crt: leaf.Crt, key: leaf.Key, wantErr: ErrorEmptyPassword},
+		{name: "invalid-chain", crt: leaf.Crt, key: leaf.Key, chain: ca.Key, password: "secret", wantErr: ErrorInvalidChain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncodePKCS12(tt.crt, tt.key, tt.chain, tt.password); err != tt.wantErr {
+				t.Errorf("EncodePKCS12() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := EncodePKCS12(leaf.Crt, intermediate.Key, nil, "secret"); err == nil {
+		t.Errorf("EncodePKCS12() expected an error for a key that does not match the certificate")
+	}
+}
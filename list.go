@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/tkuchiki/parsetime"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var errorInvalidStatus = errors.New("invalid status")
+
+var listCommand = cli.Command{
+	Name:        "list",
+	Aliases:     []string{"ls"},
+	Usage:       "List the certificates in the CA DB",
+	Description: `Lists the certificates in the CA DB ordered by the time they were issued, optionally filtered.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "status",
+			Value: "",
+			Usage: "Only list certificates with the given status (valid or revoked)",
+		},
+		cli.StringFlag{
+			Name:  "cn",
+			Value: "",
+			Usage: "Only list certificates with a common name containing the given value",
+		},
+		cli.StringFlag{
+			Name:  "expires-before",
+			Value: "",
+			Usage: "Only list certificates that expire before the given timestamp",
+		},
+		cli.StringFlag{
+			Name:  "issued-after",
+			Value: "",
+			Usage: "Only list certificates that were issued after the given timestamp",
+		},
+		cli.StringFlag{
+			Name:  "timezone",
+			Value: "UTC",
+			Usage: "Timezone to use. Default is set to UTC.",
+		},
+		cli.IntFlag{
+			Name:  "offset",
+			Value: 0,
+			Usage: "Number of certificates to skip",
+		},
+		cli.IntFlag{
+			Name:  "limit",
+			Value: 0,
+			Usage: "Maximum number of certificates to list. The default (0) is no limit.",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "table",
+			Usage: "Output format (table or json)",
+		},
+	}, dbFlags),
+	Action: func(c *cli.Context) error {
+
+		if c.String("format") != "table" && c.String("format") != "json" {
+			fmt.Printf("Error parsing --format: %s\n", errorInvalidFormat.Error())
+			return errorInvalidFormat
+		}
+
+		filter, err := listFilter(c)
+		if err != nil {
+			return err
+		}
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		crts, err := DB.GetCertificateRepository().List(filter)
+		if err != nil {
+			fmt.Printf("Error listing certificates: %s\n", err.Error())
+			return err
+		}
+
+		if c.String("format") == "json" {
+			return writeListJSON(crts)
+		}
+		return writeListTable(crts)
+	},
+}
+
+// listItem is the JSON representation of a certificate in the list output
+type listItem struct {
+	SerialNumber     string     `json:"serial_number"`
+	NameSerialNumber string     `json:"name_serial_number"`
+	CommonName       string     `json:"common_name"`
+	Status           string     `json:"status"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	ExpirationDate   time.Time  `json:"expiration_date"`
+	RevocationDate   *time.Time `json:"revocation_date,omitempty"`
+}
+
+// listFilter creates the certificate filter from the list flags
+func listFilter(c *cli.Context) (database.CertificateFilter, error) {
+	filter := database.CertificateFilter{
+		Status:     c.String("status"),
+		CommonName: c.String("cn"),
+		Offset:     c.Int("offset"),
+		Limit:      c.Int("limit"),
+	}
+
+	switch filter.Status {
+	case "", database.StatusValid, database.StatusRevoked:
+	default:
+		fmt.Printf("Error parsing --status: %s\n", errorInvalidStatus.Error())
+		return filter, errorInvalidStatus
+	}
+
+	p, err := parsetime.NewParseTime(c.String("timezone"))
+	if err != nil {
+		fmt.Printf("Error parsing --timezone: %s\n", err.Error())
+		return filter, err
+	}
+
+	if c.String("expires-before") != "" {
+		expiresBefore, err := p.Parse(c.String("expires-before"))
+		if err != nil {
+			fmt.Printf("Error parsing --expires-before: %s\n", err.Error())
+			return filter, err
+		}
+		filter.ExpiresBefore = &expiresBefore
+	}
+
+	if c.String("issued-after") != "" {
+		issuedAfter, err := p.Parse(c.String("issued-after"))
+		if err != nil {
+			fmt.Printf("Error parsing --issued-after: %s\n", err.Error())
+			return filter, err
+		}
+		filter.IssuedAfter = &issuedAfter
+	}
+
+	return filter, nil
+}
+
+func writeListJSON(crts []*database.Certificate) error {
+	items := make([]listItem, 0, len(crts))
+	for _, crt := range crts {
+		items = append(items, listItem{
+			SerialNumber:     formatSerialNumber(crt),
+			NameSerialNumber: crt.NameSerialNumber,
+			CommonName:       crt.CommonName,
+			Status:           crt.Status,
+			IssuedAt:         crt.CreatedAt,
+			ExpirationDate:   crt.ExpirationDate,
+			RevocationDate:   crt.RevocationDate,
+		})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(items); err != nil {
+		fmt.Printf("Error writing certificates: %s\n", err.Error())
+		return err
+	}
+	return nil
+}
+
+func writeListTable(crts []*database.Certificate) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERIAL NUMBER\tNAME SERIAL NUMBER\tCOMMON NAME\tSTATUS\tISSUED\tEXPIRES")
+	for _, crt := range crts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatSerialNumber(crt),
+			crt.NameSerialNumber,
+			crt.CommonName,
+			crt.Status,
+			crt.CreatedAt.Format(time.RFC3339),
+			crt.ExpirationDate.Format(time.RFC3339),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Printf("Error writing certificates: %s\n", err.Error())
+		return err
+	}
+	return nil
+}
+
+// formatSerialNumber returns the serial number of the certificate in decimal notation. The serial number is a string
+// in the JSON output as well, so large serial numbers keep their precision.
+func formatSerialNumber(crt *database.Certificate) string {
+	if crt.SerialNumber == nil {
+		return ""
+	}
+	return crt.SerialNumber.String()
+}
@@ -5,18 +5,17 @@ import (
 	"github.com/google/uuid"
 	"github.com/mvmaasakkers/certificates/cert"
 	"github.com/mvmaasakkers/certificates/database"
-	"github.com/mvmaasakkers/certificates/database/file"
-	"github.com/mvmaasakkers/certificates/database/sql"
-	"github.com/tkuchiki/parsetime"
 	"gopkg.in/urfave/cli.v1"
 	"io/ioutil"
 	"math/big"
 	"os"
-	"time"
 )
 
 func main() {
 	if err := run(os.Args); err != nil {
+		if err == errorVerificationFailed {
+			os.Exit(exitCodeVerificationFailed)
+		}
 		os.Exit(1)
 	}
 }
@@ -43,406 +42,384 @@ var certificateCommand = cli.Command{
 	Aliases: []string{"cert"},
 	Usage:   "certificate commands",
 	Subcommands: []cli.Command{
-		{
-			Name:        "generate-ca",
-			Aliases:     []string{"gen-ca"},
-			Usage:       "Generate a CA pair",
-			Description: `To generate a CA pair you need to supply at least a valid name.`,
-			Flags: []cli.Flag{
-				cli.BoolFlag{
-					Name:  "stdout",
-					Usage: "Send pem to stdout instead of to file",
-				},
-				cli.StringFlag{
-					Name:  "ca",
-					Value: "ca.crt",
-					Usage: "Filename to write the ca cert to",
-				},
-				cli.StringFlag{
-					Name:  "ca-key",
-					Value: "ca.key",
-					Usage: "Filename to write the ca key to",
-				},
-				cli.StringFlag{
-					Name:  "cn",
-					Value: "",
-					Usage: "Common name attached to the ca cert",
-				},
-				cli.StringFlag{
-					Name:  "org",
-					Value: "",
-					Usage: "Organisation",
-				},
-				cli.StringFlag{
-					Name:  "country",
-					Value: "",
-					Usage: "Country",
-				},
-				cli.StringFlag{
-					Name:  "province",
-					Value: "",
-					Usage: "Province",
-				},
-				cli.StringFlag{
-					Name:  "locality",
-					Value: "",
-					Usage: "Locality",
-				},
-				cli.StringFlag{
-					Name:  "postalcode",
-					Value: "",
-					Usage: "PostalCode",
-				},
-				cli.StringFlag{
-					Name:  "streetaddress",
-					Value: "",
-					Usage: "StreetAddress",
-				},
-				cli.StringFlag{
-					Name:  "notbefore",
-					Value: time.Now().String(),
-					Usage: "NotBefore sets the NotBefore timestamp of the certificate request",
-				},
-				cli.StringFlag{
-					Name:  "notafter",
-					Value: time.Now().AddDate(0, 0, 30).String(),
-					Usage: "NotAfter sets the NotAfter timestamp of the certificate request. The default is 30 days from now.",
-				},
-				cli.StringFlag{
-					Name:  "timezone",
-					Value: "UTC",
-					Usage: "Timezone to use. Default is set to UTC.",
-				},
-			},
-			Action: func(c *cli.Context) error {
-
-				ca := cert.NewRequest()
-				ca.CommonName = c.String("cn")
-				ca.Organization = c.String("org")
-				ca.Country = c.String("country")
-				ca.Province = c.String("province")
-				ca.Locality = c.String("locality")
-				ca.PostalCode = c.String("postalcode")
-				ca.StreetAddress = c.String("streetaddress")
-
-				p, err := parsetime.NewParseTime(c.String("timezone"))
-				if err != nil {
-					fmt.Printf("Error parsing --timezone: %s\n", err.Error())
-					return err
-				}
-
-				if c.String("notbefore") != "" {
-					notBefore, err := p.Parse(c.String("notbefore"))
-					if err != nil {
-						fmt.Printf("Error parsing --notbefore: %s\n", err.Error())
-						return err
-					}
-					ca.NotBefore = notBefore
-				}
-
-				if c.String("notafter") != "" {
-					notAfter, err := p.Parse(c.String("notafter"))
-					if err != nil {
-						fmt.Printf("Error parsing --notafter: %s\n", err.Error())
-						return err
-					}
-					ca.NotAfter = notAfter
-				}
-
-				caCrt, caKey, err := cert.GenerateCA(ca)
-				if err != nil {
-					fmt.Printf("Error generating CA: %s\n", err.Error())
-					return err
-				}
-
-				if c.Bool("stdout") {
-					fmt.Println(string(caKey))
-					fmt.Println(string(caCrt))
-					return nil
-				}
-
-				if err := ioutil.WriteFile(c.String("ca"), caCrt, 0600); err != nil {
-					fmt.Printf("Error writing CA: %s\n", err.Error())
-					return err
-				}
-
-				if err := ioutil.WriteFile(c.String("ca-key"), caKey, 0600); err != nil {
-					fmt.Printf("Error writing CA: %s\n", err.Error())
-					return err
-				}
-
-				return nil
-			},
+		generateCACommand,
+		generateIntermediateCommand,
+		generateCommand,
+		generateCSRCommand,
+		convertCommand,
+		listCommand,
+		showCommand,
+		inspectCommand,
+		verifyCommand,
+		renewCommand,
+		revokeCommand,
+		crlCommand,
+		generateOCSPSignerCommand,
+		ocspServeCommand,
+		acmeServeCommand,
+		serveCommand,
+	},
+}
+
+var generateCACommand = cli.Command{
+	Name:        "generate-ca",
+	Aliases:     []string{"gen-ca"},
+	Usage:       "Generate a CA pair",
+	Description: `To generate a CA pair you need to supply at least a valid name.`,
+	Flags: flags([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "stdout",
+			Usage: "Send pem to stdout instead of to file",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "Filename to write the ca cert to",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "Filename to write the ca key to",
+		},
+		cli.IntFlag{
+			Name:  "max-path-len",
+			Value: -1,
+			Usage: "Maximum number of intermediate CAs allowed below this CA. The default (-1) is unlimited.",
+		},
+		cli.StringFlag{
+			Name:  "truststore",
+			Value: "",
+			Usage: "Filename to write a password protected jks truststore with the ca cert to",
+		},
+	}, subjectFlags, validityFlags, keyFlags, nameConstraintFlags, caKeyPassFlags, passwordFlags),
+	Action: func(c *cli.Context) error {
+
+		var password string
+		if c.String("truststore") != "" {
+			var err error
+			password, err = readPassword(c)
+			if err != nil {
+				return err
+			}
+		}
+
+		ca := cert.NewRequest()
+		setSubject(c, ca)
+		setKey(c, ca)
+		setMaxPathLen(c, ca)
+		setNameConstraints(c, ca)
+
+		if err := setValidity(c, ca); err != nil {
+			return err
+		}
+
+		caCrt, caKey, err := cert.GenerateCA(ca)
+		if err != nil {
+			fmt.Printf("Error generating CA: %s\n", err.Error())
+			return err
+		}
+
+		caKey, err = encryptKey(c, caKey, "ca-key-pass")
+		if err != nil {
+			fmt.Printf("Error encrypting CA key: %s\n", err.Error())
+			return err
+		}
+
+		if c.String("truststore") != "" {
+			if err := writeTrustStore(c.String("truststore"), caCrt, password); err != nil {
+				return err
+			}
+		}
+
+		if c.Bool("stdout") {
+			fmt.Println(string(caKey))
+			fmt.Println(string(caCrt))
+			return nil
+		}
+
+		if err := ioutil.WriteFile(c.String("ca"), caCrt, 0600); err != nil {
+			fmt.Printf("Error writing CA: %s\n", err.Error())
+			return err
+		}
+
+		if err := ioutil.WriteFile(c.String("ca-key"), caKey, 0600); err != nil {
+			fmt.Printf("Error writing CA: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
+
+var generateCommand = cli.Command{
+	Name:    "generate",
+	Aliases: []string{"gen"},
+	Usage:   "Generate a signed certificate pair",
+	Flags: flags([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "stdout",
+			Usage: "Send pem to stdout instead of to file",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "CA Key file",
+		},
+		cli.StringFlag{
+			Name:  "ca-chain",
+			Value: "",
+			Usage: "File with the intermediate certificates above the CA, used for the --chain and --fullchain output",
+		},
+	}, caKeyPassFlags, caSignerFlags, dbFlags, serialFlags, []cli.Flag{
+		cli.StringFlag{
+			Name:  "crt",
+			Value: "certificate.crt",
+			Usage: "Filename to write certificate to",
+		},
+		cli.StringFlag{
+			Name:  "key",
+			Value: "certificate.key",
+			Usage: "Filename to write key to (not used with --csr)",
+		},
+		cli.StringFlag{
+			Name:  "chain",
+			Value: "",
+			Usage: "Filename to write the chain of intermediate certificates to (without the certificate itself)",
+		},
+		cli.StringFlag{
+			Name:  "fullchain",
+			Value: "",
+			Usage: "Filename to write the certificate followed by the chain of intermediate certificates to",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: formatPEM,
+			Usage: "Output format: pem, or p12 or jks to write the key, certificate and chain to a password protected keystore",
+		},
+		cli.StringFlag{
+			Name:  "csr",
+			Value: "",
+			Usage: "Give the filepath to an existing CSR to sign its public key, only the certificate is written",
+		},
+	}, subjectFlags, []cli.Flag{
+		cli.StringFlag{
+			Name:  "name-serialnumber",
+			Value: "",
+			Usage: "Name SerialNumber",
+		},
+		cli.Int64Flag{
+			Name:  "serialnumber",
+			Value: 0,
+			Usage: "SerialNumber",
 		},
-		{
-			Name:    "generate",
-			Aliases: []string{"gen"},
-			Usage:   "Generate a signed certificate pair",
-			Flags: []cli.Flag{
-				cli.BoolFlag{
-					Name:  "stdout",
-					Usage: "Send pem to stdout instead of to file",
-				},
-				cli.StringFlag{
-					Name:  "ca",
-					Value: "ca.crt",
-					Usage: "CA Certificate file",
-				},
-				cli.StringFlag{
-					Name:  "ca-key",
-					Value: "ca.key",
-					Usage: "CA Key file",
-				},
-				cli.StringFlag{
-					Name:  "ca-DB-type",
-					Value: "file",
-					Usage: "CA DB type (file)",
-				},
-
-				cli.StringFlag{
-					Name:  "ca-DB-file",
-					Value: "file.DB",
-					Usage: "File DB filename",
-				},
-				cli.StringFlag{
-					Name:  "ca-DB-sql-dialect",
-					Value: "mysql",
-					Usage: "SQL Dialect",
-				},
-				cli.StringFlag{
-					Name:  "ca-DB-sql-cs",
-					Value: "user:pass@tcp(localhost:3306)/test?charset=utf8&parseTime=True",
-					Usage: "SQL Connection String",
-				},
-				cli.StringFlag{
-					Name:  "crt",
-					Value: "certificate.crt",
-					Usage: "Filename to write certificate to",
-				},
-				cli.StringFlag{
-					Name:  "key",
-					Value: "certificate.key",
-					Usage: "Filename to write key to",
-				},
-				cli.StringFlag{
-					Name:  "csr",
-					Value: "",
-					Usage: "Give the filepath to an existing CSR if you want to sign using a pre-existing CSR",
-				},
-				cli.StringFlag{
-					Name:  "cn",
-					Value: "",
-					Usage: "Common name attached to the cert",
-				},
-				cli.StringFlag{
-					Name:  "org",
-					Value: "",
-					Usage: "Organisation",
-				},
-				cli.StringFlag{
-					Name:  "country",
-					Value: "",
-					Usage: "Country",
-				},
-				cli.StringFlag{
-					Name:  "province",
-					Value: "",
-					Usage: "Province",
-				},
-				cli.StringFlag{
-					Name:  "locality",
-					Value: "",
-					Usage: "Locality",
-				},
-				cli.StringFlag{
-					Name:  "postalcode",
-					Value: "",
-					Usage: "PostalCode",
-				},
-				cli.StringFlag{
-					Name:  "streetaddress",
-					Value: "",
-					Usage: "StreetAddress",
-				},
-				cli.StringFlag{
-					Name:  "name-serialnumber",
-					Value: "",
-					Usage: "Name SerialNumber",
-				},
-				cli.Int64Flag{
-					Name:  "serialnumber",
-					Value: 0,
-					Usage: "SerialNumber",
-				},
-				cli.StringSliceFlag{
-					Name:  "subject-alt-name",
-					Usage: "Subject Alt Name",
-				},
-				cli.StringFlag{
-					Name:  "notbefore",
-					Value: time.Now().String(),
-					Usage: "NotBefore sets the NotBefore timestamp of the certificate request",
-				},
-				cli.StringFlag{
-					Name:  "notafter",
-					Value: time.Now().AddDate(0, 0, 30).String(),
-					Usage: "NotAfter sets the NotAfter timestamp of the certificate request. The default is 30 days from now.",
-				},
-				cli.StringFlag{
-					Name:  "timezone",
-					Value: "UTC",
-					Usage: "Timezone",
-				},
-				cli.IntFlag{
-					Name:  "bitsize",
-					Value: 4096,
-					Usage: "Encryption key bitsize",
-				},
-			},
-			Action: func(c *cli.Context) error {
-
-				var cr *cert.Request
-				if c.String("csr") != "" {
-					csrFile, err := ioutil.ReadFile(c.String("csr"))
-					if err != nil {
-						fmt.Printf("Error reading CSR: %s\n", err.Error())
-						return err
-					}
-
-					cr, err = cert.ReadCSR(csrFile)
-					if err != nil {
-						fmt.Printf("Error reading CSR: %s\n", err.Error())
-						return err
-					}
-				} else {
-					cr = cert.NewRequest()
-					cr.CommonName = c.String("cn")
-					cr.Organization = c.String("org")
-					cr.Country = c.String("country")
-					cr.Province = c.String("province")
-					cr.Locality = c.String("locality")
-					cr.PostalCode = c.String("postalcode")
-					cr.StreetAddress = c.String("streetaddress")
-					cr.NameSerialNumber = c.String("name-serialnumber")
-					cr.BitSize = c.Int("bitsize")
-
-					cr.SubjectAltNames = c.StringSlice("subject-alt-name")
-
-					if cr.NameSerialNumber == "" {
-						// Generating serial number
-						sn, err := uuid.NewRandom()
-						if err != nil {
-							fmt.Printf("Error generating serial number: %s\n", err.Error())
-							return err
-						}
-						cr.NameSerialNumber = sn.String()
-					}
-				}
-
-				if c.Int64("serialnumber") != 0 {
-					cr.SerialNumber = big.NewInt(c.Int64("serialnumber"))
-				} else {
-					cr.SerialNumber, _ = cert.GenerateRandomBigInt()
-				}
-
-				p, err := parsetime.NewParseTime(c.String("timezone"))
-				if err != nil {
-					fmt.Printf("Error parsing --timezone: %s\n", err.Error())
-					return err
-				}
-
-				if c.String("notbefore") != "" {
-					notBefore, err := p.Parse(c.String("notbefore"))
-					if err != nil {
-						fmt.Printf("Error parsing --notbefore: %s\n", err.Error())
-						return err
-					}
-					cr.NotBefore = notBefore
-				}
-
-				if c.String("notafter") != "" {
-					notAfter, err := p.Parse(c.String("notafter"))
-					if err != nil {
-						fmt.Printf("Error parsing --notafter: %s\n", err.Error())
-						return err
-					}
-					cr.NotAfter = notAfter
-				}
-
-				caCrt, err := ioutil.ReadFile(c.String("ca"))
-				if err != nil {
-					fmt.Printf("Error reading CA certificate: %s\n", err.Error())
-					return err
-				}
-				caKey, err := ioutil.ReadFile(c.String("ca-key"))
-				if err != nil {
-					fmt.Printf("Error reading CA key: %s\n", err.Error())
-					return err
-				}
-
-				// DB
-				var DB database.DB
-				switch c.String("ca-DB-type") {
-				case "sql":
-					DB = sql.NewDB(c.String("ca-DB-sql-dialect"), c.String("ca-DB-sql-cs"))
-				case "file":
-					DB = file.NewDB(c.String("ca-DB-file"))
-				}
-
-				if DB == nil {
-					return database.ErrorNilConnection
-				}
-
-				if err := DB.Open(); err != nil {
-					fmt.Printf("Error opening DB: %s\n", err.Error())
-					return err
-				}
-				defer DB.Close()
-
-				if err := DB.Provision(); err != nil {
-					fmt.Printf("Error provisioning DB: %s\n", err.Error())
-					return err
-				}
-
-				crt, key, err := cert.GenerateCertificate(cr, caCrt, caKey)
-				if err != nil {
-					fmt.Printf("Error generating certificate: %s\n", err.Error())
-					return err
-				}
-
-				// Store in CA DB
-				DBCert := database.NewCertificate()
-				DBCert.Status = "valid"
-				DBCert.ExpirationDate = cr.NotAfter
-				DBCert.RevocationDate = nil
-				DBCert.SerialNumber = cr.SerialNumber
-				DBCert.NameSerialNumber = cr.NameSerialNumber
-				DBCert.CommonName = cr.CommonName
-
-				if err := DB.GetCertificateRepository().Create(DBCert); err != nil {
-					fmt.Printf("Error saving certificate to DB: %s\n", err.Error())
-					return err
-				}
-
-				if c.Bool("stdout") {
-					fmt.Println(string(key))
-					fmt.Println(string(crt))
-					return nil
-				}
-
-				fmt.Printf("Generated certificate with serial number %s\n", cr.SerialNumber)
-				fmt.Printf("Writing certificate to %s\n", c.String("crt"))
-				if err := ioutil.WriteFile(c.String("crt"), crt, 0600); err != nil {
-					fmt.Printf("Error writing certificate to file: %s\n", err.Error())
-					return err
-				}
-				fmt.Printf("Writing key to %s\n", c.String("key"))
-				if err := ioutil.WriteFile(c.String("key"), key, 0600); err != nil {
-					fmt.Printf("Error writing certificate key to file: %s\n", err.Error())
-					return err
-				}
-				return nil
-			},
+		cli.StringSliceFlag{
+			Name:  "subject-alt-name",
+			Usage: "Subject Alt Name: DNS name, IP address, email:<address> or uri:<uri>",
 		},
+	}, validityFlags, keyFlags, keyPassFlags, profileFlags, policyFlags, keystoreFlags),
+	Action: func(c *cli.Context) error {
+
+		switch {
+		case c.String("format") != formatPEM && c.String("format") != formatP12 && c.String("format") != formatJKS:
+			fmt.Printf("Error parsing --format: %s\n", errorInvalidFormat.Error())
+			return errorInvalidFormat
+		case c.String("format") != formatPEM && c.String("csr") != "":
+			fmt.Printf("Error: %s\n", errorMissingKey.Error())
+			return errorMissingKey
+		}
+
+		var password string
+		if c.String("format") != formatPEM {
+			var err error
+			password, err = readPassword(c)
+			if err != nil {
+				return err
+			}
+		}
+
+		var cr *cert.Request
+		var csrFile []byte
+		if c.String("csr") != "" {
+			var err error
+			csrFile, err = ioutil.ReadFile(c.String("csr"))
+			if err != nil {
+				fmt.Printf("Error reading CSR: %s\n", err.Error())
+				return err
+			}
+
+			cr, err = cert.ReadCSR(csrFile)
+			if err != nil {
+				fmt.Printf("Error reading CSR: %s\n", err.Error())
+				return err
+			}
+		} else {
+			cr = cert.NewRequest()
+			setSubject(c, cr)
+			setKey(c, cr)
+			cr.SubjectAltNames = c.StringSlice("subject-alt-name")
+		}
+
+		if c.String("name-serialnumber") != "" {
+			cr.NameSerialNumber = c.String("name-serialnumber")
+		}
+		if cr.NameSerialNumber == "" {
+			// Generating serial number
+			sn, err := uuid.NewRandom()
+			if err != nil {
+				fmt.Printf("Error generating serial number: %s\n", err.Error())
+				return err
+			}
+			cr.NameSerialNumber = sn.String()
+		}
+
+		if err := setValidity(c, cr); err != nil {
+			return err
+		}
+
+		if err := setProfile(c, cr); err != nil {
+			return err
+		}
+
+		policy, err := readPolicy(c)
+		if err != nil {
+			return err
+		}
+		cr.Policy = policy
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		caSigner, err := openCASigner(c, caCrt)
+		if err != nil {
+			fmt.Printf("Error reading CA key: %s\n", err.Error())
+			return err
+		}
+		defer caSigner.Close()
+
+		var caChain []byte
+		if c.String("ca-chain") != "" {
+			caChain, err = ioutil.ReadFile(c.String("ca-chain"))
+			if err != nil {
+				fmt.Printf("Error reading CA chain: %s\n", err.Error())
+				return err
+			}
+		}
+		cr.CAChain = caChain
+
+		// DB
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		serials, err := openSerialGenerator(c, DB)
+		if err != nil {
+			return err
+		}
+
+		// A CSR brings its own public key, only the certificate is issued
+		var crt, key []byte
+		issue := func(serialNumber *big.Int) error {
+			cr.SerialNumber = serialNumber
+			if csrFile != nil {
+				crt, err = cert.SignCSRWithSigner(cr, csrFile, caCrt, caSigner)
+			} else {
+				crt, key, err = cert.GenerateCertificateWithSigner(cr, caCrt, caSigner)
+			}
+			if err != nil {
+				fmt.Printf("Error generating certificate: %s\n", err.Error())
+				return err
+			}
+			return createCertificate(DB.GetCertificateRepository(), cr, crt, nil, false)
+		}
+
+		// A taken serial number is only replaced when it is generated
+		if c.Int64("serialnumber") != 0 {
+			err = issue(big.NewInt(c.Int64("serialnumber")))
+		} else {
+			err = database.WithUniqueSerialNumber(DB.GetCertificateRepository(), serials, issue)
+		}
+		if err == database.ErrorDuplicateObject {
+			fmt.Printf("Error saving certificate to DB: %s\n", err.Error())
+		}
+		if err != nil {
+			return err
+		}
+
+		// Keystores are protected by their own password
+		if key != nil && c.String("format") == formatPEM {
+			key, err = encryptKey(c, key, "key-pass")
+			if err != nil {
+				fmt.Printf("Error encrypting key: %s\n", err.Error())
+				return err
+			}
+		}
+
+		if c.String("format") != formatPEM {
+			chain, err := cert.Chain(caCrt, caChain)
+			if err != nil {
+				fmt.Printf("Error building chain: %s\n", err.Error())
+				return err
+			}
+			return writeKeystore(c, c.String("format"), crt, key, chain, password)
+		}
+
+		if c.Bool("stdout") {
+			if key != nil {
+				fmt.Println(string(key))
+			}
+			fmt.Println(string(crt))
+			return nil
+		}
+
+		fmt.Printf("Generated certificate with serial number %s\n", cr.SerialNumber)
+		fmt.Printf("Writing certificate to %s\n", c.String("crt"))
+		if err := ioutil.WriteFile(c.String("crt"), crt, 0600); err != nil {
+			fmt.Printf("Error writing certificate to file: %s\n", err.Error())
+			return err
+		}
+		if key != nil {
+			fmt.Printf("Writing key to %s\n", c.String("key"))
+			if err := ioutil.WriteFile(c.String("key"), key, 0600); err != nil {
+				fmt.Printf("Error writing certificate key to file: %s\n", err.Error())
+				return err
+			}
+		}
+
+		if c.String("chain") != "" {
+			chain, err := cert.Chain(caCrt, caChain)
+			if err != nil {
+				fmt.Printf("Error building chain: %s\n", err.Error())
+				return err
+			}
+			fmt.Printf("Writing chain to %s\n", c.String("chain"))
+			if err := ioutil.WriteFile(c.String("chain"), chain, 0644); err != nil {
+				fmt.Printf("Error writing chain to file: %s\n", err.Error())
+				return err
+			}
+		}
+
+		if c.String("fullchain") != "" {
+			fullchain, err := cert.Chain(crt, caCrt, caChain)
+			if err != nil {
+				fmt.Printf("Error building chain: %s\n", err.Error())
+				return err
+			}
+			fmt.Printf("Writing full chain to %s\n", c.String("fullchain"))
+			if err := ioutil.WriteFile(c.String("fullchain"), fullchain, 0644); err != nil {
+				fmt.Printf("Error writing full chain to file: %s\n", err.Error())
+				return err
+			}
+		}
+
+		return nil
 	},
 }
@@ -1,15 +1,46 @@
 package main
 
 import (
+	"bytes"
+	"crypto/tls"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/mvmaasakkers/certificates/database/file"
+	"io/ioutil"
+	"math/big"
 	"os"
 	"testing"
+	"time"
 )
 
-func Test_run(t *testing.T) {
-	// Clean up files first
+func cleanupFiles() {
 	os.Remove("ca.crt")
 	os.Remove("ca.key")
-	os.Remove("file.db")
+	os.Remove("certificate.crt")
+	os.Remove("certificate.key")
+	os.Remove("certificate.csr")
+	os.Remove("certificate.p12")
+	os.Remove("converted.p12")
+	os.Remove("converted.jks")
+	os.Remove("certificate.jks")
+	os.Remove("truststore.jks")
+	os.Remove("file.DB")
+	os.Remove("file.DB.lock")
+	os.Remove("intermediate.crt")
+	os.Remove("intermediate.key")
+	os.Remove("chain.crt")
+	os.Remove("fullchain.crt")
+	os.Remove("ca.crl")
+	os.Remove("intermediate.crl")
+	os.Remove("ocsp.crt")
+	os.Remove("ocsp.key")
+	os.Remove("passphrase.txt")
+}
+
+func Test_run(t *testing.T) {
+	// Clean up files first
+	cleanupFiles()
+	defer cleanupFiles()
 
 	type args struct {
 		args []string
@@ -54,6 +85,34 @@ func Test_run(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid-ca-ecdsa-stdout",
+			args: args{
+				args: []string{"cert", "gen-ca", "--cn=common.test.name", "--stdout", "--key-type=ecdsa"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-ca-truststore",
+			args: args{
+				args: []string{"cert", "gen-ca", "--cn=common.test.name", "--stdout", "--key-type=ecdsa", "--truststore=truststore.jks", "--password=secret"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-ca-truststore-password",
+			args: args{
+				args: []string{"cert", "gen-ca", "--cn=common.test.name", "--stdout", "--key-type=ecdsa", "--truststore=truststore.jks"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-ca-curve",
+			args: args{
+				args: []string{"cert", "gen-ca", "--cn=common.test.name", "--stdout", "--key-type=ecdsa", "--curve=P-192"},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid-ca-timezone",
 			args: args{
@@ -76,10 +135,199 @@ func Test_run(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "valid-crt-duplicate",
+			name: "valid-crt-same-cn",
 			args: args{
 				args: []string{"cert", "gen", "--cn=common.test.name", "--stdout"},
 			},
+			wantErr: false,
+		},
+		{
+			name: "valid-crt-serial",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.serial", "--stdout", "--key-type=ecdsa", "--serialnumber=4242"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crt-duplicate-serial",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.serial.two", "--stdout", "--key-type=ecdsa", "--serialnumber=4242"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-crt-name-serial",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.serial.three", "--stdout", "--key-type=ecdsa", "--name-serialnumber=name-serial-4242"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crt-duplicate-name-serial",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.serial.four", "--stdout", "--key-type=ecdsa", "--name-serialnumber=name-serial-4242"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-crt-ecdsa",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.ecdsa", "--stdout", "--key-type=ecdsa", "--curve=P-384"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-crt-ed25519",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.ed25519", "--stdout", "--key-type=ed25519"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crt-key-type",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.dsa", "--stdout", "--key-type=dsa"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-crt-subject-alt-names",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.sans", "--stdout", "--key-type=ecdsa", "--subject-alt-name=common.test.name.sans", "--subject-alt-name=127.0.0.1", "--subject-alt-name=email:admin@test.name", "--subject-alt-name=uri:spiffe://test.name/service"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crt-subject-alt-name",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.invalid.san", "--stdout", "--key-type=ecdsa", "--subject-alt-name=email:admin"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-crt-profile",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.profile", "--stdout", "--key-type=ecdsa", "--profile=server"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crt-profile",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.invalid.profile", "--stdout", "--key-type=ecdsa", "--profile=unknown"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-crt-profiles-file",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.invalid.profiles", "--stdout", "--key-type=ecdsa", "--profiles=missing.json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-convert-p12",
+			args: args{
+				args: []string{"cert", "convert", "--password=secret", "--keystore=converted.p12"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-convert-jks",
+			args: args{
+				args: []string{"cert", "convert", "--format=jks", "--alias=server", "--password=secret", "--keystore=converted.jks"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-convert-missing-password",
+			args: args{
+				args: []string{"cert", "convert", "--keystore=converted.p12"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-convert-password-file",
+			args: args{
+				args: []string{"cert", "convert", "--password-file=missing.txt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-convert-format",
+			args: args{
+				args: []string{"cert", "convert", "--password=secret", "--format=der"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-crt-p12",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.p12", "--key-type=ecdsa", "--format=p12", "--password=secret"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-crt-jks",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.jks", "--key-type=ecdsa", "--format=jks", "--password=secret"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crt-p12-missing-password",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.p12.two", "--key-type=ecdsa", "--format=p12"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-crt-format",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.der", "--key-type=ecdsa", "--format=der"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-crt-policy-file",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.invalid.policy", "--stdout", "--key-type=ecdsa", "--policy=missing.json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-csr",
+			args: args{
+				args: []string{"cert", "gen-csr", "--cn=common.test.name.csr", "--key-type=ecdsa", "--subject-alt-name=common.test.name.csr"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-crt-csr",
+			args: args{
+				args: []string{"cert", "gen", "--csr=certificate.csr", "--stdout"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crt-csr-p12",
+			args: args{
+				args: []string{"cert", "gen", "--csr=certificate.csr", "--format=p12", "--password=secret"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-csr-stdout",
+			args: args{
+				args: []string{"cert", "gen-csr", "--cn=common.test.name.csr", "--key-type=ed25519", "--stdout"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-csr",
+			args: args{
+				args: []string{"cert", "gen-csr", "--key-type=ecdsa", "--stdout"},
+			},
 			wantErr: true,
 		},
 		{
@@ -90,38 +338,815 @@ func Test_run(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "invalid-timezone",
+			name: "valid-intermediate",
 			args: args{
-				args: []string{"cert", "gen", "--cn=common.test.name.two", "--stdout", "--notbefore=2019-01-01", "--notafter=2019-10-20", "--timezone=ASISJDOI.JAOSIJD"},
+				args: []string{"cert", "gen-intermediate", "--cn=intermediate.test.name", "--key-type=ecdsa"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-intermediate-stdout",
+			args: args{
+				args: []string{"cert", "gen-intermediate", "--cn=intermediate.test.name", "--stdout", "--key-type=ecdsa", "--max-path-len=-1"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-intermediate-parent",
+			args: args{
+				args: []string{"cert", "gen-intermediate", "--cn=intermediate.test.name", "--ca=notfound.crt"},
 			},
 			wantErr: true,
 		},
 		{
-			name: "invalid-notbefore",
+			name: "invalid-intermediate-path-len",
 			args: args{
-				args: []string{"cert", "gen", "--cn=common.test.name.two", "--stdout", "--notbefore=invalid", "--notafter=2019-10-20"},
+				args: []string{"cert", "gen-intermediate", "--cn=sub.intermediate.test.name", "--ca=intermediate.crt", "--ca-key=intermediate.key"},
 			},
 			wantErr: true,
 		},
 		{
-			name: "invalid-notafter",
+			name: "valid-crt-fullchain",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.chain", "--key-type=ecdsa", "--ca=intermediate.crt", "--ca-key=intermediate.key", "--ca-chain=ca.crt", "--chain=chain.crt", "--fullchain=fullchain.crt"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-crt-revocable",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.revoke", "--stdout", "--key-type=ecdsa", "--serialnumber=424242", "--name-serialnumber=revoke-me"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-revoke",
+			args: args{
+				args: []string{"cert", "revoke", "--serial=424242", "--reason=keyCompromise"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-revoke-already-revoked",
 			args: args{
-				args: []string{"cert", "gen", "--cn=common.test.name.two", "--stdout", "--notbefore=2019-01-01", "--notafter=invalid"},
+				args: []string{"cert", "revoke", "--name-serial=revoke-me"},
 			},
 			wantErr: true,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			args := os.Args[0:1]
-			args = append(args, tt.args.args...)
-			if err := run(args); (err != nil) != tt.wantErr {
-				t.Errorf("run() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-
-	if err := run(nil); (err != nil) != false {
-		t.Errorf("run() error = %v, wantErr %v", err, false)
+		{
+			name: "invalid-revoke-not-found",
+			args: args{
+				args: []string{"cert", "revoke", "--serial=0x1"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-revoke-missing-serial",
+			args: args{
+				args: []string{"cert", "revoke"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-renew-serial",
+			args: args{
+				args: []string{"cert", "renew", "--serial=4242", "--stdout"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-renew-cn-revoke",
+			args: args{
+				args: []string{"cert", "renew", "--cn=common.test.name.serial.three", "--revoke", "--stdout"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-renew-already-revoked",
+			args: args{
+				args: []string{"cert", "renew", "--name-serial=revoke-me", "--revoke", "--stdout"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-renew-ambiguous-cn",
+			args: args{
+				args: []string{"cert", "renew", "--cn=common.test.name", "--stdout"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-renew-not-found",
+			args: args{
+				args: []string{"cert", "renew", "--serial=0x1", "--stdout"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-renew-missing-serial",
+			args: args{
+				args: []string{"cert", "renew"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-crl",
+			args: args{
+				args: []string{"cert", "crl"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-crl-der-stdout",
+			args: args{
+				args: []string{"cert", "crl", "--format=der", "--stdout", "--next-update=1h"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-crl-format",
+			args: args{
+				args: []string{"cert", "crl", "--format=txt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-crl-ca",
+			args: args{
+				args: []string{"cert", "crl", "--ca=notfound.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-crl-ca-signer-scheme",
+			args: args{
+				args: []string{"cert", "crl", "--ca-signer=kms:ca"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-crt-ca-signer-uri",
+			args: args{
+				args: []string{"cert", "gen", "--cn=signer.test.name", "--ca-signer=pkcs11:token=ca;object=ca"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-ocsp-signer",
+			args: args{
+				args: []string{"cert", "gen-ocsp-signer", "--cn=ocsp.test.name", "--key-type=ecdsa"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-ocsp-signer-ca",
+			args: args{
+				args: []string{"cert", "gen-ocsp-signer", "--cn=ocsp.test.name.two", "--ca=notfound.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-ocsp-serve-ca",
+			args: args{
+				args: []string{"cert", "ocsp-serve", "--ca=notfound.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-ocsp-serve-responder",
+			args: args{
+				args: []string{"cert", "ocsp-serve", "--responder-crt=certificate.crt", "--responder-key=certificate.key"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-ocsp-serve-missing-responder-key",
+			args: args{
+				args: []string{"cert", "ocsp-serve", "--responder-crt=ocsp.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-ocsp-serve-interval",
+			args: args{
+				args: []string{"cert", "ocsp-serve", "--responder-crt=ocsp.crt", "--responder-key=ocsp.key", "--update-interval=0s"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-acme-serve-ca",
+			args: args{
+				args: []string{"cert", "acme-serve", "--ca=notfound.crt", "--base-url=https://ca.test.local/acme"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-acme-serve-ca-chain",
+			args: args{
+				args: []string{"cert", "acme-serve", "--ca-chain=notfound.crt", "--base-url=https://ca.test.local/acme"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-acme-serve-missing-base-url",
+			args: args{
+				args: []string{"cert", "acme-serve"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-acme-serve-listen",
+			args: args{
+				args: []string{"cert", "acme-serve", "--base-url=https://ca.test.local/acme", "--listen=invalid"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-serve-ca",
+			args: args{
+				args: []string{"cert", "serve", "--ca=notfound.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-serve-roles-file",
+			args: args{
+				args: []string{"cert", "serve", "--roles=notfound.json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-serve-roles",
+			args: args{
+				args: []string{"cert", "serve", "--roles=ca.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-serve-tls-crt",
+			args: args{
+				args: []string{"cert", "serve", "--listen=127.0.0.1:0", "--tls-crt=notfound.crt", "--tls-key=notfound.key"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-list",
+			args: args{
+				args: []string{"cert", "list"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-list-filter-json",
+			args: args{
+				args: []string{"cert", "list", "--status=revoked", "--cn=revoke", "--expires-before=2100-01-01", "--issued-after=2019-01-01", "--offset=0", "--limit=10", "--format=json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-show",
+			args: args{
+				args: []string{"cert", "show", "--serial=424242"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-show-pem",
+			args: args{
+				args: []string{"cert", "show", "--name-serial=revoke-me", "--format=pem"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-show-json",
+			args: args{
+				args: []string{"cert", "show", "--serial=0x67932", "--format=json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-show-not-found",
+			args: args{
+				args: []string{"cert", "show", "--serial=1"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-show-zero-serial",
+			args: args{
+				args: []string{"cert", "show", "--serial=0"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-show-missing-serial",
+			args: args{
+				args: []string{"cert", "show"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-show-format",
+			args: args{
+				args: []string{"cert", "show", "--serial=424242", "--format=der"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-inspect",
+			args: args{
+				args: []string{"cert", "inspect", "ca.crt"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-inspect-key-json",
+			args: args{
+				args: []string{"cert", "inspect", "--crt=ca.crt", "--format=json", "ca.key"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-inspect-crl",
+			args: args{
+				args: []string{"cert", "inspect", "ca.crl"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-inspect-missing-file",
+			args: args{
+				args: []string{"cert", "inspect"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-inspect-not-found",
+			args: args{
+				args: []string{"cert", "inspect", "missing.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-inspect-unknown-object",
+			args: args{
+				args: []string{"cert", "inspect", "main.go"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-inspect-crt",
+			args: args{
+				args: []string{"cert", "inspect", "--crt=ca.key", "ca.key"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-inspect-format",
+			args: args{
+				args: []string{"cert", "inspect", "--format=pem", "ca.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-verify",
+			args: args{
+				args: []string{"cert", "verify", "--usage=server-auth", "--format=json", "ca.crt"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-verify-failed",
+			args: args{
+				args: []string{"cert", "verify", "--host=other.test.local", "ca.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-verify-missing-file",
+			args: args{
+				args: []string{"cert", "verify"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-verify-ca",
+			args: args{
+				args: []string{"cert", "verify", "--ca=missing.crt", "ca.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-verify-usage",
+			args: args{
+				args: []string{"cert", "verify", "--usage=everything", "ca.crt"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-list-status",
+			args: args{
+				args: []string{"cert", "list", "--status=expired"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-list-format",
+			args: args{
+				args: []string{"cert", "list", "--format=xml"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-list-timezone",
+			args: args{
+				args: []string{"cert", "list", "--expires-before=2100-01-01", "--timezone=ASISJDOI.JAOSIJD"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-revoke-reason",
+			args: args{
+				args: []string{"cert", "revoke", "--serial=424242", "--reason=lost"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-timezone",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.two", "--stdout", "--notbefore=2019-01-01", "--notafter=2019-10-20", "--timezone=ASISJDOI.JAOSIJD"},
+			},
+			wantErr: true,
+		},
+		// parsetime falls back to the current time for input it can not parse, a NotAfter before that is rejected
+		{
+			name: "lenient-notbefore",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.three", "--stdout", "--key-type=ecdsa", "--notbefore=invalid", "--notafter=2019-10-20"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "lenient-notafter",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.four", "--stdout", "--key-type=ecdsa", "--notbefore=2019-01-01", "--notafter=invalid"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-validity",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.five", "--stdout", "--key-type=ecdsa", "--validity=90d", "--backdate=5m"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-validity",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.five", "--stdout", "--key-type=ecdsa", "--validity=90x"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-backdate",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.five", "--stdout", "--key-type=ecdsa", "--backdate=-5m"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "conflicting-validity",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.five", "--stdout", "--key-type=ecdsa", "--validity=1y", "--notafter=2030-01-01"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid-sequential-serial",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.six", "--stdout", "--key-type=ecdsa", "--serial-type=sequential"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid-prefixed-serial",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.six", "--stdout", "--key-type=ecdsa", "--serial-type=prefixed", "--serial-prefix=0x2a01"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid-serial-prefix",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.six", "--stdout", "--key-type=ecdsa", "--serial-type=prefixed", "--serial-prefix=ff"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid-serial-type",
+			args: args{
+				args: []string{"cert", "gen", "--cn=common.test.name.six", "--stdout", "--key-type=ecdsa", "--serial-type=counter"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := os.Args[0:1]
+			args = append(args, tt.args.args...)
+			if err := run(args); (err != nil) != tt.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := run(nil); (err != nil) != false {
+		t.Errorf("run() error = %v, wantErr %v", err, false)
+	}
+}
+
+func Test_run_signCSR(t *testing.T) {
+	cleanupFiles()
+	defer cleanupFiles()
+
+	for _, args := range [][]string{
+		{"cert", "gen-ca", "--cn=ca.test.name", "--key-type=ecdsa"},
+		{"cert", "gen-csr", "--cn=csr.test.name", "--key-type=ecdsa"},
+	} {
+		if err := run(append(os.Args[0:1], args...)); err != nil {
+			t.Fatalf("run(%v) error = %v", args, err)
+		}
+	}
+
+	key, err := ioutil.ReadFile("certificate.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(append(os.Args[0:1], "cert", "gen", "--csr=certificate.csr")); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	// The key of the CSR is kept and used for the certificate
+	keyAfter, err := ioutil.ReadFile("certificate.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, keyAfter) {
+		t.Errorf("signing the CSR overwrote the key")
+	}
+
+	crt, err := ioutil.ReadFile("certificate.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tls.X509KeyPair(crt, key); err != nil {
+		t.Errorf("certificate does not match the CSR key: %s", err)
+	}
+}
+
+func Test_run_encryptedKey(t *testing.T) {
+	cleanupFiles()
+	defer cleanupFiles()
+
+	if err := ioutil.WriteFile("passphrase.txt", []byte("ca secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "gen-ca", args: []string{"cert", "gen-ca", "--cn=ca.test.name", "--key-type=ecdsa", "--ca-key-pass-file=passphrase.txt"}},
+		{name: "gen-missing-passphrase", args: []string{"cert", "gen", "--cn=cert.test.name"}, wantErr: true},
+		{name: "gen-incorrect-passphrase", args: []string{"cert", "gen", "--cn=cert.test.name", "--ca-key-pass=wrong"}, wantErr: true},
+		{name: "gen", args: []string{"cert", "gen", "--cn=cert.test.name", "--key-type=ecdsa", "--ca-key-pass=ca secret", "--key-pass=secret"}},
+		{name: "convert-missing-passphrase", args: []string{"cert", "convert", "--password=secret", "--keystore=converted.p12"}, wantErr: true},
+		{name: "convert", args: []string{"cert", "convert", "--password=secret", "--keystore=converted.p12", "--key-pass=secret"}},
+		{name: "crl", args: []string{"cert", "crl", "--ca-key-pass-file=passphrase.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := run(append(os.Args[0:1], tt.args...)); (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	for _, filename := range []string{"ca.key", "certificate.key"} {
+		key, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cert.IsEncryptedKey(key) {
+			t.Errorf("%s is not encrypted", filename)
+		}
+	}
+}
+
+func Test_run_renew(t *testing.T) {
+	cleanupFiles()
+	defer cleanupFiles()
+
+	for _, args := range [][]string{
+		{"cert", "gen-ca", "--cn=ca.test.name", "--key-type=ecdsa"},
+		{"cert", "gen", "--cn=renew.test.name", "--key-type=ed25519", "--profile=server", "--serialnumber=1001"},
+	} {
+		if err := run(append(os.Args[0:1], args...)); err != nil {
+			t.Fatalf("run(%v) error = %v", args, err)
+		}
+	}
+
+	key, err := ioutil.ReadFile("certificate.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(append(os.Args[0:1], "cert", "renew", "--serial=1001", "--reuse-key", "--revoke")); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	// The existing key is kept and used for the renewal
+	crt, err := ioutil.ReadFile("certificate.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tls.X509KeyPair(crt, key); err != nil {
+		t.Errorf("renewal does not match the existing key: %s", err)
+	}
+
+	DB := file.NewDB("file.DB")
+	if err := DB.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+
+	old, err := DB.GetCertificateRepository().GetBySerialNumber(big.NewInt(1001))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Status != database.StatusRevoked || old.RevocationReason != cert.ReasonSuperseded || old.RenewedBy == "" {
+		t.Errorf("renewed certificate = %+v, want it revoked as superseded and linked to the renewal", old)
+	}
+
+	renewedBy, ok := new(big.Int).SetString(old.RenewedBy, 10)
+	if !ok {
+		t.Fatalf("renewed by = %q, want a serial number", old.RenewedBy)
+	}
+	renewal, err := DB.GetCertificateRepository().GetBySerialNumber(renewedBy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewal.Renews != "1001" || renewal.Profile != cert.ProfileServer {
+		t.Errorf("renewal = %+v, want it linked to 1001 with the server profile", renewal)
+	}
+
+	// The renewal has a new name serial number, in its subject as well as in the CA DB
+	if got := nameSerialNumber(t, crt); got != renewal.NameSerialNumber || got == old.NameSerialNumber {
+		t.Errorf("renewal subject serialNumber = %q, want the new name serial number %q", got, renewal.NameSerialNumber)
+	}
+
+	// Nothing is stored when the renewal can not be written
+	if err := run(append(os.Args[0:1], "cert", "renew", "--serial="+renewal.SerialNumber.String(), "--revoke", "--crt=missing/certificate.crt")); err == nil {
+		t.Fatalf("run() with an unwritable --crt succeeded")
+	}
+	unwritten, err := DB.GetCertificateRepository().GetBySerialNumber(renewal.SerialNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unwritten.RenewedBy != "" || unwritten.Status != database.StatusValid {
+		t.Errorf("certificate = %+v, want it not renewed or revoked", unwritten)
+	}
+}
+
+// nameSerialNumber returns the name serial number in the subject of the PEM encoded certificate
+func nameSerialNumber(t *testing.T, crt []byte) string {
+	req, err := cert.ReadCertificate(crt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req.NameSerialNumber
+}
+
+func Test_run_crlIssuers(t *testing.T) {
+	cleanupFiles()
+	defer cleanupFiles()
+
+	for _, args := range [][]string{
+		{"cert", "gen-ca", "--cn=ca.test.name", "--key-type=ecdsa"},
+		{"cert", "gen-intermediate", "--cn=intermediate.test.name", "--key-type=ecdsa"},
+		{"cert", "gen", "--cn=root.test.name", "--key-type=ecdsa", "--serialnumber=2001"},
+		{"cert", "gen", "--cn=intermediate.test.name", "--key-type=ecdsa", "--serialnumber=2002", "--ca=intermediate.crt", "--ca-key=intermediate.key"},
+		{"cert", "revoke", "--serial=2001"},
+		{"cert", "revoke", "--serial=2002"},
+		{"cert", "crl"},
+		{"cert", "crl"},
+		{"cert", "crl", "--ca=intermediate.crt", "--ca-key=intermediate.key", "--crl=intermediate.crl"},
+	} {
+		if err := run(append(os.Args[0:1], args...)); err != nil {
+			t.Fatalf("run(%v) error = %v", args, err)
+		}
+	}
+
+	// Each CRL only lists the certificates of its CA and is numbered by its CA
+	for _, tt := range []struct {
+		crl       string
+		revoked   string
+		crlNumber string
+	}{
+		{crl: "ca.crl", revoked: "2001", crlNumber: "2"},
+		{crl: "intermediate.crl", revoked: "2002", crlNumber: "1"},
+	} {
+		data, err := ioutil.ReadFile(tt.crl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inspections, err := cert.Inspect(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl := inspections[0]
+		if len(crl.RevokedSerials) != 1 || crl.RevokedSerials[0] != tt.revoked || crl.CRLNumber != tt.crlNumber {
+			t.Errorf("%s revoked = %v, number %s, want [%s], number %s", tt.crl, crl.RevokedSerials, crl.CRLNumber, tt.revoked, tt.crlNumber)
+		}
+	}
+}
+
+func Test_run_nameConstraints(t *testing.T) {
+	cleanupFiles()
+	defer cleanupFiles()
+
+	for _, args := range [][]string{
+		{"cert", "gen-ca", "--cn=ca.test.name", "--key-type=ecdsa"},
+		{"cert", "gen-intermediate", "--cn=payments.test.name", "--key-type=ecdsa", "--permit-dns=*.payments.internal", "--permit-ip=10.20.0.0/16", "--name-constraints-critical"},
+	} {
+		if err := run(append(os.Args[0:1], args...)); err != nil {
+			t.Fatalf("run(%v) error = %v", args, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name: "permitted",
+			args: []string{"--cn=api.payments.internal", "--subject-alt-name=api.payments.internal", "--subject-alt-name=10.20.0.10"},
+		},
+		{
+			name:    "dns-not-permitted",
+			args:    []string{"--cn=www.test.name", "--subject-alt-name=www.test.name"},
+			wantErr: true,
+		},
+		{
+			name:    "ip-not-permitted",
+			args:    []string{"--cn=api.payments.internal", "--subject-alt-name=10.30.0.10"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"cert", "gen", "--stdout", "--key-type=ecdsa", "--ca=intermediate.crt", "--ca-key=intermediate.key"}, tt.args...)
+			if err := run(append(os.Args[0:1], args...)); (err != nil) != tt.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := run(append(os.Args[0:1], "cert", "gen-intermediate", "--cn=invalid.test.name", "--key-type=ecdsa", "--permit-ip=10.20.0.0")); err == nil {
+		t.Errorf("run() error = %v, want an invalid name constraint", err)
+	}
+}
+
+func Test_parseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "90d", want: 90 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "1y", want: 365 * 24 * time.Hour},
+		{in: "5m", want: 5 * time.Minute},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "", wantErr: true},
+		{in: "d", wantErr: true},
+		{in: "1.5d", wantErr: true},
+		{in: "-1d", wantErr: true},
+		{in: "-5m", wantErr: true},
+		{in: "90x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDuration(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration() = %s, want %s", got, tt.want)
+			}
+		})
 	}
 }
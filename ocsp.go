@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/mvmaasakkers/certificates/responder"
+	"github.com/mvmaasakkers/certificates/signer"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+var generateOCSPSignerCommand = cli.Command{
+	Name:        "generate-ocsp-signer",
+	Aliases:     []string{"gen-ocsp-signer"},
+	Usage:       "Generate a delegated OCSP signing certificate pair",
+	Description: `The OCSP signing pair is signed by the CA pair and can be used by ocsp-serve to sign responses on behalf of the CA.`,
+	Flags: flags([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "stdout",
+			Usage: "Send pem to stdout instead of to file",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "CA Key file",
+		},
+		cli.StringFlag{
+			Name:  "crt",
+			Value: "ocsp.crt",
+			Usage: "Filename to write the ocsp signing certificate to",
+		},
+		cli.StringFlag{
+			Name:  "key",
+			Value: "ocsp.key",
+			Usage: "Filename to write the ocsp signing key to",
+		},
+	}, caKeyPassFlags, caSignerFlags, keyPassFlags, dbFlags, serialFlags, subjectFlags, validityFlags, keyFlags),
+	Action: func(c *cli.Context) error {
+
+		cr := cert.NewRequest()
+		setSubject(c, cr)
+		setKey(c, cr)
+
+		sn, err := uuid.NewRandom()
+		if err != nil {
+			fmt.Printf("Error generating serial number: %s\n", err.Error())
+			return err
+		}
+		cr.NameSerialNumber = sn.String()
+
+		if err := setValidity(c, cr); err != nil {
+			return err
+		}
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		caSigner, err := openCASigner(c, caCrt)
+		if err != nil {
+			fmt.Printf("Error reading CA key: %s\n", err.Error())
+			return err
+		}
+		defer caSigner.Close()
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		serials, err := openSerialGenerator(c, DB)
+		if err != nil {
+			return err
+		}
+
+		var crt, key []byte
+		err = database.WithUniqueSerialNumber(DB.GetCertificateRepository(), serials, func(serialNumber *big.Int) error {
+			cr.SerialNumber = serialNumber
+			crt, key, err = cert.GenerateOCSPSignerWithSigner(cr, caCrt, caSigner)
+			if err != nil {
+				fmt.Printf("Error generating OCSP signer: %s\n", err.Error())
+				return err
+			}
+			return createCertificate(DB.GetCertificateRepository(), cr, crt, nil, false)
+		})
+		if err == database.ErrorDuplicateObject {
+			fmt.Printf("Error saving certificate to DB: %s\n", err.Error())
+		}
+		if err != nil {
+			return err
+		}
+
+		key, err = encryptKey(c, key, "key-pass")
+		if err != nil {
+			fmt.Printf("Error encrypting key: %s\n", err.Error())
+			return err
+		}
+
+		if c.Bool("stdout") {
+			fmt.Println(string(key))
+			fmt.Println(string(crt))
+			return nil
+		}
+
+		fmt.Printf("Generated OCSP signer with serial number %s\n", cr.SerialNumber)
+		fmt.Printf("Writing certificate to %s\n", c.String("crt"))
+		if err := ioutil.WriteFile(c.String("crt"), crt, 0600); err != nil {
+			fmt.Printf("Error writing certificate to file: %s\n", err.Error())
+			return err
+		}
+		fmt.Printf("Writing key to %s\n", c.String("key"))
+		if err := ioutil.WriteFile(c.String("key"), key, 0600); err != nil {
+			fmt.Printf("Error writing certificate key to file: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
+
+var ocspServeCommand = cli.Command{
+	Name:  "ocsp-serve",
+	Usage: "Serve an OCSP responder for the certificates in the CA DB",
+	Description: `The responder answers RFC 6960 GET and POST requests. Responses are signed by the CA pair, or by a delegated
+   OCSP signing pair (see generate-ocsp-signer) when --responder-crt and --responder-key are given.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "listen",
+			Value: ":8080",
+			Usage: "Address to listen on",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "CA Key file, used to sign responses when no responder pair is given",
+		},
+		cli.StringFlag{
+			Name:  "responder-crt",
+			Value: "",
+			Usage: "Delegated OCSP signing certificate file",
+		},
+		cli.StringFlag{
+			Name:  "responder-key",
+			Value: "",
+			Usage: "Delegated OCSP signing key file",
+		},
+		cli.DurationFlag{
+			Name:  "update-interval",
+			Value: time.Hour,
+			Usage: "Time between thisUpdate and nextUpdate of the responses. Responses are cached for this interval.",
+		},
+	}, caKeyPassFlags, caSignerFlags, keyPassFlags, dbFlags),
+	Action: func(c *cli.Context) error {
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+
+		// Responses are signed by the CA unless a delegated responder pair is given, the passphrase of the delegated
+		// responder key is given with the key-pass flags
+		responderCrt, responderSigner := caCrt, signer.Signer(nil)
+		if c.String("responder-crt") != "" || c.String("responder-key") != "" {
+			responderCrt, err = ioutil.ReadFile(c.String("responder-crt"))
+			if err != nil {
+				fmt.Printf("Error reading responder certificate: %s\n", err.Error())
+				return err
+			}
+			responderSigner, err = readSigner(c, responderCrt, c.String("responder-key"), "key-pass")
+		} else {
+			responderSigner, err = openCASigner(c, caCrt)
+		}
+		if err != nil {
+			fmt.Printf("Error reading responder key: %s\n", err.Error())
+			return err
+		}
+		defer responderSigner.Close()
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		r, err := responder.NewWithSigner(DB.GetCertificateRepository(), caCrt, responderCrt, responderSigner, c.Duration("update-interval"))
+		if err != nil {
+			fmt.Printf("Error creating OCSP responder: %s\n", err.Error())
+			return err
+		}
+
+		fmt.Printf("Serving OCSP responder on %s\n", c.String("listen"))
+		if err := http.ListenAndServe(c.String("listen"), r); err != nil {
+			fmt.Printf("Error serving OCSP responder: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
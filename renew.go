@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"math/big"
+	"os"
+)
+
+var (
+	errorMissingRenewSerial = errors.New("either --serial, --name-serial or --cn is required")
+	errorAmbiguousCN        = errors.New("more than one valid certificate has this common name, use --serial or --name-serial")
+)
+
+var renewCommand = cli.Command{
+	Name:  "renew",
+	Usage: "Renew a certificate stored in the CA DB",
+	Description: `To renew a certificate you need to supply either the serial number, the name serial number or the common name
+   of a valid certificate. The renewal gets the subject, subject alt names and profile of the stored certificate, a
+   new serial number and a validity of the same length starting now. The subject gets a new name serial number
+   (serialNumber attribute) as well, which is the name serial number of the renewal in the CA DB. A new key of the
+   same type is generated unless --reuse-key is given, which signs the existing key in --key again.
+
+   Both certificates are linked in the CA DB, with --revoke the old certificate is revoked as superseded when the
+   renewal is stored.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "serial",
+			Value: "",
+			Usage: "SerialNumber of the certificate (decimal or 0x prefixed hexadecimal)",
+		},
+		cli.StringFlag{
+			Name:  "name-serial",
+			Value: "",
+			Usage: "Name SerialNumber of the certificate",
+		},
+		cli.StringFlag{
+			Name:  "cn",
+			Value: "",
+			Usage: "Common name of the certificate, only one valid certificate may have it",
+		},
+		cli.BoolFlag{
+			Name:  "reuse-key",
+			Usage: "Reuse the key of the certificate read from --key instead of generating a new one",
+		},
+		cli.BoolFlag{
+			Name:  "revoke",
+			Usage: "Revoke the renewed certificate as superseded",
+		},
+		cli.BoolFlag{
+			Name:  "stdout",
+			Usage: "Send pem to stdout instead of to file",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "CA Key file",
+		},
+	}, caKeyPassFlags, caSignerFlags, dbFlags, serialFlags, []cli.Flag{
+		cli.StringFlag{
+			Name:  "crt",
+			Value: "certificate.crt",
+			Usage: "Filename to write certificate to",
+		},
+		cli.StringFlag{
+			Name:  "key",
+			Value: "certificate.key",
+			Usage: "Filename to write key to, or to read the key from with --reuse-key",
+		},
+	}, validityFlags, keyFlags, keyPassFlags, profileFlags, policyFlags),
+	Action: func(c *cli.Context) error {
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		repo := DB.GetCertificateRepository()
+		old, err := lookupRenewal(c, repo)
+		if err != nil {
+			return err
+		}
+		if old.CertificatePEM == "" {
+			fmt.Printf("Error: %s\n", errorMissingPEM.Error())
+			return errorMissingPEM
+		}
+		if c.Bool("revoke") && old.Status == database.StatusRevoked {
+			fmt.Printf("Error revoking certificate: %s\n", database.ErrorAlreadyRevoked.Error())
+			return database.ErrorAlreadyRevoked
+		}
+
+		cr, err := cert.ReadCertificate([]byte(old.CertificatePEM))
+		if err != nil {
+			fmt.Printf("Error reading certificate: %s\n", err.Error())
+			return err
+		}
+
+		sn, err := uuid.NewRandom()
+		if err != nil {
+			fmt.Printf("Error generating serial number: %s\n", err.Error())
+			return err
+		}
+		cr.NameSerialNumber = sn.String()
+
+		// Without --notafter or --validity the renewal keeps the validity period of the renewed certificate
+		if err := setValidityPeriod(c, cr, cr.NotAfter.Sub(cr.NotBefore)); err != nil {
+			return err
+		}
+
+		if c.IsSet("key-type") || c.IsSet("bitsize") || c.IsSet("curve") {
+			setKey(c, cr)
+		}
+
+		if err := setProfile(c, cr); err != nil {
+			return err
+		}
+		if !c.IsSet("profile") {
+			cr.Profile = old.Profile
+		}
+
+		policy, err := readPolicy(c)
+		if err != nil {
+			return err
+		}
+		cr.Policy = policy
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		caSigner, err := openCASigner(c, caCrt)
+		if err != nil {
+			fmt.Printf("Error reading CA key: %s\n", err.Error())
+			return err
+		}
+		defer caSigner.Close()
+
+		serials, err := openSerialGenerator(c, DB)
+		if err != nil {
+			return err
+		}
+
+		// The existing key has to belong to the certificate that is renewed
+		var keySigner crypto.Signer
+		if c.Bool("reuse-key") {
+			keySigner, err = readSigner(c, []byte(old.CertificatePEM), c.String("key"), "key-pass")
+			if err != nil {
+				fmt.Printf("Error reading key: %s\n", err.Error())
+				return err
+			}
+		}
+
+		// The output files are opened before the renewal is stored, so a renewal is not stored when it can not be written
+		var crtFile, keyFile *os.File
+		if !c.Bool("stdout") {
+			if crtFile, err = openOutput(c.String("crt")); err != nil {
+				fmt.Printf("Error writing certificate to file: %s\n", err.Error())
+				return err
+			}
+			defer crtFile.Close()
+			if keySigner == nil {
+				if keyFile, err = openOutput(c.String("key")); err != nil {
+					fmt.Printf("Error writing certificate key to file: %s\n", err.Error())
+					return err
+				}
+				defer keyFile.Close()
+			}
+		}
+
+		// Store in CA DB, linked to the renewed certificate which is revoked along with it
+		var crt, key []byte
+		err = database.WithUniqueSerialNumber(repo, serials, func(serialNumber *big.Int) error {
+			cr.SerialNumber = serialNumber
+			if keySigner != nil {
+				crt, err = cert.SignPublicKeyWithSigner(cr, keySigner.Public(), caCrt, caSigner)
+			} else {
+				crt, key, err = cert.GenerateCertificateWithSigner(cr, caCrt, caSigner)
+			}
+			if err != nil {
+				fmt.Printf("Error generating certificate: %s\n", err.Error())
+				return err
+			}
+			if key != nil {
+				key, err = encryptKey(c, key, "key-pass")
+				if err != nil {
+					fmt.Printf("Error encrypting key: %s\n", err.Error())
+					return err
+				}
+			}
+			return createCertificate(repo, cr, crt, old.SerialNumber, c.Bool("revoke"))
+		})
+		if err == database.ErrorDuplicateObject {
+			fmt.Printf("Error saving certificate to DB: %s\n", err.Error())
+		}
+		if err != nil {
+			return err
+		}
+
+		if c.Bool("stdout") {
+			if key != nil {
+				fmt.Println(string(key))
+			}
+			fmt.Println(string(crt))
+			return nil
+		}
+
+		fmt.Printf("Renewed certificate with serial number %s as serial number %s\n", old.SerialNumber, cr.SerialNumber)
+		if c.Bool("revoke") {
+			fmt.Printf("Revoked certificate with serial number %s (%s)\n", old.SerialNumber, cert.RevocationReasonName(cert.ReasonSuperseded))
+		}
+		fmt.Printf("Writing certificate to %s\n", c.String("crt"))
+		if err := writeOutput(crtFile, crt); err != nil {
+			fmt.Printf("Error writing certificate to file: %s\n", err.Error())
+			return err
+		}
+		if key != nil {
+			fmt.Printf("Writing key to %s\n", c.String("key"))
+			if err := writeOutput(keyFile, key); err != nil {
+				fmt.Printf("Error writing certificate key to file: %s\n", err.Error())
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// lookupRenewal gets the certificate to renew identified by the serial, name-serial or cn flag. A common name is
+// looked up among the valid certificates and has to identify a single certificate.
+func lookupRenewal(c *cli.Context, repo database.CertificateRepository) (*database.Certificate, error) {
+	if c.String("cn") == "" {
+		if c.String("serial") == "" && c.String("name-serial") == "" {
+			fmt.Printf("Error: %s\n", errorMissingRenewSerial.Error())
+			return nil, errorMissingRenewSerial
+		}
+		return lookupCertificate(c, repo)
+	}
+
+	list, err := repo.List(database.CertificateFilter{Status: database.StatusValid, CommonName: c.String("cn")})
+	if err != nil {
+		fmt.Printf("Error finding certificate: %s\n", err.Error())
+		return nil, err
+	}
+
+	var found *database.Certificate
+	for _, crt := range list {
+		if crt.CommonName != c.String("cn") {
+			continue
+		}
+		if found != nil {
+			fmt.Printf("Error finding certificate: %s\n", errorAmbiguousCN.Error())
+			return nil, errorAmbiguousCN
+		}
+		found = crt
+	}
+
+	if found == nil {
+		fmt.Printf("Error finding certificate: %s\n", database.ErrorObjectNotFound.Error())
+		return nil, database.ErrorObjectNotFound
+	}
+	return found, nil
+}
+
+// openOutput opens a file to write to later, without truncating it yet
+func openOutput(filename string) (*os.File, error) {
+	return os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0600)
+}
+
+// writeOutput replaces the contents of a file opened with openOutput
+func writeOutput(f *os.File, data []byte) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.WriteAt(data, 0)
+	return err
+}
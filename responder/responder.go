@@ -0,0 +1,355 @@
+// Package responder implements an RFC 6960 OCSP responder backed by the CA database.
+//
+// The responder answers GET and POST requests for certificates issued by a single CA. Responses are signed by
+// either the CA itself or a delegated OCSP signing certificate issued by the CA (see cert.GenerateOCSPSigner).
+// Responses for certificates in the CA database are cached until their next update, the least recently used ones are
+// dropped when the cache is full. Certificates that are not in the CA database, or that were issued by another CA
+// sharing the database, are unknown. Unknown responses are not cached.
+package responder
+
+import (
+	"bytes"
+	"container/list"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"golang.org/x/crypto/ocsp"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRequestSize is the maximum size of an OCSP request body
+const maxRequestSize = 10 * 1024
+
+// maxCacheSize is the maximum number of cached responses
+const maxCacheSize = 10000
+
+var (
+	// ErrorInvalidCertificate is given if a certificate can not be parsed
+	ErrorInvalidCertificate = errors.New("invalid certificate")
+	// ErrorInvalidResponder is given if the responder certificate is not allowed to sign responses for the CA
+	ErrorInvalidResponder = errors.New("responder certificate is not a delegated ocsp signer of the ca")
+	// ErrorInvalidInterval is given if the update interval is not positive
+	ErrorInvalidInterval = errors.New("invalid update interval")
+
+	errorUnauthorized = errors.New("request is not for this ca")
+)
+
+// Responder is an http.Handler answering OCSP requests for certificates in the CA database
+type Responder struct {
+	issuer    *x509.Certificate
+	responder *x509.Certificate
+	signer    crypto.Signer
+	delegated bool
+
+	repo     database.CertificateRepository
+	interval time.Duration
+
+	cacheLock sync.Mutex
+	cache     map[string]*list.Element
+	// cacheList holds the cached responses, the most recently used first
+	cacheList *list.List
+	cacheSize int
+	// nextPrune is the time the expired responses are dropped from the cache again
+	nextPrune time.Time
+
+	now func() time.Time
+}
+
+type cachedResponse struct {
+	key        string
+	der        []byte
+	thisUpdate time.Time
+	nextUpdate time.Time
+}
+
+// New creates a new Responder for the given CA certificate. The responses are signed by the responder certificate
+// and key, which can either be the CA pair itself or a delegated OCSP signing pair issued by the CA. The interval is
+// the time between thisUpdate and nextUpdate of each response.
+func New(repo database.CertificateRepository, caCrt []byte, responderCrt []byte, responderKey []byte, interval time.Duration) (*Responder, error) {
+	keyPair, err := tls.X509KeyPair(responderCrt, responderKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, ErrorInvalidResponder
+	}
+
+	return NewWithSigner(repo, caCrt, responderCrt, signer, interval)
+}
+
+// NewWithSigner works like New, the responses are signed by the signer for the responder certificate, so the key of
+// the responder can be kept in an HSM or KMS
+func NewWithSigner(repo database.CertificateRepository, caCrt []byte, responderCrt []byte, signer crypto.Signer, interval time.Duration) (*Responder, error) {
+	if interval <= 0 {
+		return nil, ErrorInvalidInterval
+	}
+
+	issuer, err := parseCertificate(caCrt)
+	if err != nil {
+		return nil, err
+	}
+
+	responder, err := parseCertificate(responderCrt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cert.CheckSigner(responderCrt, signer); err != nil {
+		return nil, err
+	}
+
+	delegated := !bytes.Equal(responder.Raw, issuer.Raw)
+	if delegated {
+		if err := responder.CheckSignatureFrom(issuer); err != nil {
+			return nil, ErrorInvalidResponder
+		}
+
+		if !hasExtKeyUsage(responder, x509.ExtKeyUsageOCSPSigning) {
+			return nil, ErrorInvalidResponder
+		}
+	}
+
+	return &Responder{
+		issuer:    issuer,
+		responder: responder,
+		signer:    signer,
+		delegated: delegated,
+		repo:      repo,
+		interval:  interval,
+		cache:     make(map[string]*list.Element),
+		cacheList: list.New(),
+		cacheSize: maxCacheSize,
+		now:       time.Now,
+	}, nil
+}
+
+// ServeHTTP handles OCSP requests sent with GET (base64 encoded in the path) or POST (DER encoded in the body)
+func (r *Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var raw []byte
+	var err error
+
+	switch req.Method {
+	case http.MethodGet:
+		raw, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(req.URL.Path, "/"))
+	case http.MethodPost:
+		raw, err = ioutil.ReadAll(io.LimitReader(req.Body, maxRequestSize+1))
+		if err == nil && len(raw) > maxRequestSize {
+			err = errors.New("request too large")
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		r.write(w, ocsp.MalformedRequestErrorResponse, nil)
+		return
+	}
+
+	ocspReq, err := ocsp.ParseRequest(raw)
+	if err != nil {
+		r.write(w, ocsp.MalformedRequestErrorResponse, nil)
+		return
+	}
+
+	resp, err := r.respond(ocspReq)
+	switch err {
+	case nil:
+	case errorUnauthorized:
+		r.write(w, ocsp.UnauthorizedErrorResponse, nil)
+		return
+	default:
+		r.write(w, ocsp.InternalErrorErrorResponse, nil)
+		return
+	}
+
+	if req.Method == http.MethodGet {
+		r.write(w, resp.der, resp)
+		return
+	}
+	r.write(w, resp.der, nil)
+}
+
+// respond creates (or gets from cache) the signed response for the given OCSP request
+func (r *Responder) respond(req *ocsp.Request) (*cachedResponse, error) {
+	if !r.isIssuer(req) {
+		return nil, errorUnauthorized
+	}
+
+	now := r.now()
+	key := fmt.Sprintf("%d:%x", req.HashAlgorithm, req.SerialNumber)
+
+	if cached := r.cached(key, now); cached != nil {
+		return cached, nil
+	}
+
+	template := ocsp.Response{
+		SerialNumber: req.SerialNumber,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(r.interval),
+		IssuerHash:   req.HashAlgorithm,
+	}
+
+	if r.delegated {
+		template.Certificate = r.responder
+	}
+
+	// The CA database can be shared by several CAs, certificates of other CAs are unknown to this responder
+	crt, err := r.repo.GetBySerialNumber(req.SerialNumber)
+	switch {
+	case err == database.ErrorObjectNotFound, err == nil && crt.Issuer != r.issuer.Subject.String():
+		template.Status = ocsp.Unknown
+	case err != nil:
+		return nil, err
+	case crt.Status == database.StatusRevoked:
+		template.Status = ocsp.Revoked
+		template.RevocationReason = crt.RevocationReason
+		if crt.RevocationDate != nil {
+			template.RevokedAt = *crt.RevocationDate
+		}
+	default:
+		template.Status = ocsp.Good
+	}
+
+	der, err := ocsp.CreateResponse(r.issuer, r.responder, template, r.signer)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &cachedResponse{key: key, der: der, thisUpdate: template.ThisUpdate, nextUpdate: template.NextUpdate}
+
+	// Any serial number can be requested, so unknown responses would fill the cache
+	if template.Status != ocsp.Unknown {
+		r.store(resp, now)
+	}
+
+	return resp, nil
+}
+
+// cached returns the cached response for the key when it is not expired yet
+func (r *Responder) cached(key string, now time.Time) *cachedResponse {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	e, ok := r.cache[key]
+	if !ok {
+		return nil
+	}
+
+	cached := e.Value.(*cachedResponse)
+	if !now.Before(cached.nextUpdate) {
+		r.cacheList.Remove(e)
+		delete(r.cache, key)
+		return nil
+	}
+
+	r.cacheList.MoveToFront(e)
+	return cached
+}
+
+// store adds the response to the cache. Expired responses are dropped once per interval, and the least recently used
+// responses when the cache is full.
+func (r *Responder) store(resp *cachedResponse, now time.Time) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	if !now.Before(r.nextPrune) {
+		for e := r.cacheList.Front(); e != nil; {
+			next := e.Next()
+			if cached := e.Value.(*cachedResponse); !now.Before(cached.nextUpdate) {
+				r.cacheList.Remove(e)
+				delete(r.cache, cached.key)
+			}
+			e = next
+		}
+		r.nextPrune = now.Add(r.interval)
+	}
+
+	if e, ok := r.cache[resp.key]; ok {
+		r.cacheList.Remove(e)
+	}
+	r.cache[resp.key] = r.cacheList.PushFront(resp)
+
+	for r.cacheList.Len() > r.cacheSize {
+		e := r.cacheList.Back()
+		r.cacheList.Remove(e)
+		delete(r.cache, e.Value.(*cachedResponse).key)
+	}
+}
+
+// isIssuer checks if the issuer name and key hash of the request match the CA of the responder
+func (r *Responder) isIssuer(req *ocsp.Request) bool {
+	if !req.HashAlgorithm.Available() {
+		return false
+	}
+
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(r.issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return false
+	}
+
+	h := req.HashAlgorithm.New()
+	h.Write(publicKeyInfo.PublicKey.RightAlign())
+	if !bytes.Equal(h.Sum(nil), req.IssuerKeyHash) {
+		return false
+	}
+
+	h.Reset()
+	h.Write(r.issuer.RawSubject)
+	return bytes.Equal(h.Sum(nil), req.IssuerNameHash)
+}
+
+// write writes an OCSP response. When the response is cacheable the RFC 5019 caching headers are added.
+func (r *Responder) write(w http.ResponseWriter, der []byte, cacheable *cachedResponse) {
+	w.Header().Set("Content-Type", "application/ocsp-response")
+
+	if cacheable != nil {
+		maxAge := int(cacheable.nextUpdate.Sub(r.now()).Seconds())
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		w.Header().Set("Last-Modified", cacheable.thisUpdate.UTC().Format(http.TimeFormat))
+		w.Header().Set("Expires", cacheable.nextUpdate.UTC().Format(http.TimeFormat))
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public, no-transform, must-revalidate", maxAge))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(der)
+}
+
+func parseCertificate(crt []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(crt)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, ErrorInvalidCertificate
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func hasExtKeyUsage(crt *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range crt.ExtKeyUsage {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,399 @@
+package responder
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"github.com/mvmaasakkers/certificates/database/file"
+	"golang.org/x/crypto/ocsp"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testPair struct {
+	Crt []byte
+	Key []byte
+}
+
+func generateTestPKI(t *testing.T) (ca, other, signer, leaf *testPair, db database.DB) {
+	var err error
+	ca = &testPair{}
+	ca.Crt, ca.Key, err = cert.GenerateCA(&cert.Request{CommonName: "ca.test.local", KeyType: cert.KeyTypeECDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other = &testPair{}
+	other.Crt, other.Key, err = cert.GenerateCA(&cert.Request{CommonName: "other.test.local", KeyType: cert.KeyTypeECDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer = &testPair{}
+	signer.Crt, signer.Key, err = cert.GenerateOCSPSigner(&cert.Request{CommonName: "ocsp.test.local", KeyType: cert.KeyTypeECDSA}, ca.Crt, ca.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf = &testPair{}
+	leaf.Crt, leaf.Key, err = cert.GenerateCertificate(&cert.Request{CommonName: "leaf.test.local", KeyType: cert.KeyTypeECDSA, SerialNumber: big.NewInt(1)}, ca.Crt, ca.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = file.NewDB(filepath.Join(t.TempDir(), "file.db"))
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Provision(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		serialNumber int64
+		cn           string
+		issuer       string
+	}{
+		{serialNumber: 1, cn: "leaf.test.local", issuer: "CN=ca.test.local"},
+		{serialNumber: 2, cn: "revoked.test.local", issuer: "CN=ca.test.local"},
+		{serialNumber: 4, cn: "other.test.local", issuer: "CN=other.test.local"},
+	} {
+		createTestCertificate(t, db, c.serialNumber, c.cn, c.issuer)
+	}
+
+	if err := db.GetCertificateRepository().Revoke(big.NewInt(2), cert.ReasonKeyCompromise, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	return ca, other, signer, leaf, db
+}
+
+func createTestCertificate(t *testing.T, db database.DB, serialNumber int64, cn string, issuer string) {
+	c := database.NewCertificate()
+	c.Status = database.StatusValid
+	c.SerialNumber = big.NewInt(serialNumber)
+	c.NameSerialNumber = c.UUID
+	c.CommonName = cn
+	c.Issuer = issuer
+	if err := db.GetCertificateRepository().Create(c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func parseTestCertificate(t *testing.T, crt []byte) *x509.Certificate {
+	block, _ := pem.Decode(crt)
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNew(t *testing.T) {
+	ca, other, signer, leaf, db := generateTestPKI(t)
+	defer db.Close()
+
+	tests := []struct {
+		name         string
+		caCrt        []byte
+		responderCrt []byte
+		responderKey []byte
+		interval     time.Duration
+		wantErr      bool
+	}{
+		{name: "ca_signed", caCrt: ca.Crt, responderCrt: ca.Crt, responderKey: ca.Key, interval: time.Hour, wantErr: false},
+		{name: "delegated", caCrt: ca.Crt, responderCrt: signer.Crt, responderKey: signer.Key, interval: time.Hour, wantErr: false},
+		{name: "delegated_other_ca", caCrt: other.Crt, responderCrt: signer.Crt, responderKey: signer.Key, interval: time.Hour, wantErr: true},
+		{name: "delegated_without_ocsp_signing", caCrt: ca.Crt, responderCrt: leaf.Crt, responderKey: leaf.Key, interval: time.Hour, wantErr: true},
+		{name: "invalid_ca", caCrt: []byte("invalid"), responderCrt: ca.Crt, responderKey: ca.Key, interval: time.Hour, wantErr: true},
+		{name: "invalid_key", caCrt: ca.Crt, responderCrt: ca.Crt, responderKey: other.Key, interval: time.Hour, wantErr: true},
+		{name: "invalid_interval", caCrt: ca.Crt, responderCrt: ca.Crt, responderKey: ca.Key, interval: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(db.GetCertificateRepository(), tt.caCrt, tt.responderCrt, tt.responderKey, tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewWithSigner(t *testing.T) {
+	ca, other, _, _, db := generateTestPKI(t)
+	defer db.Close()
+
+	signer := func(pair *testPair) crypto.Signer {
+		keyPair, err := tls.X509KeyPair(pair.Crt, pair.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keyPair.PrivateKey.(crypto.Signer)
+	}
+
+	if _, err := NewWithSigner(db.GetCertificateRepository(), ca.Crt, ca.Crt, signer(ca), time.Hour); err != nil {
+		t.Errorf("NewWithSigner() error = %v", err)
+	}
+	if _, err := NewWithSigner(db.GetCertificateRepository(), ca.Crt, ca.Crt, signer(other), time.Hour); err != cert.ErrorSignerMismatch {
+		t.Errorf("NewWithSigner() error = %v, want %v", err, cert.ErrorSignerMismatch)
+	}
+}
+
+func TestResponder_ServeHTTP(t *testing.T) {
+	ca, other, signer, _, db := generateTestPKI(t)
+	defer db.Close()
+
+	issuer := parseTestCertificate(t, ca.Crt)
+
+	request := func(serial int64, issuer *x509.Certificate, hash crypto.Hash) []byte {
+		raw, err := ocsp.CreateRequest(&x509.Certificate{SerialNumber: big.NewInt(serial)}, issuer, &ocsp.RequestOptions{Hash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name           string
+		responderCrt   []byte
+		responderKey   []byte
+		method         string
+		body           []byte
+		wantHTTPStatus int
+		wantStatus     int
+		wantReason     int
+		wantErr        bool
+	}{
+		{
+			name:           "get_good",
+			responderCrt:   ca.Crt,
+			responderKey:   ca.Key,
+			method:         http.MethodGet,
+			body:           request(1, issuer, crypto.SHA1),
+			wantHTTPStatus: http.StatusOK,
+			wantStatus:     ocsp.Good,
+		},
+		{
+			name:           "post_revoked",
+			responderCrt:   ca.Crt,
+			responderKey:   ca.Key,
+			method:         http.MethodPost,
+			body:           request(2, issuer, crypto.SHA256),
+			wantHTTPStatus: http.StatusOK,
+			wantStatus:     ocsp.Revoked,
+			wantReason:     ocsp.KeyCompromise,
+		},
+		{
+			name:           "delegated_unknown",
+			responderCrt:   signer.Crt,
+			responderKey:   signer.Key,
+			method:         http.MethodPost,
+			body:           request(3, issuer, crypto.SHA1),
+			wantHTTPStatus: http.StatusOK,
+			wantStatus:     ocsp.Unknown,
+		},
+		{
+			name:           "issued_by_other_ca",
+			responderCrt:   ca.Crt,
+			responderKey:   ca.Key,
+			method:         http.MethodPost,
+			body:           request(4, issuer, crypto.SHA1),
+			wantHTTPStatus: http.StatusOK,
+			wantStatus:     ocsp.Unknown,
+		},
+		{
+			name:           "other_issuer",
+			responderCrt:   ca.Crt,
+			responderKey:   ca.Key,
+			method:         http.MethodPost,
+			body:           request(1, parseTestCertificate(t, other.Crt), crypto.SHA1),
+			wantHTTPStatus: http.StatusOK,
+			wantErr:        true,
+		},
+		{
+			name:           "malformed",
+			responderCrt:   ca.Crt,
+			responderKey:   ca.Key,
+			method:         http.MethodPost,
+			body:           []byte("malformed"),
+			wantHTTPStatus: http.StatusOK,
+			wantErr:        true,
+		},
+		{
+			name:           "method_not_allowed",
+			responderCrt:   ca.Crt,
+			responderKey:   ca.Key,
+			method:         http.MethodPut,
+			wantHTTPStatus: http.StatusMethodNotAllowed,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(db.GetCertificateRepository(), ca.Crt, tt.responderCrt, tt.responderKey, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var req *http.Request
+			if tt.method == http.MethodGet {
+				req = httptest.NewRequest(tt.method, "/"+base64.StdEncoding.EncodeToString(tt.body), nil)
+			} else {
+				req = httptest.NewRequest(tt.method, "/", bytes.NewReader(tt.body))
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantHTTPStatus {
+				t.Errorf("ServeHTTP() status = %d, want %d", w.Code, tt.wantHTTPStatus)
+				return
+			}
+
+			body, _ := ioutil.ReadAll(w.Body)
+			resp, err := ocsp.ParseResponse(body, issuer)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ServeHTTP() response error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if resp.Status != tt.wantStatus {
+				t.Errorf("ServeHTTP() ocsp status = %d, want %d", resp.Status, tt.wantStatus)
+			}
+			if resp.Status == ocsp.Revoked && resp.RevocationReason != tt.wantReason {
+				t.Errorf("ServeHTTP() revocation reason = %d, want %d", resp.RevocationReason, tt.wantReason)
+			}
+			if tt.method == http.MethodGet && w.Header().Get("Cache-Control") == "" {
+				t.Errorf("ServeHTTP() is missing caching headers for GET")
+			}
+		})
+	}
+}
+
+func TestResponder_cache(t *testing.T) {
+	ca, _, _, _, db := generateTestPKI(t)
+	defer db.Close()
+
+	r, err := New(db.GetCertificateRepository(), ca.Crt, ca.Crt, ca.Key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	issuer := parseTestCertificate(t, ca.Crt)
+	raw, err := ocsp.CreateRequest(&x509.Certificate{SerialNumber: big.NewInt(1)}, issuer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ocsp.ParseRequest(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := r.respond(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.GetCertificateRepository().Revoke(big.NewInt(1), cert.ReasonSuperseded, now); err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := r.respond(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.der, cached.der) {
+		t.Errorf("respond() did not return the cached response before the next update")
+	}
+
+	r.now = func() time.Time { return now.Add(2 * time.Hour) }
+	refreshed, err := r.respond(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := ocsp.ParseResponse(refreshed.der, issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != ocsp.Revoked {
+		t.Errorf("respond() status after next update = %d, want %d", resp.Status, ocsp.Revoked)
+	}
+}
+
+func TestResponder_cacheBounds(t *testing.T) {
+	ca, _, _, _, db := generateTestPKI(t)
+	defer db.Close()
+
+	for serialNumber := int64(10); serialNumber < 13; serialNumber++ {
+		createTestCertificate(t, db, serialNumber, "bounds.test.local", "CN=ca.test.local")
+	}
+
+	r, err := New(db.GetCertificateRepository(), ca.Crt, ca.Crt, ca.Key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.cacheSize = 2
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	issuer := parseTestCertificate(t, ca.Crt)
+	respond := func(serialNumber int64) {
+		raw, err := ocsp.CreateRequest(&x509.Certificate{SerialNumber: big.NewInt(serialNumber)}, issuer, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := ocsp.ParseRequest(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.respond(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cached := func(serialNumber int64) bool {
+		_, ok := r.cache[fmt.Sprintf("%d:%x", crypto.SHA1, big.NewInt(serialNumber))]
+		return ok
+	}
+
+	// Unknown responses are not cached
+	respond(3)
+	respond(4)
+	if len(r.cache) != 0 {
+		t.Errorf("respond() cached %d unknown responses", len(r.cache))
+	}
+
+	// The least recently used response is dropped when the cache is full
+	respond(10)
+	respond(11)
+	respond(10)
+	respond(12)
+	if len(r.cache) != 2 || r.cacheList.Len() != 2 || !cached(10) || cached(11) || !cached(12) {
+		t.Errorf("respond() cache holds %d responses, 10 %v, 11 %v, 12 %v", len(r.cache), cached(10), cached(11), cached(12))
+	}
+
+	// Expired responses are dropped when the next response is stored
+	now = now.Add(2 * time.Hour)
+	respond(11)
+	if len(r.cache) != 1 || !cached(11) {
+		t.Errorf("respond() cache holds %d responses after the next update, want only 11", len(r.cache))
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"gopkg.in/urfave/cli.v1"
+	"time"
+)
+
+var revokeCommand = cli.Command{
+	Name:        "revoke",
+	Usage:       "Revoke a certificate in the CA DB",
+	Description: `To revoke a certificate you need to supply either the serial number or the name serial number of the certificate.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "serial",
+			Value: "",
+			Usage: "SerialNumber of the certificate (decimal or 0x prefixed hexadecimal)",
+		},
+		cli.StringFlag{
+			Name:  "name-serial",
+			Value: "",
+			Usage: "Name SerialNumber of the certificate",
+		},
+		cli.StringFlag{
+			Name:  "reason",
+			Value: "unspecified",
+			Usage: "RFC 5280 revocation reason, as name (for example keyCompromise or superseded) or code",
+		},
+	}, dbFlags),
+	Action: func(c *cli.Context) error {
+
+		reason, err := cert.ParseRevocationReason(c.String("reason"))
+		if err != nil {
+			fmt.Printf("Error parsing --reason: %s\n", err.Error())
+			return err
+		}
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		crt, err := findCertificate(c, DB.GetCertificateRepository())
+		if err != nil {
+			return err
+		}
+
+		if err := DB.GetCertificateRepository().Revoke(crt.SerialNumber, reason, time.Now()); err != nil {
+			fmt.Printf("Error revoking certificate: %s\n", err.Error())
+			return err
+		}
+
+		fmt.Printf("Revoked certificate with serial number %s (%s)\n", crt.SerialNumber, cert.RevocationReasonName(reason))
+		return nil
+	},
+}
+
+// findCertificate looks up the certificate identified by either the serial or the name-serial flag. When the serial
+// is given the certificate itself is not looked up as the repository methods work on the serial number directly.
+func findCertificate(c *cli.Context, repo database.CertificateRepository) (*database.Certificate, error) {
+	switch {
+	case c.String("serial") != "":
+		serialNumber, err := cert.ParseSerialNumber(c.String("serial"))
+		if err != nil {
+			fmt.Printf("Error parsing --serial: %s\n", err.Error())
+			return nil, err
+		}
+		return &database.Certificate{SerialNumber: serialNumber}, nil
+	case c.String("name-serial") != "":
+		crt, err := repo.GetByNameSerialNumber(c.String("name-serial"))
+		if err != nil {
+			fmt.Printf("Error finding certificate: %s\n", err.Error())
+			return nil, err
+		}
+		return crt, nil
+	}
+
+	fmt.Printf("Error: %s\n", errorMissingSerial.Error())
+	return nil, errorMissingSerial
+}
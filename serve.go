@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/api"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+var serveCommand = cli.Command{
+	Name:  "serve",
+	Usage: "Serve the REST API to issue, sign, look up and revoke certificates",
+	Description: `Clients authenticate with a client certificate issued by the CA pair. The organizational units (--ou) of the
+   client certificate are its roles: admin, issuer and reader by default, or the roles in the --roles file.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "listen",
+			Value: ":8443",
+			Usage: "Address to listen on",
+		},
+		cli.StringFlag{
+			Name:  "tls-crt",
+			Value: "server.crt",
+			Usage: "Certificate file to serve HTTPS with",
+		},
+		cli.StringFlag{
+			Name:  "tls-key",
+			Value: "server.key",
+			Usage: "Key file to serve HTTPS with",
+		},
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "CA Certificate file",
+		},
+		cli.StringFlag{
+			Name:  "ca-key",
+			Value: "ca.key",
+			Usage: "CA Key file",
+		},
+		cli.StringFlag{
+			Name:  "ca-chain",
+			Value: "",
+			Usage: "File with the intermediate certificates above the CA, added to the chain in the responses",
+		},
+		cli.StringFlag{
+			Name:  "roles",
+			Value: "",
+			Usage: "JSON file mapping roles to their permissions (issue, sign, read and revoke)",
+		},
+		cli.DurationFlag{
+			Name:  "validity",
+			Value: 30 * 24 * time.Hour,
+			Usage: "Validity of the issued certificates when the request has no not_after",
+		},
+	}, caKeyPassFlags, caSignerFlags, policyFlags, dbFlags, serialFlags),
+	Action: func(c *cli.Context) error {
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading CA certificate: %s\n", err.Error())
+			return err
+		}
+		caSigner, err := openCASigner(c, caCrt)
+		if err != nil {
+			fmt.Printf("Error reading CA key: %s\n", err.Error())
+			return err
+		}
+		defer caSigner.Close()
+
+		var caChain []byte
+		if c.String("ca-chain") != "" {
+			caChain, err = ioutil.ReadFile(c.String("ca-chain"))
+			if err != nil {
+				fmt.Printf("Error reading CA chain: %s\n", err.Error())
+				return err
+			}
+		}
+
+		var roles map[string][]string
+		if c.String("roles") != "" {
+			d, err := ioutil.ReadFile(c.String("roles"))
+			if err != nil {
+				fmt.Printf("Error reading roles: %s\n", err.Error())
+				return err
+			}
+			if err := json.Unmarshal(d, &roles); err != nil {
+				fmt.Printf("Error parsing roles: %s\n", err.Error())
+				return err
+			}
+		}
+
+		policy, err := readPolicy(c)
+		if err != nil {
+			return err
+		}
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		serials, err := openSerialGenerator(c, DB)
+		if err != nil {
+			return err
+		}
+
+		s, err := api.New(api.Config{
+			CACrt:    caCrt,
+			CASigner: caSigner,
+			CAChain:  caChain,
+			DB:       DB,
+			Roles:    roles,
+			Validity: c.Duration("validity"),
+			Policy:   policy,
+			Serials:  serials,
+		})
+		if err != nil {
+			fmt.Printf("Error creating API server: %s\n", err.Error())
+			return err
+		}
+
+		server := &http.Server{
+			Addr:      c.String("listen"),
+			Handler:   s,
+			TLSConfig: s.TLSConfig(),
+		}
+
+		fmt.Printf("Serving API on %s\n", c.String("listen"))
+		if err := server.ListenAndServeTLS(c.String("tls-crt"), c.String("tls-key")); err != nil {
+			fmt.Printf("Error serving API: %s\n", err.Error())
+			return err
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"github.com/mvmaasakkers/certificates/database"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var showCommand = cli.Command{
+	Name:        "show",
+	Usage:       "Show a certificate stored in the CA DB",
+	Description: `To show a certificate you need to supply either the serial number or the name serial number of the certificate.`,
+	Flags: flags([]cli.Flag{
+		cli.StringFlag{
+			Name:  "serial",
+			Value: "",
+			Usage: "SerialNumber of the certificate (decimal or 0x prefixed hexadecimal)",
+		},
+		cli.StringFlag{
+			Name:  "name-serial",
+			Value: "",
+			Usage: "Name SerialNumber of the certificate",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "Output format (text, pem or json)",
+		},
+	}, dbFlags),
+	Action: func(c *cli.Context) error {
+
+		switch c.String("format") {
+		case "text", "pem", "json":
+		default:
+			fmt.Printf("Error parsing --format: %s\n", errorInvalidFormat.Error())
+			return errorInvalidFormat
+		}
+
+		DB, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer DB.Close()
+
+		crt, err := lookupCertificate(c, DB.GetCertificateRepository())
+		if err != nil {
+			return err
+		}
+
+		switch c.String("format") {
+		case "pem":
+			if crt.CertificatePEM == "" {
+				fmt.Printf("Error: %s\n", errorMissingPEM.Error())
+				return errorMissingPEM
+			}
+			fmt.Print(crt.CertificatePEM)
+			return nil
+		case "json":
+			return writeShowJSON(crt)
+		}
+		return writeShowText(crt)
+	},
+}
+
+// showItem is the JSON representation of a certificate in the show output
+type showItem struct {
+	listItem
+
+	RevocationReason string `json:"revocation_reason,omitempty"`
+	SubjectAltNames  string `json:"subject_alt_names"`
+	Issuer           string `json:"issuer"`
+	KeyAlgorithm     string `json:"key_algorithm"`
+	Fingerprint      string `json:"fingerprint_sha256"`
+	SubjectKeyID     string `json:"subject_key_id"`
+	Profile          string `json:"profile,omitempty"`
+	Renews           string `json:"renews,omitempty"`
+	RenewedBy        string `json:"renewed_by,omitempty"`
+	CertificatePEM   string `json:"certificate_pem"`
+}
+
+// lookupCertificate gets the certificate identified by either the serial or the name-serial flag
+func lookupCertificate(c *cli.Context, repo database.CertificateRepository) (*database.Certificate, error) {
+	var crt *database.Certificate
+	var err error
+
+	switch {
+	case c.String("serial") != "":
+		serialNumber, perr := cert.ParseSerialNumber(c.String("serial"))
+		if perr != nil {
+			fmt.Printf("Error parsing --serial: %s\n", perr.Error())
+			return nil, perr
+		}
+		crt, err = repo.GetBySerialNumber(serialNumber)
+	case c.String("name-serial") != "":
+		crt, err = repo.GetByNameSerialNumber(c.String("name-serial"))
+	default:
+		fmt.Printf("Error: %s\n", errorMissingSerial.Error())
+		return nil, errorMissingSerial
+	}
+
+	if err != nil {
+		fmt.Printf("Error finding certificate: %s\n", err.Error())
+		return nil, err
+	}
+	return crt, nil
+}
+
+func writeShowJSON(crt *database.Certificate) error {
+	item := showItem{
+		listItem: listItem{
+			SerialNumber:     formatSerialNumber(crt),
+			NameSerialNumber: crt.NameSerialNumber,
+			CommonName:       crt.CommonName,
+			Status:           crt.Status,
+			IssuedAt:         crt.CreatedAt,
+			ExpirationDate:   crt.ExpirationDate,
+			RevocationDate:   crt.RevocationDate,
+		},
+		SubjectAltNames: crt.SubjectAltNames,
+		Issuer:          crt.Issuer,
+		KeyAlgorithm:    crt.KeyAlgorithm,
+		Fingerprint:     crt.Fingerprint,
+		SubjectKeyID:    crt.SubjectKeyID,
+		Profile:         crt.Profile,
+		Renews:          crt.Renews,
+		RenewedBy:       crt.RenewedBy,
+		CertificatePEM:  crt.CertificatePEM,
+	}
+	if crt.Status == database.StatusRevoked {
+		item.RevocationReason = cert.RevocationReasonName(crt.RevocationReason)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(item); err != nil {
+		fmt.Printf("Error writing certificate: %s\n", err.Error())
+		return err
+	}
+	return nil
+}
+
+func writeShowText(crt *database.Certificate) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Serial Number:\t%s\n", formatSerialNumber(crt))
+	fmt.Fprintf(w, "Name Serial Number:\t%s\n", crt.NameSerialNumber)
+	fmt.Fprintf(w, "Common Name:\t%s\n", crt.CommonName)
+	fmt.Fprintf(w, "Subject Alt Names:\t%s\n", crt.SubjectAltNames)
+	fmt.Fprintf(w, "Issuer:\t%s\n", crt.Issuer)
+	fmt.Fprintf(w, "Key Algorithm:\t%s\n", crt.KeyAlgorithm)
+	fmt.Fprintf(w, "Status:\t%s\n", crt.Status)
+	if crt.Status == database.StatusRevoked && crt.RevocationDate != nil {
+		fmt.Fprintf(w, "Revoked:\t%s (%s)\n", crt.RevocationDate.Format(time.RFC3339), cert.RevocationReasonName(crt.RevocationReason))
+	}
+	fmt.Fprintf(w, "Issued:\t%s\n", crt.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "Expires:\t%s\n", crt.ExpirationDate.Format(time.RFC3339))
+	fmt.Fprintf(w, "SHA-256 Fingerprint:\t%s\n", crt.Fingerprint)
+	fmt.Fprintf(w, "Subject Key ID:\t%s\n", crt.SubjectKeyID)
+	if crt.Profile != "" {
+		fmt.Fprintf(w, "Profile:\t%s\n", crt.Profile)
+	}
+	if crt.Renews != "" {
+		fmt.Fprintf(w, "Renews:\t%s\n", crt.Renews)
+	}
+	if crt.RenewedBy != "" {
+		fmt.Fprintf(w, "Renewed By:\t%s\n", crt.RenewedBy)
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Printf("Error writing certificate: %s\n", err.Error())
+		return err
+	}
+
+	if crt.CertificatePEM != "" {
+		fmt.Println()
+		fmt.Print(crt.CertificatePEM)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package signer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// The exec signer protocol lets any program that can reach the key, such as a cloud KMS client, sign for the CA. The
+// command of the exec URI is run with the arguments of the operation appended:
+//
+//	<command> public-key
+//	<command> sign <hash> [pss <salt length>]
+//
+// public-key writes the PEM encoded public key ("PUBLIC KEY") to stdout, it is run once when the signer is opened.
+// sign reads the digest from stdin and writes the signature to stdout in the format crypto.Signer uses: PKCS#1 v1.5
+// or PSS for RSA, ASN.1 DER for ECDSA. The hash is the name of the crypto.Hash (SHA-256, SHA-384 or SHA-512), or
+// "none" for Ed25519 in which case stdin holds the message itself. RSA-PSS adds the salt length in bytes. A command
+// that exits with a non-zero status fails the operation.
+
+// execSigner signs by running an external program
+type execSigner struct {
+	command []string
+	pub     crypto.PublicKey
+}
+
+// OpenExec opens an exec signer for the command, given as the program followed by its arguments separated by spaces
+func OpenExec(command string) (Signer, error) {
+	s := &execSigner{command: strings.Fields(command)}
+	if len(s.command) == 0 {
+		return nil, fmt.Errorf("%w: exec command is required", ErrorInvalidURI)
+	}
+
+	out, err := s.run(nil, "public-key")
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(out)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrorInvalidPublicKey
+	}
+	s.pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrorInvalidPublicKey
+	}
+
+	return s, nil
+}
+
+// Public returns the public key given by the command when the signer was opened
+func (s *execSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// Sign runs the command to sign the digest
+func (s *execSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	hash := "none"
+	if opts.HashFunc() != 0 {
+		hash = opts.HashFunc().String()
+	}
+
+	args := []string{"sign", hash}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		saltLength := pss.SaltLength
+		if saltLength == rsa.PSSSaltLengthEqualsHash {
+			saltLength = opts.HashFunc().Size()
+		}
+		args = append(args, "pss", strconv.Itoa(saltLength))
+	}
+
+	return s.run(digest, args...)
+}
+
+// Close does nothing, the command only runs during an operation
+func (s *execSigner) Close() error {
+	return nil
+}
+
+// run runs the command for the operation with the input on stdin and returns its stdout
+func (s *execSigner) run(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(s.command[0], append(s.command[1:len(s.command):len(s.command)], args...)...)
+	cmd.Stdin = bytes.NewReader(input)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("exec signer %s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("exec signer %s: %w", args[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
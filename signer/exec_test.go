@@ -0,0 +1,190 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHelperSigner is not a real test, it implements the exec signer protocol for the key in the
+// SIGNER_HELPER_KEY file when run as the command of an exec signer
+func TestHelperSigner(t *testing.T) {
+	keyFile := os.Getenv("SIGNER_HELPER_KEY")
+	if keyFile == "" {
+		return
+	}
+
+	if err := helperSigner(keyFile, os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func helperSigner(keyFile string, args []string) error {
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return errors.New("missing operation")
+	}
+
+	crt, err := ioutil.ReadFile(keyFile + ".crt")
+	if err != nil {
+		return err
+	}
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+	keyPair, err := tls.X509KeyPair(crt, key)
+	if err != nil {
+		return err
+	}
+	priv := keyPair.PrivateKey.(crypto.Signer)
+
+	switch args[1] {
+	case "public-key":
+		der, err := x509.MarshalPKIXPublicKey(priv.Public())
+		if err != nil {
+			return err
+		}
+		return pem.Encode(os.Stdout, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	case "sign":
+		digest, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		var opts crypto.SignerOpts = crypto.Hash(0)
+		for _, h := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+			if args[2] == h.String() {
+				opts = h
+			}
+		}
+		if len(args) == 5 && args[3] == "pss" {
+			var saltLength int
+			fmt.Sscan(args[4], &saltLength)
+			opts = &rsa.PSSOptions{SaltLength: saltLength, Hash: opts.HashFunc()}
+		}
+
+		signature, err := priv.Sign(rand.Reader, digest, opts)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(signature)
+		return err
+	}
+
+	return errors.New("unknown operation")
+}
+
+// helperCommand returns the exec signer command running TestHelperSigner for a new CA
+func helperCommand(t *testing.T, keyType string) (string, []byte) {
+	caCrt, caKey, err := cert.GenerateCA(&cert.Request{
+		CommonName: "ca.test.local",
+		NotBefore:  time.Now(),
+		NotAfter:   time.Now().Add(time.Hour),
+		KeyType:    keyType,
+		BitSize:    2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "ca.key")
+	if err := ioutil.WriteFile(keyFile, caKey, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile+".crt", caCrt, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SIGNER_HELPER_KEY", keyFile)
+
+	return os.Args[0] + " -test.run=^TestHelperSigner$ --", caCrt
+}
+
+func TestOpenExec(t *testing.T) {
+	for _, keyType := range []string{cert.KeyTypeRSA, cert.KeyTypeECDSA, cert.KeyTypeEd25519} {
+		t.Run(keyType, func(t *testing.T) {
+			command, caCrt := helperCommand(t, keyType)
+
+			s, err := Open(SchemeExec + ":" + command)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer s.Close()
+
+			crt, _, err := cert.GenerateCertificateWithSigner(&cert.Request{
+				CommonName: "www.test.local",
+				NotBefore:  time.Now(),
+				NotAfter:   time.Now().Add(time.Hour),
+				KeyType:    cert.KeyTypeECDSA,
+			}, caCrt, s)
+			if err != nil {
+				t.Fatalf("GenerateCertificateWithSigner() error = %v", err)
+			}
+
+			block, _ := pem.Decode(crt)
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			caBlock, _ := pem.Decode(caCrt)
+			ca, err := x509.ParseCertificate(caBlock.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := parsed.CheckSignatureFrom(ca); err != nil {
+				t.Errorf("certificate is not signed by the ca: %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenExec_PSS(t *testing.T) {
+	command, _ := helperCommand(t, cert.KeyTypeRSA)
+
+	s, err := OpenExec(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256([]byte("message"))
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	signature, err := s.Sign(rand.Reader, digest[:], opts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if err := rsa.VerifyPSS(s.Public().(*rsa.PublicKey), crypto.SHA256, digest[:], signature, opts); err != nil {
+		t.Errorf("Sign() signature error = %v", err)
+	}
+}
+
+func TestOpenExec_Errors(t *testing.T) {
+	if _, err := OpenExec(" "); !errors.Is(err, ErrorInvalidURI) {
+		t.Errorf("OpenExec() error = %v, want %v", err, ErrorInvalidURI)
+	}
+
+	// The helper fails without a key
+	t.Setenv("SIGNER_HELPER_KEY", filepath.Join(t.TempDir(), "missing.key"))
+	if _, err := OpenExec(os.Args[0] + " -test.run=^TestHelperSigner$ --"); err == nil {
+		t.Errorf("OpenExec() expected an error for a failing command")
+	}
+
+	if _, err := Open("file:ca.key"); err != ErrorUnsupportedScheme {
+		t.Errorf("Open() error = %v, want %v", err, ErrorUnsupportedScheme)
+	}
+}
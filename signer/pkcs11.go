@@ -0,0 +1,112 @@
+package signer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PKCS11Config selects a key pair in a PKCS#11 token
+type PKCS11Config struct {
+	// ModulePath is the path of the PKCS#11 library, such as /usr/lib/softhsm/libsofthsm2.so
+	ModulePath string
+	// TokenLabel, TokenSerial and SlotID select the token, exactly one of them has to be set
+	TokenLabel  string
+	TokenSerial string
+	SlotID      *int
+	// Pin is the user PIN used to log in to the token
+	Pin string
+	// ObjectLabel and ObjectID select the key pair in the token, at least one of them has to be set. The private and
+	// public key need the same ID.
+	ObjectLabel string
+	ObjectID    []byte
+}
+
+// ParsePKCS11URI parses a PKCS#11 URI (RFC 7512) into a PKCS11Config. The path attributes token, serial and slot-id
+// select the token and object and id the key pair. The query attributes module-path, pin-value and pin-source (a
+// file with the PIN) configure the library and the login. Other attributes are ignored.
+func ParsePKCS11URI(uri string) (*PKCS11Config, error) {
+	if !strings.HasPrefix(uri, SchemePKCS11+":") {
+		return nil, ErrorUnsupportedScheme
+	}
+
+	path, query, _ := strings.Cut(strings.TrimPrefix(uri, SchemePKCS11+":"), "?")
+	attributes := map[string]string{}
+	for _, part := range append(splitAttributes(path, ";"), splitAttributes(query, "&")...) {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: attribute %q has no value", ErrorInvalidURI, part)
+		}
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: attribute %q: %s", ErrorInvalidURI, name, err)
+		}
+		if _, ok := attributes[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate attribute %q", ErrorInvalidURI, name)
+		}
+		attributes[name] = unescaped
+	}
+
+	cfg := &PKCS11Config{
+		ModulePath:  attributes["module-path"],
+		TokenLabel:  attributes["token"],
+		TokenSerial: attributes["serial"],
+		Pin:         attributes["pin-value"],
+		ObjectLabel: attributes["object"],
+	}
+	if id, ok := attributes["id"]; ok {
+		cfg.ObjectID = []byte(id)
+	}
+
+	if slot, ok := attributes["slot-id"]; ok {
+		slotID, err := strconv.Atoi(slot)
+		if err != nil || slotID < 0 {
+			return nil, fmt.Errorf("%w: invalid slot-id %q", ErrorInvalidURI, slot)
+		}
+		cfg.SlotID = &slotID
+	}
+
+	if source, ok := attributes["pin-source"]; ok {
+		if cfg.Pin != "" {
+			return nil, fmt.Errorf("%w: pin-value and pin-source are exclusive", ErrorInvalidURI)
+		}
+		pin, err := ioutil.ReadFile(strings.TrimPrefix(source, "file:"))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Pin = strings.TrimRight(string(pin), "\r\n")
+	}
+
+	if t, ok := attributes["type"]; ok && t != "private" {
+		return nil, fmt.Errorf("%w: type has to be private", ErrorInvalidURI)
+	}
+
+	selectors := 0
+	for _, selector := range []bool{cfg.TokenLabel != "", cfg.TokenSerial != "", cfg.SlotID != nil} {
+		if selector {
+			selectors++
+		}
+	}
+
+	switch {
+	case cfg.ModulePath == "":
+		return nil, fmt.Errorf("%w: module-path is required", ErrorInvalidURI)
+	case selectors != 1:
+		return nil, fmt.Errorf("%w: exactly one of token, serial and slot-id is required", ErrorInvalidURI)
+	case cfg.ObjectLabel == "" && len(cfg.ObjectID) == 0:
+		return nil, fmt.Errorf("%w: object or id is required", ErrorInvalidURI)
+	}
+
+	return cfg, nil
+}
+
+// splitAttributes splits the path or query of a PKCS#11 URI into its attributes
+func splitAttributes(s string, sep string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, sep)
+}
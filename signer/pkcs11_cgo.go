@@ -0,0 +1,50 @@
+//go:build cgo
+// +build cgo
+
+package signer
+
+import (
+	"crypto"
+	"github.com/ThalesIgnite/crypto11"
+)
+
+// pkcs11Signer signs with a key pair in a PKCS#11 token and closes the session to the token on Close
+type pkcs11Signer struct {
+	crypto.Signer
+	ctx *crypto11.Context
+}
+
+// OpenPKCS11 loads the PKCS#11 library, logs in to the token and finds the key pair of the configuration
+func OpenPKCS11(cfg *PKCS11Config) (Signer, error) {
+	ctx, err := crypto11.Configure(&crypto11.Config{
+		Path:        cfg.ModulePath,
+		TokenLabel:  cfg.TokenLabel,
+		TokenSerial: cfg.TokenSerial,
+		SlotNumber:  cfg.SlotID,
+		Pin:         cfg.Pin,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var label []byte
+	if cfg.ObjectLabel != "" {
+		label = []byte(cfg.ObjectLabel)
+	}
+
+	key, err := ctx.FindKeyPair(cfg.ObjectID, label)
+	if err == nil && key == nil {
+		err = ErrorKeyNotFound
+	}
+	if err != nil {
+		ctx.Close()
+		return nil, err
+	}
+
+	return &pkcs11Signer{Signer: key, ctx: ctx}, nil
+}
+
+// Close closes the session to the token
+func (s *pkcs11Signer) Close() error {
+	return s.ctx.Close()
+}
@@ -0,0 +1,123 @@
+//go:build cgo
+// +build cgo
+
+package signer
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/mvmaasakkers/certificates/cert"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// softHSMModule returns the path of the SoftHSM library, the test is skipped when SoftHSM is not installed
+func softHSMModule(t *testing.T) string {
+	if _, err := exec.LookPath("softhsm2-util"); err != nil {
+		t.Skip("SoftHSM is not installed")
+	}
+
+	if module := os.Getenv("SOFTHSM2_MODULE"); module != "" {
+		return module
+	}
+	for _, module := range []string{
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	} {
+		if _, err := os.Stat(module); err == nil {
+			return module
+		}
+	}
+
+	t.Skip("SoftHSM library not found, set SOFTHSM2_MODULE to the path of libsofthsm2.so")
+	return ""
+}
+
+func TestOpenPKCS11(t *testing.T) {
+	module := softHSMModule(t)
+
+	// Initialize a token in a temporary SoftHSM store
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "tokens"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(dir, "softhsm2.conf")
+	if err := ioutil.WriteFile(conf, []byte("directories.tokendir = "+filepath.Join(dir, "tokens")+"\nobjectstore.backend = file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	if out, err := exec.Command("softhsm2-util", "--init-token", "--free", "--label", "ca", "--pin", "1234", "--so-pin", "5678").CombinedOutput(); err != nil {
+		t.Fatalf("softhsm2-util error = %v: %s", err, out)
+	}
+
+	ctx, err := crypto11.Configure(&crypto11.Config{Path: module, TokenLabel: "ca", Pin: "1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctx.GenerateECDSAKeyPairWithLabel([]byte{1}, []byte("ca key"), elliptic.P256()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open("pkcs11:token=ca;object=ca%20key?module-path=" + module + "&pin-value=1234")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	// The CA certificate is self-signed by the key in the token
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca.test.local"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, s.Public(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crt, _, err := cert.GenerateCertificateWithSigner(&cert.Request{
+		CommonName: "www.test.local",
+		NotBefore:  time.Now(),
+		NotAfter:   time.Now().Add(time.Hour),
+		KeyType:    cert.KeyTypeECDSA,
+	}, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), s)
+	if err != nil {
+		t.Fatalf("GenerateCertificateWithSigner() error = %v", err)
+	}
+
+	block, _ := pem.Decode(crt)
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("certificate is not signed by the ca: %v", err)
+	}
+
+	if _, err := Open("pkcs11:token=ca;object=missing?module-path=" + module + "&pin-value=1234"); err != ErrorKeyNotFound {
+		t.Errorf("Open() error = %v, want %v", err, ErrorKeyNotFound)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !cgo
+// +build !cgo
+
+package signer
+
+// OpenPKCS11 needs cgo to load the PKCS#11 library, without it ErrorPKCS11Unsupported is returned
+func OpenPKCS11(cfg *PKCS11Config) (Signer, error) {
+	return nil, ErrorPKCS11Unsupported
+}
@@ -0,0 +1,61 @@
+package signer
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePKCS11URI(t *testing.T) {
+	pinFile := filepath.Join(t.TempDir(), "pin.txt")
+	if err := ioutil.WriteFile(pinFile, []byte("1234\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	slot := 3
+	tests := []struct {
+		name    string
+		uri     string
+		want    *PKCS11Config
+		wantErr error
+	}{
+		{
+			name: "token-and-object",
+			uri:  "pkcs11:token=ca;object=ca%20key?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-value=1234",
+			want: &PKCS11Config{ModulePath: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "ca", ObjectLabel: "ca key", Pin: "1234"},
+		},
+		{
+			name: "slot-and-id",
+			uri:  "pkcs11:slot-id=3;id=%01%02;type=private?module-path=/lib/p11.so&pin-source=file:" + pinFile,
+			want: &PKCS11Config{ModulePath: "/lib/p11.so", SlotID: &slot, ObjectID: []byte{1, 2}, Pin: "1234"},
+		},
+		{
+			name: "serial-ignores-unknown-attributes",
+			uri:  "pkcs11:serial=abc;manufacturer=SoftHSM;object=ca?module-path=/lib/p11.so",
+			want: &PKCS11Config{ModulePath: "/lib/p11.so", TokenSerial: "abc", ObjectLabel: "ca"},
+		},
+		{name: "other-scheme", uri: "exec:signer", wantErr: ErrorUnsupportedScheme},
+		{name: "missing-module", uri: "pkcs11:token=ca;object=ca", wantErr: ErrorInvalidURI},
+		{name: "missing-token", uri: "pkcs11:object=ca?module-path=/lib/p11.so", wantErr: ErrorInvalidURI},
+		{name: "two-tokens", uri: "pkcs11:token=ca;slot-id=1;object=ca?module-path=/lib/p11.so", wantErr: ErrorInvalidURI},
+		{name: "missing-object", uri: "pkcs11:token=ca?module-path=/lib/p11.so", wantErr: ErrorInvalidURI},
+		{name: "invalid-slot", uri: "pkcs11:slot-id=one;object=ca?module-path=/lib/p11.so", wantErr: ErrorInvalidURI},
+		{name: "public-key", uri: "pkcs11:token=ca;object=ca;type=public?module-path=/lib/p11.so", wantErr: ErrorInvalidURI},
+		{name: "duplicate-attribute", uri: "pkcs11:token=ca;token=other;object=ca?module-path=/lib/p11.so", wantErr: ErrorInvalidURI},
+		{name: "invalid-escape", uri: "pkcs11:token=ca;object=%zz?module-path=/lib/p11.so", wantErr: ErrorInvalidURI},
+		{name: "pin-value-and-source", uri: "pkcs11:token=ca;object=ca?module-path=/lib/p11.so&pin-value=1&pin-source=" + pinFile, wantErr: ErrorInvalidURI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePKCS11URI(tt.uri)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParsePKCS11URI() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePKCS11URI() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package signer opens the crypto.Signer of a CA key that is kept out of the filesystem, in an HSM, a cloud KMS or
+// behind any other program, so the cert package can sign with it (see cert.GenerateCertificateWithSigner). Signers
+// are addressed with a URI:
+//
+//	pkcs11:token=<token label>;object=<key label>?module-path=<library>&pin-source=<pin file>
+//	exec:<command> [arguments]
+//
+// A pkcs11 URI (RFC 7512) selects a key pair in a PKCS#11 token, see ParsePKCS11URI and OpenPKCS11. An exec URI runs
+// an external program implementing the exec signer protocol for each signature, see OpenExec.
+package signer
+
+import (
+	"crypto"
+	"errors"
+	"strings"
+)
+
+const (
+	// SchemePKCS11 is the URI scheme of PKCS#11 signers
+	SchemePKCS11 = "pkcs11"
+	// SchemeExec is the URI scheme of external process signers
+	SchemeExec = "exec"
+)
+
+var (
+	// ErrorUnsupportedScheme is given if the URI scheme is not one of the supported signer schemes
+	ErrorUnsupportedScheme = errors.New("unsupported signer scheme")
+	// ErrorInvalidURI is given if a signer URI can not be parsed or lacks required attributes
+	ErrorInvalidURI = errors.New("invalid signer uri")
+	// ErrorKeyNotFound is given if the key pair of a PKCS#11 URI is not in the token
+	ErrorKeyNotFound = errors.New("key not found")
+	// ErrorPKCS11Unsupported is given if a PKCS#11 signer is opened in a build without cgo
+	ErrorPKCS11Unsupported = errors.New("pkcs11 signers are not supported without cgo")
+	// ErrorInvalidPublicKey is given if an exec signer does not return a PEM encoded public key
+	ErrorInvalidPublicKey = errors.New("invalid public key")
+)
+
+// Signer is a crypto.Signer holding resources, such as a PKCS#11 session, that are released with Close
+type Signer interface {
+	crypto.Signer
+	Close() error
+}
+
+// Open opens the signer addressed by the URI
+func Open(uri string) (Signer, error) {
+	scheme, rest, _ := strings.Cut(uri, ":")
+	switch scheme {
+	case SchemePKCS11:
+		cfg, err := ParsePKCS11URI(uri)
+		if err != nil {
+			return nil, err
+		}
+		return OpenPKCS11(cfg)
+	case SchemeExec:
+		return OpenExec(rest)
+	}
+
+	return nil, ErrorUnsupportedScheme
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mvmaasakkers/certificates/cert"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// exitCodeVerificationFailed is the exit code when a certificate fails verification, other errors exit with 1
+const exitCodeVerificationFailed = 2
+
+var errorVerificationFailed = errors.New("certificate verification failed")
+
+var verifyCommand = cli.Command{
+	Name:      "verify",
+	Usage:     "Verify that a certificate chains to the CA and is valid for a host and usages",
+	ArgsUsage: "<file>",
+	Description: `Checks that the certificate chains up to the CA, that it and the rest of its chain are valid now and, when
+   given, that it is valid for --host and has every --usage. Any certificates after the first one in the file are
+   used as intermediates. Every check is reported, not just the first one that fails.
+
+   The exit code is 0 when all checks pass, 2 when the certificate fails a check and 1 for any other error.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "ca",
+			Value: "ca.crt",
+			Usage: "File with the trusted CA certificates",
+		},
+		cli.StringFlag{
+			Name:  "chain",
+			Value: "",
+			Usage: "File with the intermediate certificates between the certificate and the CA",
+		},
+		cli.StringFlag{
+			Name:  "host",
+			Value: "",
+			Usage: "DNS name or IP address the certificate has to be valid for",
+		},
+		cli.StringSliceFlag{
+			Name:  "usage",
+			Usage: "Key usage or extended key usage the certificate must have, like server-auth or digital-signature",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "Output format (text or json)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+
+		switch c.String("format") {
+		case "text", "json":
+		default:
+			fmt.Printf("Error parsing --format: %s\n", errorInvalidFormat.Error())
+			return errorInvalidFormat
+		}
+
+		if c.NArg() != 1 {
+			fmt.Printf("Error: %s\n", errorMissingVerifyFile.Error())
+			return errorMissingVerifyFile
+		}
+
+		crt, err := ioutil.ReadFile(c.Args().First())
+		if err != nil {
+			fmt.Printf("Error reading crt file: %s\n", err.Error())
+			return err
+		}
+
+		caCrt, err := ioutil.ReadFile(c.String("ca"))
+		if err != nil {
+			fmt.Printf("Error reading ca file: %s\n", err.Error())
+			return err
+		}
+
+		var chain []byte
+		if c.String("chain") != "" {
+			chain, err = ioutil.ReadFile(c.String("chain"))
+			if err != nil {
+				fmt.Printf("Error reading chain file: %s\n", err.Error())
+				return err
+			}
+		}
+
+		report, err := cert.Verify(crt, chain, caCrt, cert.VerifyOptions{
+			Host:   c.String("host"),
+			Usages: c.StringSlice("usage"),
+		})
+		if err != nil {
+			fmt.Printf("Error verifying certificate: %s\n", err.Error())
+			return err
+		}
+
+		if c.String("format") == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(report)
+		} else {
+			err = writeVerifyText(report)
+		}
+		if err != nil {
+			fmt.Printf("Error writing report: %s\n", err.Error())
+			return err
+		}
+
+		if !report.Valid {
+			return errorVerificationFailed
+		}
+		return nil
+	},
+}
+
+func writeVerifyText(report *cert.VerifyReport) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, check := range report.Checks {
+		name := check.Name
+		if check.Usage != "" {
+			name += " " + check.Usage
+		}
+		if check.Passed {
+			fmt.Fprintf(w, "%s:\tOK\n", name)
+		} else {
+			fmt.Fprintf(w, "%s:\tFAILED\t%s\n", name, check.Message)
+		}
+	}
+	if len(report.Chain) > 0 {
+		fmt.Fprintf(w, "Chain:\t%s\n", strings.Join(report.Chain, " <- "))
+	}
+	if report.Valid {
+		fmt.Fprintln(w, "Result:\tOK")
+	} else {
+		fmt.Fprintln(w, "Result:\tFAILED")
+	}
+
+	return w.Flush()
+}